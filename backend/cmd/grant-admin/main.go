@@ -0,0 +1,58 @@
+// Command grant-admin gives (or with -revoke, takes away) the site admin role.
+//
+//	go run ./cmd/grant-admin alice
+//	go run ./cmd/grant-admin -revoke alice
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"backend/internal/db"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/moderation"
+)
+
+func main() {
+	revoke := flag.Bool("revoke", false, "remove the site admin role instead of granting it")
+	dbPath := flag.String("db", "./social-network.db", "path of the SQLite database")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: grant-admin [-revoke] [-db path] <username or email>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := db.InitDB(*dbPath); err != nil {
+		fmt.Printf("Failed to initialize database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	user, err := queries.GetUserByIdentifier(flag.Arg(0))
+	if err == sql.ErrNoRows {
+		fmt.Printf("No user %q\n", flag.Arg(0))
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Failed to find user: %v\n", err)
+		os.Exit(1)
+	}
+
+	role := models.SiteRoleAdmin
+	if *revoke {
+		role = models.SiteRoleUser
+	}
+	if err := moderation.SetRole(0, user.ID, role); err != nil {
+		fmt.Printf("Failed to set role: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s (id %d) is now %s\n", user.Username, user.ID, role)
+}
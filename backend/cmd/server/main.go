@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,8 +10,16 @@ import (
 	"strings"
 	"syscall"
 
+	"backend/internal/contentfilter"
+	"backend/internal/dataexport"
 	"backend/internal/db"
+	"backend/internal/deletion"
+	"backend/internal/digest"
+	"backend/internal/media"
+	"backend/internal/reminders"
+	"backend/internal/scheduler"
 	"backend/internal/server"
+	"backend/internal/webhooks"
 )
 
 // loadEnv reads a .env file and sets environment variables.
@@ -37,6 +46,10 @@ func main() {
 	// Load environment variables
 	loadEnv(".env")
 
+	if err := contentfilter.Load(); err != nil {
+		panic(fmt.Sprintf("Failed to load content filter: %v", err))
+	}
+
 	// Initialize database
 	if err := db.InitDB("./social-network.db"); err != nil {
 		panic(fmt.Sprintf("Failed to initialize database: %v", err))
@@ -48,6 +61,25 @@ func main() {
 		panic(fmt.Sprintf("Failed to run migrations: %v", err))
 	}
 
+	// Open the media store and move old uploads into it
+	if err := media.Init(); err != nil {
+		panic(fmt.Sprintf("Failed to open media store: %v", err))
+	}
+	if err := media.ImportLegacyUploads(); err != nil {
+		panic(fmt.Sprintf("Failed to import uploads: %v", err))
+	}
+
+	// Start background jobs
+	ctx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	reminders.Register()
+	digest.Register()
+	webhooks.Register()
+	dataexport.Register()
+	deletion.Register()
+	media.Register()
+	go scheduler.Run(ctx)
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	server.SetupRoutes(mux)
@@ -0,0 +1,327 @@
+// Package audiences manages audience lists: named lists of followers, such as
+// "close friends", that users share posts with. A list is private to its owner;
+// its members are never told about it. The posts package shares its follower
+// check and lost-access notification for posts shared with selected followers.
+package audiences
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/policy"
+	"backend/internal/utils"
+	"backend/internal/ws"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxLists bounds the audience lists of one user
+	maxLists      = 20
+	maxNameLength = 50
+)
+
+type listBody struct {
+	Name    string `json:"name"`
+	UserIDs []int  `json:"user_ids"`
+}
+
+type membersBody struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+// loadList returns the audience list of the {id} path value if the current
+// user owns it, or writes an error response and returns false.
+func loadList(w http.ResponseWriter, r *http.Request, userID int) (models.AudienceList, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid list ID"})
+		return models.AudienceList{}, false
+	}
+
+	list, err := queries.GetAudienceList(id)
+	if err != nil && err != sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch audience list"})
+		return list, false
+	}
+	// Same answer for the lists of others, so IDs cannot be probed
+	if err == sql.ErrNoRows || list.OwnerID != userID {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Audience list not found"})
+		return list, false
+	}
+	return list, true
+}
+
+// validName trims a list name and checks its length. On failure the error
+// response is written and ok is false.
+func validName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if n := utf8.RuneCountInString(name); n == 0 || n > maxNameLength {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("Name must be between 1 and %d characters", maxNameLength),
+		})
+		return name, false
+	}
+	return name, true
+}
+
+// isDuplicateName reports whether err is the owner already having a list of
+// the name.
+func isDuplicateName(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// CheckFollowers verifies that every user given is an accepted follower of
+// the owner, for an audience list or the audience of a post. On failure the
+// error response is written and ok is false.
+func CheckFollowers(w http.ResponseWriter, ownerID int, userIDs []int) bool {
+	other, err := policy.NonFollower(ownerID, userIDs)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to verify followers"})
+		return false
+	}
+	if other != 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("User %d is not one of your followers", other),
+		})
+		return false
+	}
+	return true
+}
+
+// NotifyLostAccess sends a post_removed event for each of the posts to those
+// of before, who could see it before a change of its privacy, its audience or
+// its audience list, who can no longer see it. postIDs is read before the
+// change when the change unlinks the posts from a list.
+func NotifyLostAccess(postIDs []int64, before []int) {
+	if len(before) == 0 {
+		return
+	}
+	for _, postID := range postIDs {
+		lost, err := policy.LostViewers(models.Post{ID: postID}, before)
+		if err != nil {
+			fmt.Printf("audiences: failed to check the viewers of post %d: %v\n", postID, err)
+			continue
+		}
+		ws.NotifyPostRemoved(postID, lost)
+	}
+}
+
+// respondList writes an audience list with its members.
+func respondList(w http.ResponseWriter, status int, listID int64, ownerID int) {
+	list, err := queries.GetAudienceList(listID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch audience list"})
+		return
+	}
+	members, err := queries.GetAudienceListMembers(listID, ownerID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch audience list"})
+		return
+	}
+	if members == nil {
+		members = []models.UserSearchResult{}
+	}
+	utils.RespondJSON(w, status, map[string]interface{}{
+		"success": true,
+		"list":    list,
+		"members": members,
+	})
+}
+
+// GetListsHandler handles GET /api/audience-lists: the audience lists of the
+// current user.
+func GetListsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	lists, err := queries.GetAudienceLists(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch audience lists"})
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"lists":   lists,
+	})
+}
+
+// CreateListHandler handles POST /api/audience-lists
+// Body: { "name": "Close friends", "user_ids": [4, 5] }; user_ids is optional.
+func CreateListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	var body listBody
+	if err := utils.ParseJSON(r, &body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	name, ok := validName(w, body.Name)
+	if !ok || !CheckFollowers(w, userID, body.UserIDs) {
+		return
+	}
+
+	lists, err := queries.GetAudienceLists(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create audience list"})
+		return
+	}
+	if len(lists) >= maxLists {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can have at most %d audience lists", maxLists),
+		})
+		return
+	}
+
+	listID, err := queries.CreateAudienceList(userID, name)
+	if err != nil {
+		if isDuplicateName(err) {
+			utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "You already have a list with this name"})
+			return
+		}
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create audience list"})
+		return
+	}
+	if err := queries.AddAudienceListMembers(listID, userID, body.UserIDs); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to add members"})
+		return
+	}
+	respondList(w, http.StatusCreated, listID, userID)
+}
+
+// GetListHandler handles GET /api/audience-lists/{id}: a list with its members.
+func GetListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	list, ok := loadList(w, r, userID)
+	if !ok {
+		return
+	}
+	respondList(w, http.StatusOK, list.ID, userID)
+}
+
+// RenameListHandler handles PUT /api/audience-lists/{id}
+// Body: { "name": "Family" }
+func RenameListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	list, ok := loadList(w, r, userID)
+	if !ok {
+		return
+	}
+	var body listBody
+	if err := utils.ParseJSON(r, &body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	name, ok := validName(w, body.Name)
+	if !ok {
+		return
+	}
+
+	if err := queries.RenameAudienceList(list.ID, name); err != nil {
+		if isDuplicateName(err) {
+			utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "You already have a list with this name"})
+			return
+		}
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to rename audience list"})
+		return
+	}
+	respondList(w, http.StatusOK, list.ID, userID)
+}
+
+// DeleteListHandler handles DELETE /api/audience-lists/{id}. The posts shared
+// with the list are left visible to their author only.
+func DeleteListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	list, ok := loadList(w, r, userID)
+	if !ok {
+		return
+	}
+	// Read before the delete unlinks them
+	postIDs, err := queries.GetAudienceListPostIDs(list.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to delete audience list"})
+		return
+	}
+	members, err := queries.GetAudienceListMemberIDs(list.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to delete audience list"})
+		return
+	}
+
+	if err := queries.DeleteAudienceList(list.ID); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to delete audience list"})
+		return
+	}
+	NotifyLostAccess(postIDs, ws.OnlineAmong(members))
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Audience list deleted"})
+}
+
+// AddMembersHandler handles POST /api/audience-lists/{id}/members
+// Body: { "user_ids": [4, 5] }. Members must be accepted followers of the owner.
+func AddMembersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	list, ok := loadList(w, r, userID)
+	if !ok {
+		return
+	}
+	var body membersBody
+	if err := utils.ParseJSON(r, &body); err != nil || len(body.UserIDs) == 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	if !CheckFollowers(w, userID, body.UserIDs) {
+		return
+	}
+
+	if err := queries.AddAudienceListMembers(list.ID, userID, body.UserIDs); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to add members"})
+		return
+	}
+	respondList(w, http.StatusOK, list.ID, userID)
+}
+
+// RemoveMemberHandler handles DELETE /api/audience-lists/{id}/members/{userId}.
+// The user stops seeing every post shared with the list.
+func RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	list, ok := loadList(w, r, userID)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid user ID"})
+		return
+	}
+
+	removed, err := queries.RemoveAudienceListMember(list.ID, memberID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to remove member"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User is not in this list"})
+		return
+	}
+	if postIDs, err := queries.GetAudienceListPostIDs(list.ID); err == nil {
+		NotifyLostAccess(postIDs, ws.OnlineAmong([]int{memberID}))
+	}
+	respondList(w, http.StatusOK, list.ID, userID)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"backend/internal/db/queries"
+	"backend/internal/deletion"
 	"backend/internal/models"
 	"backend/internal/utils"
 
@@ -71,6 +72,33 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Suspended accounts can't log in until the suspension ends
+	suspension, err := queries.GetActiveSuspension(dbUser.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to retrieve user",
+		})
+		return
+	}
+	if suspension != nil {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+			Success: false,
+			Message: suspension.Message(),
+		})
+		return
+	}
+
+	// Logging in is how a user takes back a scheduled account deletion
+	deletionCancelled, err := deletion.Cancel(dbUser.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to retrieve user",
+		})
+		return
+	}
+
 	//get user's browser fingerprint
 	browserFingerprint := utils.FingerprintFromRequest(r)
 	// Create session
@@ -97,10 +125,15 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		Expires:  time.Now().Add(365 * 24 * time.Hour),
 	})
 
+	message := "Login successful"
+	if deletionCancelled {
+		message = "Login successful. Your account is no longer scheduled for deletion"
+	}
+
 	// Respond WITHOUT sensitive fields
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
 		Success: true,
-		Message: "Login successful",
+		Message: message,
 		User: &models.UserPublic{
 			UserId:      dbUser.ID,
 			Email:       dbUser.Email,
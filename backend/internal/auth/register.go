@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
 
@@ -48,8 +49,8 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 
-		// Save avatar using utility function
-		path, err := utils.SaveUploadedFile(file, handler, "avatars")
+		// The account does not exist yet, so the avatar gets its owner below
+		urls, err := media.SaveUploadedFile(file, handler, "avatars", 0)
 		if err != nil {
 			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 				Success: false,
@@ -57,7 +58,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		avatarPath = path
+		avatarPath = urls.Original
 	}
 	// If no file uploaded or error reading file (other than missing), avatarPath remains empty
 	// Validate all fields using the validation functions
@@ -149,6 +150,9 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if avatarPath != "" {
+		media.SetOwner(avatarPath, dbUser.ID)
+	}
 
 	browserFingerprint := utils.FingerprintFromRequest(r)
 	// Create session for the new user
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	prodID = "-//Social Network//Group Events//EN"
+	// Event times are stored without a zone, so they are written as floating times.
+	floatingLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+)
+
+// Event is a single VEVENT entry of a calendar.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time // optional
+	Created     time.Time
+	// Rule and ExDates describe a recurring series; both are optional.
+	Rule    string
+	ExDates []time.Time
+}
+
+// WriteCalendar serialises events as a VCALENDAR document named name.
+func WriteCalendar(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + prodID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	lw.line("X-WR-CALNAME:" + escapeText(name))
+
+	stamp := time.Now().UTC().Format(utcLayout)
+	for _, e := range events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line("DTSTAMP:" + stamp)
+		if !e.Created.IsZero() {
+			lw.line("CREATED:" + e.Created.UTC().Format(utcLayout))
+		}
+		lw.line("DTSTART:" + e.Start.Format(floatingLayout))
+		if !e.End.IsZero() {
+			lw.line("DTEND:" + e.End.Format(floatingLayout))
+		}
+		lw.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			lw.line("LOCATION:" + escapeText(e.Location))
+		}
+		if e.Rule != "" {
+			lw.line("RRULE:" + e.Rule)
+		}
+		for _, ex := range e.ExDates {
+			lw.line("EXDATE:" + ex.Format(floatingLayout))
+		}
+		lw.line("END:VEVENT")
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+// lineWriter writes CRLF-terminated content lines folded at 75 octets.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	// Continuation lines start with a space, leaving room for 74 octets
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// Never split a multi-byte UTF-8 sequence
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, lw.err = lw.w.WriteString(s[:cut] + "\r\n "); lw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = 74
+	}
+	_, lw.err = lw.w.WriteString(s + "\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
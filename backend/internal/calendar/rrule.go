@@ -0,0 +1,265 @@
+// Package calendar implements the subset of iCalendar (RFC 5545) used by group
+// events: recurrence rules, occurrence expansion and .ics serialisation.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+
+	// MaxCount bounds COUNT and the number of occurrences expanded per request.
+	MaxCount = 500
+	// maxPeriods guards expansion of rules that rarely produce occurrences.
+	maxPeriods = 5000
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule. Supported parts:
+//   - FREQ=DAILY|WEEKLY|MONTHLY (required)
+//   - INTERVAL=n
+//   - COUNT=n or UNTIL=YYYYMMDD[THHMMSS[Z]] (mutually exclusive)
+//   - BYDAY=MO,WE,... (WEEKLY only)
+//   - BYMONTHDAY=1,15,... (MONTHLY only)
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+// ParseRule parses an RRULE value (with or without the "RRULE:" prefix).
+func ParseRule(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, errors.New("recurrence rule is empty")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || key == "" || value == "" {
+			return rule, fmt.Errorf("malformed rule part %q", part)
+		}
+		if seen[key] {
+			return rule, fmt.Errorf("duplicate rule part %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly {
+				return rule, fmt.Errorf("unsupported FREQ %s", value)
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 99 {
+				return rule, errors.New("INTERVAL must be between 1 and 99")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MaxCount {
+				return rule, fmt.Errorf("COUNT must be between 1 and %d", MaxCount)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return rule, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY value %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 31 {
+					return rule, errors.New("BYMONTHDAY values must be between 1 and 31")
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return rule, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != FreqWeekly {
+		return rule, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != FreqMonthly {
+		return rule, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	sort.Slice(rule.ByDay, func(i, j int) bool { return mondayIndex(rule.ByDay[i]) < mondayIndex(rule.ByDay[j]) })
+	sort.Ints(rule.ByMonthDay)
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("UNTIL must be formatted as YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+// String returns the canonical RRULE value (without the "RRULE:" prefix).
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		// Event times are floating, so UNTIL is written as a floating time too
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			for code, wd := range weekdayCodes {
+				if wd == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between expands the rule for a series starting at start and returns the
+// occurrences in [from, to], at most max of them. Candidates before start are
+// skipped and COUNT is counted from start, as in RFC 5545.
+func (r Rule) Between(start, from, to time.Time, max int) []time.Time {
+	if max <= 0 || max > MaxCount {
+		max = MaxCount
+	}
+
+	var out []time.Time
+	produced := 0
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if t.After(to) {
+			return false
+		}
+		produced++
+		if r.Count > 0 && produced > r.Count {
+			return false
+		}
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+		return len(out) < max
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, candidate := range r.periodCandidates(start, period*interval) {
+			if !emit(candidate) {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// periodCandidates returns the sorted candidate times of the n-th period after start.
+func (r Rule) periodCandidates(start time.Time, n int) []time.Time {
+	hour, min, sec := start.Clock()
+	switch r.Freq {
+	case FreqDaily:
+		return []time.Time{start.AddDate(0, 0, n)}
+
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*n)}
+		}
+		weekStart := start.AddDate(0, 0, -mondayIndex(start.Weekday())+7*n)
+		candidates := make([]time.Time, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			candidates = append(candidates, weekStart.AddDate(0, 0, mondayIndex(day)))
+		}
+		return candidates
+
+	case FreqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1, hour, min, sec, 0, start.Location())
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		candidates := make([]time.Time, 0, len(days))
+		for _, d := range days {
+			t := first.AddDate(0, 0, d-1)
+			// Invalid dates (e.g. the 31st in a 30-day month) are skipped
+			if t.Month() == first.Month() {
+				candidates = append(candidates, t)
+			}
+		}
+		return candidates
+	}
+	return nil
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
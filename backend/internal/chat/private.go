@@ -4,6 +4,7 @@ import (
 	"backend/internal/db/queries"
 	"backend/internal/models"
 	"backend/internal/utils"
+	"fmt"
 	"net/http"
 	"strconv"
 )
@@ -201,6 +202,13 @@ func GetPrivateChatMessages(w http.ResponseWriter, r *http.Request) {
 		messages = []models.PrivateChatMessage{}
 	}
 
+	// Loading the latest page means the conversation was read
+	if offset == 0 {
+		if err := queries.MarkConversationRead(conversationID, userID); err != nil {
+			fmt.Printf("Failed to mark conversation %d read for user %d: %v\n", conversationID, userID, err)
+		}
+	}
+
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"success":  true,
 		"messages": messages,
@@ -0,0 +1,205 @@
+package contentfilter
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Config is the JSON file named by CONTENT_FILTER_CONFIG. Every section is
+// optional; the zero Config gives the default chain.
+type Config struct {
+	WordLists []WordListConfig `json:"word_lists"`
+	Links     LinkConfig       `json:"links"`
+	Repeats   RepeatConfig     `json:"repeats"`
+	Mentions  MentionConfig    `json:"mentions"`
+}
+
+// WordListConfig is a named list of words and regular expressions sharing an action.
+type WordListConfig struct {
+	Name     string   `json:"name"`
+	Action   string   `json:"action"`   // default "reject"
+	Reason   string   `json:"reason"`   // report reason when flagged, default "other"
+	Words    []string `json:"words"`    // matched as whole words, ignoring case
+	Patterns []string `json:"patterns"` // RE2 regular expressions, ignoring case
+}
+
+// LinkConfig lists domains by name; subdomains match too. Allowed domains
+// win over denied ones.
+type LinkConfig struct {
+	Allow      []string `json:"allow"`
+	Deny       []string `json:"deny"`
+	DenyAction string   `json:"deny_action"` // default "reject"
+	Unlisted   string   `json:"unlisted"`    // action for links to other domains, default "allow"
+}
+
+// RepeatConfig limits how often a user may send the same text.
+type RepeatConfig struct {
+	Max       int    `json:"max"`        // copies allowed within the window, default 3; -1 disables
+	Window    string `json:"window"`     // default "10m"
+	MinLength int    `json:"min_length"` // shorter texts are not tracked, default 10
+	Action    string `json:"action"`     // default "reject"
+	// Kinds of content tracked, default posts, comments, replies and group posts. Chat
+	// messages are left out unless listed: a greeting sent to several people is not spam.
+	Kinds []string `json:"kinds"`
+}
+
+// defaultRepeatKinds are the kinds the repeat filter tracks when none are configured.
+var defaultRepeatKinds = []string{KindPost, KindComment, KindReply, KindGroupPost}
+
+// kinds lists every kind of content, for validating configurations.
+var kinds = []string{KindPost, KindComment, KindReply, KindGroupPost, KindGroupMessage, KindPrivateMessage}
+
+// MentionConfig limits the distinct @mentions in one text.
+type MentionConfig struct {
+	Max    int    `json:"max"`    // default 10; -1 disables
+	Action string `json:"action"` // default "reject"
+}
+
+// Build compiles the configuration into a chain.
+func (cfg Config) Build() (Chain, error) {
+	var chain Chain
+
+	for i, list := range cfg.WordLists {
+		f, err := newWordFilter(list)
+		if err != nil {
+			return nil, fmt.Errorf("word_lists[%d]: %w", i, err)
+		}
+		if f != nil {
+			chain = append(chain, f)
+		}
+	}
+
+	links, err := newLinkFilter(cfg.Links)
+	if err != nil {
+		return nil, fmt.Errorf("links: %w", err)
+	}
+	if links != nil {
+		chain = append(chain, links)
+	}
+
+	if cfg.Mentions.Max >= 0 {
+		limit := cfg.Mentions.Max
+		if limit == 0 {
+			limit = 10
+		}
+		action, err := parseAction(cfg.Mentions.Action, Reject)
+		if err != nil {
+			return nil, fmt.Errorf("mentions: %w", err)
+		}
+		chain = append(chain, &mentionFilter{max: limit, action: action})
+	}
+
+	// Last, so content rejected by another filter is never recorded as sent
+	if cfg.Repeats.Max >= 0 {
+		f := &repeatFilter{max: cfg.Repeats.Max, window: 10 * time.Minute, minLength: cfg.Repeats.MinLength, seen: map[repeatKey][]time.Time{}}
+		if f.max == 0 {
+			f.max = 3
+		}
+		if f.minLength == 0 {
+			f.minLength = 10
+		}
+		if cfg.Repeats.Window != "" {
+			d, err := time.ParseDuration(cfg.Repeats.Window)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("repeats: invalid window %q", cfg.Repeats.Window)
+			}
+			f.window = d
+		}
+		if f.action, err = parseAction(cfg.Repeats.Action, Reject); err != nil {
+			return nil, fmt.Errorf("repeats: %w", err)
+		}
+		tracked := cfg.Repeats.Kinds
+		if len(tracked) == 0 {
+			tracked = defaultRepeatKinds
+		}
+		f.kinds = map[string]bool{}
+		for _, k := range tracked {
+			if !contains(kinds, k) {
+				return nil, fmt.Errorf("repeats: kind must be one of: %s", strings.Join(kinds, ", "))
+			}
+			f.kinds[k] = true
+		}
+		chain = append(chain, f)
+	}
+
+	return chain, nil
+}
+
+func newWordFilter(cfg WordListConfig) (*wordFilter, error) {
+	f := &wordFilter{name: cfg.Name, reason: cfg.Reason}
+	if f.name == "" {
+		f.name = "words"
+	}
+	if f.reason == "" {
+		f.reason = "other"
+	}
+	if !contains(models.ReportReasons, f.reason) {
+		return nil, fmt.Errorf("reason must be one of: %s", strings.Join(models.ReportReasons, ", "))
+	}
+	var err error
+	if f.action, err = parseAction(cfg.Action, Reject); err != nil {
+		return nil, err
+	}
+
+	var words []string
+	for _, w := range cfg.Words {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	if len(words) > 0 {
+		// \b only knows ASCII, so word edges are spelled out for any script
+		f.patterns = append(f.patterns, regexp.MustCompile(
+			`(?i)(?:^|[^\p{L}\p{N}_])(`+strings.Join(words, "|")+`)(?:$|[^\p{L}\p{N}_])`))
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(`(?i)(` + p + `)`)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+
+	if len(f.patterns) == 0 || f.action == Allow {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func newLinkFilter(cfg LinkConfig) (*linkFilter, error) {
+	f := &linkFilter{allow: normalizeDomains(cfg.Allow), deny: normalizeDomains(cfg.Deny)}
+	var err error
+	if f.denyAction, err = parseAction(cfg.DenyAction, Reject); err != nil {
+		return nil, err
+	}
+	if f.unlisted, err = parseAction(cfg.Unlisted, Allow); err != nil {
+		return nil, err
+	}
+	if (len(f.deny) == 0 || f.denyAction == Allow) && f.unlisted == Allow {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func normalizeDomains(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
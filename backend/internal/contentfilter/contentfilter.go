@@ -0,0 +1,180 @@
+// Package contentfilter screens user-generated text before it is stored. A chain
+// of filters looks at each post, comment, reply and chat message; each filter
+// can let it through, flag it for review by the site admins, or reject it.
+package contentfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Action is what a filter decides about a piece of content.
+type Action int
+
+const (
+	Allow  Action = iota
+	Flag          // stored, and queued for review in the moderation reports
+	Reject        // refused with a message to the author
+)
+
+func (a Action) String() string {
+	switch a {
+	case Flag:
+		return "flag"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+func parseAction(s string, fallback Action) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return fallback, nil
+	case "allow":
+		return Allow, nil
+	case "flag":
+		return Flag, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Allow, fmt.Errorf("unknown action %q", s)
+}
+
+// Kinds of content the chain runs on
+const (
+	KindPost           = "post"
+	KindComment        = "comment"
+	KindReply          = "reply"
+	KindGroupPost      = "group_post"
+	KindGroupMessage   = "group_message"
+	KindPrivateMessage = "private_message"
+)
+
+// Input is one piece of content about to be stored.
+type Input struct {
+	UserID int
+	Kind   string
+	Text   string
+}
+
+// Verdict is the outcome of one filter. Message is shown to the author when the
+// content is rejected; Detail and ReportReason end up in the report when it is flagged.
+type Verdict struct {
+	Action       Action
+	Filter       string
+	Message      string
+	Detail       string
+	ReportReason string
+}
+
+// Filter checks a piece of content. Filters must be safe for concurrent use.
+type Filter interface {
+	Check(in Input) Verdict
+}
+
+// recorder is implemented by filters that remember content that got through,
+// such as the repeated-message filter.
+type recorder interface {
+	Record(in Input)
+}
+
+// Decision is the combined outcome of a chain.
+type Decision struct {
+	Rejection *Verdict
+	Flags     []Verdict
+}
+
+// Rejected reports whether the content must not be stored.
+func (d Decision) Rejected() bool {
+	return d.Rejection != nil
+}
+
+// Message is the reason given to the author of rejected content.
+func (d Decision) Message() string {
+	if d.Rejection == nil {
+		return ""
+	}
+	return d.Rejection.Message
+}
+
+// Chain runs filters in order. The first rejection stops the chain; flags from
+// every filter are collected.
+type Chain []Filter
+
+// Check runs the chain on in. Content that is not rejected is recorded by the
+// filters that keep history.
+func (c Chain) Check(in Input) Decision {
+	var d Decision
+	if strings.TrimSpace(in.Text) == "" {
+		return d
+	}
+	for _, f := range c {
+		v := f.Check(in)
+		switch v.Action {
+		case Reject:
+			d.Rejection = &v
+			return d
+		case Flag:
+			d.Flags = append(d.Flags, v)
+		}
+	}
+	for _, f := range c {
+		if r, ok := f.(recorder); ok {
+			r.Record(in)
+		}
+	}
+	return d
+}
+
+var (
+	mu     sync.RWMutex
+	active = mustBuild(Config{})
+)
+
+func mustBuild(cfg Config) Chain {
+	chain, err := cfg.Build()
+	if err != nil {
+		panic(err)
+	}
+	return chain
+}
+
+// Load builds the chain from the JSON file named by CONTENT_FILTER_CONFIG, or
+// from the defaults when it is unset. It is called once at startup.
+func Load() error {
+	cfg := Config{}
+	if path := strings.TrimSpace(os.Getenv("CONTENT_FILTER_CONFIG")); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read content filter config: %w", err)
+		}
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return fmt.Errorf("parse content filter config: %w", err)
+		}
+	}
+	chain, err := cfg.Build()
+	if err != nil {
+		return fmt.Errorf("content filter config: %w", err)
+	}
+	Use(chain)
+	return nil
+}
+
+// Use replaces the active chain.
+func Use(chain Chain) {
+	mu.Lock()
+	active = chain
+	mu.Unlock()
+}
+
+// Check runs the active chain.
+func Check(in Input) Decision {
+	mu.RLock()
+	chain := active
+	mu.RUnlock()
+	return chain.Check(in)
+}
@@ -0,0 +1,215 @@
+package contentfilter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// wordFilter matches a word list.
+type wordFilter struct {
+	name     string
+	action   Action
+	reason   string
+	patterns []*regexp.Regexp
+}
+
+func (f *wordFilter) Check(in Input) Verdict {
+	for _, re := range f.patterns {
+		if m := re.FindStringSubmatch(in.Text); m != nil {
+			return Verdict{
+				Action:       f.action,
+				Filter:       "words:" + f.name,
+				Message:      "Your message contains language that isn't allowed",
+				Detail:       fmt.Sprintf("matched %q", m[1]),
+				ReportReason: f.reason,
+			}
+		}
+	}
+	return Verdict{}
+}
+
+var (
+	// Explicit links, whose domain is checked against both lists
+	linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()]+`)
+	// Anything shaped like a domain, checked against the deny list only so
+	// "spam.example" written without a scheme is caught too
+	domainPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}\b`)
+)
+
+// linkFilter applies the link allow and deny lists.
+type linkFilter struct {
+	allow, deny []string
+	denyAction  Action
+	unlisted    Action
+}
+
+func matchesDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.ToLower(u.Hostname()), ".")
+}
+
+func (f *linkFilter) Check(in Input) Verdict {
+	if f.denyAction != Allow {
+		for _, host := range domainPattern.FindAllString(in.Text, -1) {
+			host = strings.ToLower(host)
+			if matchesDomain(host, f.deny) && !matchesDomain(host, f.allow) {
+				return Verdict{
+					Action:       f.denyAction,
+					Filter:       "links",
+					Message:      fmt.Sprintf("Links to %s aren't allowed", host),
+					Detail:       "denied domain " + host,
+					ReportReason: "spam",
+				}
+			}
+		}
+	}
+	if f.unlisted != Allow {
+		for _, link := range linkPattern.FindAllString(in.Text, -1) {
+			host := linkHost(link)
+			if host != "" && !matchesDomain(host, f.allow) {
+				return Verdict{
+					Action:       f.unlisted,
+					Filter:       "links",
+					Message:      fmt.Sprintf("Links to %s aren't allowed", host),
+					Detail:       "unlisted domain " + host,
+					ReportReason: "spam",
+				}
+			}
+		}
+	}
+	return Verdict{}
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@(\w[\w.-]*)`)
+
+// mentionFilter limits the number of distinct users mentioned in one text.
+type mentionFilter struct {
+	max    int
+	action Action
+}
+
+func (f *mentionFilter) Check(in Input) Verdict {
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(in.Text, -1) {
+		seen[strings.ToLower(strings.TrimRight(m[1], ".-"))] = true
+	}
+	if len(seen) <= f.max {
+		return Verdict{}
+	}
+	return Verdict{
+		Action:       f.action,
+		Filter:       "mentions",
+		Message:      fmt.Sprintf("You can mention at most %d people at once", f.max),
+		Detail:       fmt.Sprintf("%d mentions", len(seen)),
+		ReportReason: "spam",
+	}
+}
+
+type repeatKey struct {
+	userID int
+	sum    uint64
+}
+
+// repeatFilter catches a user sending the same text over and over, across
+// the kinds of content it tracks. History is kept in memory only.
+type repeatFilter struct {
+	max       int
+	window    time.Duration
+	minLength int
+	action    Action
+	kinds     map[string]bool
+
+	mu        sync.Mutex
+	seen      map[repeatKey][]time.Time
+	lastSweep time.Time
+}
+
+// key normalizes case and whitespace so trivial variations count as repeats.
+func (f *repeatFilter) key(in Input) (repeatKey, bool) {
+	if !f.kinds[in.Kind] {
+		return repeatKey{}, false
+	}
+	text := strings.Join(strings.Fields(strings.ToLower(in.Text)), " ")
+	if utf8.RuneCountInString(text) < f.minLength {
+		return repeatKey{}, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return repeatKey{userID: in.UserID, sum: h.Sum64()}, true
+}
+
+// recent drops the times outside the window. f.mu must be held.
+func (f *repeatFilter) recent(key repeatKey, now time.Time) []time.Time {
+	times := f.seen[key]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) > f.window {
+		i++
+	}
+	times = times[i:]
+	if len(times) == 0 {
+		delete(f.seen, key)
+	} else {
+		f.seen[key] = times
+	}
+	return times
+}
+
+func (f *repeatFilter) Check(in Input) Verdict {
+	key, ok := f.key(in)
+	if !ok {
+		return Verdict{}
+	}
+	f.mu.Lock()
+	count := len(f.recent(key, time.Now()))
+	f.mu.Unlock()
+
+	if count < f.max {
+		return Verdict{}
+	}
+	return Verdict{
+		Action:       f.action,
+		Filter:       "repeats",
+		Message:      "You've already sent this several times, please wait before sending it again",
+		Detail:       fmt.Sprintf("sent %d times within %s", count+1, f.window),
+		ReportReason: "spam",
+	}
+}
+
+func (f *repeatFilter) Record(in Input) {
+	key, ok := f.key(in)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seen[key] = append(f.recent(key, now), now)
+	if now.Sub(f.lastSweep) > f.window {
+		for k := range f.seen {
+			f.recent(k, now)
+		}
+		f.lastSweep = now
+	}
+}
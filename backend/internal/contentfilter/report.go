@@ -0,0 +1,44 @@
+package contentfilter
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ActionContentFlagged is recorded in the moderation log for flagged content.
+const ActionContentFlagged = "content.flagged"
+
+// Report queues flagged content for review: it files a report without a
+// reporter and logs it as a system action. It does nothing if nothing was
+// flagged. targetType is a report target type, so replies are "comment" and
+// group posts "post".
+func (d Decision) Report(targetType string, targetID int64, authorID int, content string) {
+	if len(d.Flags) == 0 {
+		return
+	}
+
+	filters := make([]string, 0, len(d.Flags))
+	for _, v := range d.Flags {
+		filters = append(filters, v.Filter+": "+v.Detail)
+	}
+	id, err := queries.CreateReport(models.Report{
+		TargetType:      targetType,
+		TargetID:        targetID,
+		TargetUserID:    &authorID,
+		Reason:          d.Flags[0].ReportReason,
+		Details:         "Flagged by the content filter (" + strings.Join(filters, "; ") + ")",
+		ContentSnapshot: content,
+	})
+	if err != nil {
+		fmt.Printf("contentfilter: failed to report flagged %s %d: %v\n", targetType, targetID, err)
+		return
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"filters": filters})
+	if err := queries.AddModerationLog(nil, ActionContentFlagged, targetType, targetID, &id, string(details)); err != nil {
+		fmt.Printf("contentfilter: failed to log flagged %s %d: %v\n", targetType, targetID, err)
+	}
+}
@@ -0,0 +1,259 @@
+// Package dataexport builds archives of a user's personal data. An export is
+// requested through the API, built by a scheduler job into a ZIP file of JSON
+// documents and uploaded media, and can be downloaded by its owner until it expires.
+package dataexport
+
+import (
+	"archive/zip"
+	"backend/internal/db/queries"
+	"backend/internal/media"
+	"backend/internal/models"
+	activity "backend/internal/notifications"
+	"backend/internal/scheduler"
+	"backend/internal/storage"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	jobKind        = "data_export"
+	maintenanceJob = "data_export_maintenance"
+
+	// Archives are kept outside the media store, which is served publicly
+	exportDir = "exports"
+
+	defaultTTL = 7 * 24 * time.Hour
+	// MinInterval is how long a user waits between two exports
+	MinInterval = 24 * time.Hour
+	// staleAfter is how long an unfinished export may take before it is
+	// considered interrupted, e.g. by a restart
+	staleAfter = time.Hour
+)
+
+// Register installs the export jobs. It must be called before scheduler.Run.
+func Register() {
+	scheduler.Register(jobKind, run)
+	scheduler.RegisterPeriodic(maintenanceJob, time.Hour, runMaintenance)
+}
+
+// ttl is how long an archive can be downloaded (DATA_EXPORT_TTL).
+func ttl() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DATA_EXPORT_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return defaultTTL
+}
+
+type jobPayload struct {
+	ExportID int64 `json:"export_id"`
+}
+
+// Request queues an export of a user's data and returns its ID.
+func Request(userID int) (int64, error) {
+	id, err := queries.CreateDataExport(userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := scheduler.Schedule(jobKind, fmt.Sprintf("data_export:%d", id), time.Now(), jobPayload{ExportID: id}); err != nil {
+		_ = queries.FailDataExport(id, "could not be queued")
+		return 0, err
+	}
+	return id, nil
+}
+
+func run(payload []byte) error {
+	var p jobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	started, err := queries.StartDataExport(p.ExportID)
+	if err != nil || !started {
+		return err
+	}
+	export, err := queries.GetDataExport(p.ExportID)
+	if err != nil {
+		return err
+	}
+
+	path, size, err := build(export.UserID, export.ID)
+	if err != nil {
+		if ferr := queries.FailDataExport(export.ID, "the archive could not be built"); ferr != nil {
+			fmt.Printf("dataexport: failed to mark export %d failed: %v\n", export.ID, ferr)
+		}
+		notify(export.UserID, export.ID, activity.DataExportFailed())
+		return fmt.Errorf("build export %d: %w", export.ID, err)
+	}
+
+	expiresAt := time.Now().Add(ttl())
+	finished, err := queries.FinishDataExport(export.ID, path, size, expiresAt)
+	if err != nil || !finished {
+		// Failed as stale meanwhile: the user already sees it failed, so the archive is not offered
+		os.Remove(path)
+		return err
+	}
+	notify(export.UserID, export.ID, activity.DataExportReady(expiresAt))
+	return nil
+}
+
+func notify(userID int, exportID int64, text activity.RecentActivityText) {
+	err := activity.NotifyRecentActivity(userID, nil, "data_export", text, map[string]interface{}{
+		"export_id": exportID,
+	})
+	if err != nil {
+		fmt.Printf("dataexport: failed to notify user %d about export %d: %v\n", userID, exportID, err)
+	}
+}
+
+// manifest describes the archive in export.json.
+type manifest struct {
+	UserID       int       `json:"user_id"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	Files        []string  `json:"files"`
+	Media        []string  `json:"media"`
+	MissingMedia []string  `json:"missing_media,omitempty"`
+}
+
+// build writes the archive of a user's data and returns its path and size.
+func build(userID int, exportID int64) (path string, size int64, err error) {
+	if err := os.MkdirAll(exportDir, 0o700); err != nil {
+		return "", 0, err
+	}
+	// The random part keeps archive names unguessable on disk
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", 0, err
+	}
+	path = filepath.Join(exportDir, fmt.Sprintf("%d-%s.zip", exportID, token[:32]))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	m := manifest{UserID: userID, GeneratedAt: time.Now().UTC(), Files: []string{}, Media: []string{}}
+
+	profile, err := queries.GetPersonalProfile(userID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := writeJSON(zw, "profile.json", profile, m.GeneratedAt); err != nil {
+		return "", 0, err
+	}
+	m.Files = append(m.Files, "profile.json")
+
+	for _, set := range queries.PersonalDataSets {
+		rows, err := queries.GetPersonalData(set, userID)
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", set.Name, err)
+		}
+		name := set.Name + ".json"
+		if err := writeJSON(zw, name, rows, m.GeneratedAt); err != nil {
+			return "", 0, err
+		}
+		m.Files = append(m.Files, name)
+	}
+
+	media, err := queries.GetUserMediaPaths(userID)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, stored := range media {
+		name, err := addMedia(zw, stored)
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrNotFound) {
+			m.MissingMedia = append(m.MissingMedia, stored)
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		m.Media = append(m.Media, name)
+	}
+
+	if err := writeJSON(zw, "export.json", m, m.GeneratedAt); err != nil {
+		return "", 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// addMedia copies a media file into the archive under its URL path
+// (e.g. "/media/12.jpg" becomes "media/12.jpg").
+func addMedia(zw *zip.Writer, stored string) (string, error) {
+	m, obj, err := media.Open(stored)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	// Media is already compressed, so it is stored as is
+	name := strings.TrimPrefix(m.URL, "/")
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: m.CreatedAt})
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(w, obj)
+	return name, err
+}
+
+// runMaintenance fails interrupted exports and deletes expired archives.
+func runMaintenance([]byte) error {
+	if n, err := queries.FailStaleDataExports(time.Now().Add(-staleAfter)); err != nil {
+		return err
+	} else if n > 0 {
+		fmt.Printf("dataexport: marked %d interrupted export(s) failed\n", n)
+	}
+
+	expired, err := queries.GetExpiredDataExports()
+	if err != nil {
+		return err
+	}
+	for _, export := range expired {
+		if err := Remove(export); err != nil {
+			fmt.Printf("dataexport: failed to remove expired export %d: %v\n", export.ID, err)
+		}
+	}
+	return nil
+}
+
+// Remove deletes the archive of an export and marks it expired.
+func Remove(export models.DataExport) error {
+	if export.FilePath != "" {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return queries.ExpireDataExport(export.ID)
+}
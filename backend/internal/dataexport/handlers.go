@@ -0,0 +1,134 @@
+package dataexport
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// withDownloadURL sets the download link of a ready export.
+func withDownloadURL(e models.DataExport) models.DataExport {
+	if e.Status == "ready" {
+		e.DownloadURL = fmt.Sprintf("/api/account/exports/%d/download", e.ID)
+	}
+	return e
+}
+
+// RequestExport handles POST /api/account/exports: queues an archive of the
+// current user's data. Users are notified when it is ready.
+func RequestExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	exports, err := queries.ListDataExports(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to request export"})
+		return
+	}
+	for _, e := range exports {
+		if e.Status == "pending" || e.Status == "running" {
+			utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "Your export is already being prepared"})
+			return
+		}
+		if e.Status != "failed" && time.Since(e.CreatedAt) < MinInterval {
+			utils.RespondJSON(w, http.StatusTooManyRequests, models.GenericResponse{Success: false, Message: "You can request one export per day"})
+			return
+		}
+	}
+
+	id, err := Request(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to request export"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success":   true,
+		"message":   "Your export is being prepared. We'll notify you when it is ready",
+		"export_id": id,
+	})
+}
+
+// ListExports handles GET /api/account/exports
+func ListExports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	exports, err := queries.ListDataExports(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch exports"})
+		return
+	}
+	for i := range exports {
+		exports[i] = withDownloadURL(exports[i])
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"exports": exports,
+	})
+}
+
+// DownloadExport handles GET /api/account/exports/{id}/download. Only the owner
+// can download an archive, and only until it expires.
+func DownloadExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid export ID"})
+		return
+	}
+
+	export, err := queries.GetDataExport(id)
+	if err == sql.ErrNoRows || (err == nil && export.UserID != userID) {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Export not found"})
+		return
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch export"})
+		return
+	}
+	if export.Status == "expired" || (export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now())) {
+		utils.RespondJSON(w, http.StatusGone, models.GenericResponse{Success: false, Message: "This export has expired, please request a new one"})
+		return
+	}
+	if export.Status != "ready" {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "This export is not ready"})
+		return
+	}
+
+	f, err := os.Open(export.FilePath)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to open export"})
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("social-network-export-%s.zip", export.CreatedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, r, name, *export.CompletedAt, f)
+}
@@ -0,0 +1,25 @@
+DROP TABLE IF EXISTS calendar_tokens;
+
+CREATE TABLE group_event_responses_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    response TEXT NOT NULL CHECK (response IN ('going', 'not-going')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(event_id, user_id),
+
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO group_event_responses_old (id, event_id, user_id, response, created_at)
+SELECT id, event_id, user_id, response, created_at FROM group_event_responses
+WHERE occurrence_date = '';
+
+DROP TABLE group_event_responses;
+ALTER TABLE group_event_responses_old RENAME TO group_event_responses;
+
+DROP TABLE IF EXISTS group_event_exceptions;
+
+ALTER TABLE group_events DROP COLUMN recurrence_rule;
@@ -0,0 +1,47 @@
+-- Recurrence rule (RFC 5545 RRULE subset) for repeating events; NULL = single event
+ALTER TABLE group_events ADD COLUMN recurrence_rule TEXT;
+
+-- Cancelled occurrences of a recurring event
+CREATE TABLE IF NOT EXISTS group_event_exceptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(event_id, occurrence_date),
+
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE
+);
+
+-- RSVPs are tracked per occurrence: occurrence_date is '' for single events
+-- and the occurrence's date (YYYY-MM-DD) for recurring ones
+CREATE TABLE group_event_responses_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL DEFAULT '',
+    response TEXT NOT NULL CHECK (response IN ('going', 'not-going')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(event_id, user_id, occurrence_date),
+
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO group_event_responses_new (id, event_id, user_id, occurrence_date, response, created_at)
+SELECT id, event_id, user_id, '', response, created_at FROM group_event_responses;
+
+DROP TABLE group_event_responses;
+ALTER TABLE group_event_responses_new RENAME TO group_event_responses;
+
+CREATE INDEX idx_group_event_responses_user_id ON group_event_responses(user_id);
+
+-- Private tokens for subscribing to the personal events feed
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    user_id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
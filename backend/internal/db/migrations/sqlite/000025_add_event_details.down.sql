@@ -0,0 +1,28 @@
+CREATE TABLE group_event_responses_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL DEFAULT '',
+    response TEXT NOT NULL CHECK (response IN ('going', 'not-going')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(event_id, user_id, occurrence_date),
+
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- "maybe" and "waitlisted" responses cannot be represented and are dropped
+INSERT INTO group_event_responses_old (id, event_id, user_id, occurrence_date, response, created_at)
+SELECT id, event_id, user_id, occurrence_date, response, created_at FROM group_event_responses
+WHERE response IN ('going', 'not-going');
+
+DROP TABLE group_event_responses;
+ALTER TABLE group_event_responses_old RENAME TO group_event_responses;
+
+CREATE INDEX idx_group_event_responses_user_id ON group_event_responses(user_id);
+
+ALTER TABLE group_events DROP COLUMN capacity;
+ALTER TABLE group_events DROP COLUMN location;
+ALTER TABLE group_events DROP COLUMN end_time;
+ALTER TABLE group_events DROP COLUMN end_date;
@@ -0,0 +1,30 @@
+-- End time, location and capacity of events; NULL = not set / unlimited
+ALTER TABLE group_events ADD COLUMN end_date TEXT;
+ALTER TABLE group_events ADD COLUMN end_time TEXT;
+ALTER TABLE group_events ADD COLUMN location TEXT;
+ALTER TABLE group_events ADD COLUMN capacity INTEGER CHECK (capacity IS NULL OR capacity > 0);
+
+-- Allow "maybe" and "waitlisted" responses; updated_at orders the waitlist
+CREATE TABLE group_event_responses_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL DEFAULT '',
+    response TEXT NOT NULL CHECK (response IN ('going', 'not-going', 'maybe', 'waitlisted')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(event_id, user_id, occurrence_date),
+
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO group_event_responses_new (id, event_id, user_id, occurrence_date, response, created_at, updated_at)
+SELECT id, event_id, user_id, occurrence_date, response, created_at, created_at FROM group_event_responses;
+
+DROP TABLE group_event_responses;
+ALTER TABLE group_event_responses_new RENAME TO group_event_responses;
+
+CREATE INDEX idx_group_event_responses_user_id ON group_event_responses(user_id);
+CREATE INDEX idx_group_event_responses_queue ON group_event_responses(event_id, occurrence_date, response, updated_at);
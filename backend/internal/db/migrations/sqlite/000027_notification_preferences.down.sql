@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notification_mutes_target;
+DROP TABLE IF EXISTS notification_mutes;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,23 @@
+-- Per-user opt-outs by notification type and channel; a missing row means enabled
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    channel TEXT NOT NULL CHECK (channel IN ('in_app', 'push', 'email')),
+    enabled INTEGER NOT NULL CHECK (enabled IN (0, 1)),
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type, channel),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Groups and conversations a user muted, indefinitely when muted_until is NULL
+CREATE TABLE IF NOT EXISTS notification_mutes (
+    user_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('group', 'conversation')),
+    target_id INTEGER NOT NULL,
+    muted_until INTEGER, -- unix seconds
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target_type, target_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notification_mutes_target ON notification_mutes(target_type, target_id);
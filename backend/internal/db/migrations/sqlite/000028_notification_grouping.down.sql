@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_group;
+DROP INDEX IF EXISTS idx_notifications_user_id;
+ALTER TABLE notifications DROP COLUMN group_key;
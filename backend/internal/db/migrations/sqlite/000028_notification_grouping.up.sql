@@ -0,0 +1,5 @@
+-- Notifications sharing a group_key collapse into one inbox entry
+ALTER TABLE notifications ADD COLUMN group_key TEXT;
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, id);
+CREATE INDEX idx_notifications_group ON notifications(user_id, group_key, read);
@@ -0,0 +1,3 @@
+ALTER TABLE participants DROP COLUMN last_read_message_id;
+DROP TABLE IF EXISTS app_secrets;
+DROP TABLE IF EXISTS notification_digests;
@@ -0,0 +1,28 @@
+-- Email digest settings. The next digest covers notifications and private messages
+-- with IDs above the watermarks recorded when the last one was sent.
+CREATE TABLE IF NOT EXISTS notification_digests (
+    user_id INTEGER PRIMARY KEY,
+    frequency TEXT NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'hourly', 'daily', 'weekly')),
+    last_sent_at INTEGER NOT NULL, -- unix seconds
+    last_notification_id INTEGER NOT NULL DEFAULT 0,
+    last_message_id INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Server-wide secrets such as signing keys, generated on first use
+CREATE TABLE IF NOT EXISTS app_secrets (
+    name TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tracks which private messages a participant has read
+ALTER TABLE participants ADD COLUMN last_read_message_id INTEGER NOT NULL DEFAULT 0;
+
+-- Existing conversations count as read
+UPDATE participants
+SET last_read_message_id = COALESCE(
+    (SELECT MAX(id) FROM private_chat_messages m WHERE m.conversation_id = participants.conversation_id),
+    0
+);
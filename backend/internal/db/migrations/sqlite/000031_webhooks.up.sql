@@ -0,0 +1,38 @@
+-- Outgoing webhooks of a user account (group_id NULL) or of a group
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,  -- who registered it
+    group_id INTEGER,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,      -- HMAC key shared with the receiver
+    events TEXT NOT NULL,      -- comma-separated event names
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id, group_id);
+CREATE INDEX idx_webhooks_group_id ON webhooks(group_id);
+
+-- One row per event sent to a webhook; retries update the same row
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER,   -- unix time, NULL once finished
+    response_status INTEGER,   -- HTTP status of the last attempt
+    response_body TEXT,        -- start of the last response body
+    last_error TEXT,
+    duration_ms INTEGER,
+    created_at INTEGER NOT NULL,
+    finished_at INTEGER,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries(status);
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS moderation_log_no_delete;
+DROP TRIGGER IF EXISTS moderation_log_no_update;
+DROP TABLE IF EXISTS moderation_log;
+DROP TABLE IF EXISTS reports;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN site_role;
@@ -0,0 +1,60 @@
+-- Site-wide role, independent of group ownership
+ALTER TABLE users ADD COLUMN site_role TEXT NOT NULL DEFAULT 'user' CHECK (site_role IN ('user', 'admin'));
+
+-- A suspended account can't log in. suspended_until NULL with suspended_at set means indefinitely
+ALTER TABLE users ADD COLUMN suspended_at INTEGER;
+ALTER TABLE users ADD COLUMN suspended_until INTEGER;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT;
+
+-- Reports of abusive content or profiles, reviewed by site admins
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'private_message', 'group_message', 'user')),
+    target_id INTEGER NOT NULL,
+    target_user_id INTEGER,             -- author of the content, or the reported user
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    content_snapshot TEXT NOT NULL DEFAULT '', -- the content when it was reported, kept after removal
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'triaged', 'resolved', 'dismissed')),
+    assignee_id INTEGER,
+    resolution TEXT,                    -- action taken when resolved
+    resolution_note TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    closed_at INTEGER,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A reporter has at most one pending report per target
+CREATE UNIQUE INDEX idx_reports_pending_unique ON reports(reporter_id, target_type, target_id)
+    WHERE status IN ('open', 'triaged');
+CREATE INDEX idx_reports_status ON reports(status, id);
+CREATE INDEX idx_reports_target ON reports(target_type, target_id);
+
+-- Append-only record of moderation actions. No foreign keys, so entries
+-- outlive the admins, users and content they mention
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_id INTEGER,                   -- NULL for actions taken by the system
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    report_id INTEGER,
+    details TEXT NOT NULL DEFAULT '{}', -- JSON
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX idx_moderation_log_target ON moderation_log(target_type, target_id);
+
+CREATE TRIGGER moderation_log_no_update BEFORE UPDATE ON moderation_log
+BEGIN
+    SELECT RAISE(ABORT, 'moderation_log is append-only');
+END;
+
+CREATE TRIGGER moderation_log_no_delete BEFORE DELETE ON moderation_log
+BEGIN
+    SELECT RAISE(ABORT, 'moderation_log is append-only');
+END;
@@ -0,0 +1,31 @@
+DELETE FROM reports WHERE reporter_id IS NULL;
+
+CREATE TABLE reports_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'private_message', 'group_message', 'user')),
+    target_id INTEGER NOT NULL,
+    target_user_id INTEGER,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    content_snapshot TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'triaged', 'resolved', 'dismissed')),
+    assignee_id INTEGER,
+    resolution TEXT,
+    resolution_note TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    closed_at INTEGER,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO reports_old SELECT * FROM reports;
+DROP TABLE reports;
+ALTER TABLE reports_old RENAME TO reports;
+
+CREATE UNIQUE INDEX idx_reports_pending_unique ON reports(reporter_id, target_type, target_id)
+    WHERE status IN ('open', 'triaged');
+CREATE INDEX idx_reports_status ON reports(status, id);
+CREATE INDEX idx_reports_target ON reports(target_type, target_id);
@@ -0,0 +1,31 @@
+-- Reports raised by the content filter have no reporter, so reporter_id becomes
+-- nullable. SQLite can't alter a column constraint, so the table is rebuilt
+CREATE TABLE reports_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER,                -- NULL when raised by the content filter
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'private_message', 'group_message', 'user')),
+    target_id INTEGER NOT NULL,
+    target_user_id INTEGER,             -- author of the content, or the reported user
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    content_snapshot TEXT NOT NULL DEFAULT '', -- the content when it was reported, kept after removal
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'triaged', 'resolved', 'dismissed')),
+    assignee_id INTEGER,
+    resolution TEXT,                    -- action taken when resolved
+    resolution_note TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    closed_at INTEGER,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO reports_new SELECT * FROM reports;
+DROP TABLE reports;
+ALTER TABLE reports_new RENAME TO reports;
+
+CREATE UNIQUE INDEX idx_reports_pending_unique ON reports(reporter_id, target_type, target_id)
+    WHERE status IN ('open', 'triaged');
+CREATE INDEX idx_reports_status ON reports(status, id);
+CREATE INDEX idx_reports_target ON reports(target_type, target_id);
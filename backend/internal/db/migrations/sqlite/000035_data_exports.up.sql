@@ -0,0 +1,17 @@
+-- Archives of a user's personal data, built by a background job and
+-- downloadable until expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired')),
+    file_path TEXT,                     -- the ZIP on disk while ready
+    size_bytes INTEGER,
+    error TEXT,
+    created_at INTEGER NOT NULL,
+    completed_at INTEGER,
+    expires_at INTEGER,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_data_exports_user ON data_exports(user_id, id);
+CREATE INDEX idx_data_exports_status ON data_exports(status, expires_at);
@@ -0,0 +1,2 @@
+-- When a requested account deletion runs. Logging in before then cancels it
+ALTER TABLE users ADD COLUMN deletion_scheduled_at INTEGER;
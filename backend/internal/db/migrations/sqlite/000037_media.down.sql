@@ -0,0 +1,15 @@
+-- Point imported media back at the files they came from, which the import keeps.
+-- Media uploaded since keeps its /media/ URL
+UPDATE users SET avatar = (SELECT m.legacy_path FROM media m WHERE users.avatar LIKE '/media/' || m.id || '.%')
+WHERE EXISTS (SELECT 1 FROM media m WHERE m.legacy_path IS NOT NULL AND users.avatar LIKE '/media/' || m.id || '.%');
+UPDATE posts SET image_path = (SELECT m.legacy_path FROM media m WHERE posts.image_path LIKE '/media/' || m.id || '.%')
+WHERE EXISTS (SELECT 1 FROM media m WHERE m.legacy_path IS NOT NULL AND posts.image_path LIKE '/media/' || m.id || '.%');
+UPDATE groups SET cover_image_path = (SELECT m.legacy_path FROM media m WHERE groups.cover_image_path LIKE '/media/' || m.id || '.%')
+WHERE EXISTS (SELECT 1 FROM media m WHERE m.legacy_path IS NOT NULL AND groups.cover_image_path LIKE '/media/' || m.id || '.%');
+UPDATE group_events SET image_path = (SELECT m.legacy_path FROM media m WHERE group_events.image_path LIKE '/media/' || m.id || '.%')
+WHERE EXISTS (SELECT 1 FROM media m WHERE m.legacy_path IS NOT NULL AND group_events.image_path LIKE '/media/' || m.id || '.%');
+
+DROP INDEX IF EXISTS idx_media_hash;
+DROP INDEX IF EXISTS idx_media_owner;
+DROP TABLE IF EXISTS media;
+DROP TABLE IF EXISTS media_blobs;
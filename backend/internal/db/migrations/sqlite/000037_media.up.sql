@@ -0,0 +1,27 @@
+-- Stored files, addressed by the SHA-256 of their content. Identical uploads
+-- share one blob; ref_count is the number of media rows using it and the
+-- file is deleted from the store when it drops to zero
+CREATE TABLE IF NOT EXISTS media_blobs (
+    hash TEXT PRIMARY KEY,
+    size_bytes INTEGER NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL
+);
+
+-- Uploaded media, served at /media/{id}{ext}
+CREATE TABLE IF NOT EXISTS media (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER,
+    hash TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    legacy_path TEXT,                   -- the /uploads/ path it was imported from
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (hash) REFERENCES media_blobs(hash)
+);
+
+CREATE INDEX idx_media_owner ON media(owner_id);
+CREATE INDEX idx_media_hash ON media(hash);
@@ -0,0 +1,8 @@
+-- Give back the blob references held by variants. Files of blobs left unused stay in
+-- the store, as a migration can't reach it
+UPDATE media_blobs SET ref_count = ref_count - (SELECT COUNT(*) FROM media_variants v WHERE v.hash = media_blobs.hash)
+WHERE hash IN (SELECT hash FROM media_variants);
+DELETE FROM media_blobs WHERE ref_count <= 0;
+
+DROP INDEX IF EXISTS idx_media_variants_hash;
+DROP TABLE IF EXISTS media_variants;
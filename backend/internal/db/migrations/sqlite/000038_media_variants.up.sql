@@ -0,0 +1,16 @@
+-- Scaled-down copies of an image, served at /media/{media_id}/{name}{ext}.
+-- Each holds a reference on its blob like media rows do
+CREATE TABLE IF NOT EXISTS media_variants (
+    media_id INTEGER NOT NULL,
+    name TEXT NOT NULL,                 -- 'medium', 'thumbnail'
+    hash TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    PRIMARY KEY (media_id, name),
+    FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE CASCADE,
+    FOREIGN KEY (hash) REFERENCES media_blobs(hash)
+);
+
+CREATE INDEX idx_media_variants_hash ON media_variants(hash);
@@ -0,0 +1,10 @@
+ALTER TABLE posts ADD COLUMN image_path TEXT;
+
+-- Only the first attachment of a post survives; the media of the others is
+-- left unused and deleted by the cleanup job
+UPDATE posts SET image_path = (
+    SELECT path FROM post_attachments pa WHERE pa.post_id = posts.id ORDER BY pa.position, pa.id LIMIT 1
+);
+
+DROP INDEX IF EXISTS idx_post_attachments_post;
+DROP TABLE IF EXISTS post_attachments;
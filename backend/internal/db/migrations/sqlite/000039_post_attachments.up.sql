@@ -0,0 +1,17 @@
+-- Images and videos of a post, shown in position order. path holds a media
+-- URL like the other media columns
+CREATE TABLE IF NOT EXISTS post_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_post_attachments_post ON post_attachments(post_id, position);
+
+INSERT INTO post_attachments (post_id, position, path)
+SELECT id, 0, image_path FROM posts WHERE image_path IS NOT NULL AND image_path != '';
+
+ALTER TABLE posts DROP COLUMN image_path;
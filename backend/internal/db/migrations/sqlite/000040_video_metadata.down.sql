@@ -0,0 +1,3 @@
+ALTER TABLE media DROP COLUMN audio_codec;
+ALTER TABLE media DROP COLUMN video_codec;
+ALTER TABLE media DROP COLUMN duration_ms;
@@ -0,0 +1,5 @@
+-- What the container of an uploaded video says about it. Videos also get
+-- 'poster' and 'thumbnail' rows in media_variants
+ALTER TABLE media ADD COLUMN duration_ms INTEGER;
+ALTER TABLE media ADD COLUMN video_codec TEXT;
+ALTER TABLE media ADD COLUMN audio_codec TEXT;
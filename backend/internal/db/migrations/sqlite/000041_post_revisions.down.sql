@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_post_revisions_post;
+DROP TABLE IF EXISTS post_revisions;
+ALTER TABLE posts DROP COLUMN edited_at;
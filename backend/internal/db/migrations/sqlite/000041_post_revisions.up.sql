@@ -0,0 +1,14 @@
+-- When a post was last edited, NULL if never
+ALTER TABLE posts ADD COLUMN edited_at DATETIME;
+
+-- The texts a post had. The first edit records the original (edited_at NULL),
+-- then every edit records the new text, so the last row is the current one
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    edited_at DATETIME,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_post_revisions_post ON post_revisions(post_id, id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_post_selected_followers_post_user;
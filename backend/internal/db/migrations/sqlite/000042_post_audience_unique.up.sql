@@ -0,0 +1,5 @@
+-- Nothing stopped a follower from being added twice to the audience of a post
+DELETE FROM post_selected_followers
+WHERE id NOT IN (SELECT MIN(id) FROM post_selected_followers GROUP BY post_id, user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_selected_followers_post_user ON post_selected_followers(post_id, user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_audience_list;
+ALTER TABLE posts DROP COLUMN audience_list_id;
+DROP TABLE IF EXISTS audience_list_members;
+DROP TABLE IF EXISTS audience_lists;
@@ -0,0 +1,30 @@
+-- Named lists of followers a user shares posts with, such as "close friends".
+-- Posts shared with a list are visible to its members at the time of viewing
+CREATE TABLE IF NOT EXISTS audience_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audience_lists_owner_name ON audience_lists(owner_id, name);
+
+CREATE TABLE IF NOT EXISTS audience_list_members (
+    list_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, user_id),
+    FOREIGN KEY (list_id) REFERENCES audience_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_list_members_user ON audience_list_members(user_id);
+
+-- The list a "selected" post is shared with instead of post_selected_followers.
+-- Rebuilding posts to allow a new privacy value would cascade to everything that
+-- references it, so list posts stay "selected". When the list is deleted the
+-- post is left with an empty audience: only its author sees it
+ALTER TABLE posts ADD COLUMN audience_list_id INTEGER REFERENCES audience_lists(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list ON posts(audience_list_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_group_events_media;
+DROP INDEX IF EXISTS idx_groups_media;
+DROP INDEX IF EXISTS idx_post_attachments_media;
+DROP INDEX IF EXISTS idx_users_media;
+ALTER TABLE group_events DROP COLUMN media_id;
+ALTER TABLE groups DROP COLUMN media_id;
+ALTER TABLE post_attachments DROP COLUMN media_id;
+ALTER TABLE users DROP COLUMN media_id;
@@ -0,0 +1,16 @@
+-- The media record each row refers to, read from its /media/{id}{ext} URL, so
+-- media access checks and the cleanup of unused uploads are index lookups.
+-- Generated columns follow every write of the URL; other URLs give NULL
+ALTER TABLE users ADD COLUMN media_id INTEGER
+    GENERATED ALWAYS AS (CASE WHEN avatar LIKE '/media/%' THEN NULLIF(CAST(substr(avatar, 8) AS INTEGER), 0) END) VIRTUAL;
+ALTER TABLE post_attachments ADD COLUMN media_id INTEGER
+    GENERATED ALWAYS AS (CASE WHEN path LIKE '/media/%' THEN NULLIF(CAST(substr(path, 8) AS INTEGER), 0) END) VIRTUAL;
+ALTER TABLE groups ADD COLUMN media_id INTEGER
+    GENERATED ALWAYS AS (CASE WHEN cover_image_path LIKE '/media/%' THEN NULLIF(CAST(substr(cover_image_path, 8) AS INTEGER), 0) END) VIRTUAL;
+ALTER TABLE group_events ADD COLUMN media_id INTEGER
+    GENERATED ALWAYS AS (CASE WHEN image_path LIKE '/media/%' THEN NULLIF(CAST(substr(image_path, 8) AS INTEGER), 0) END) VIRTUAL;
+
+CREATE INDEX IF NOT EXISTS idx_users_media ON users(media_id);
+CREATE INDEX IF NOT EXISTS idx_post_attachments_media ON post_attachments(media_id);
+CREATE INDEX IF NOT EXISTS idx_groups_media ON groups(media_id);
+CREATE INDEX IF NOT EXISTS idx_group_events_media ON group_events(media_id);
@@ -0,0 +1,142 @@
+package queries
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScheduleAccountDeletion schedules the deletion of an account and deletes its sessions.
+func ScheduleAccountDeletion(userID int, at time.Time) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET deletion_scheduled_at = ? WHERE id = ?`, at.Unix(), userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelAccountDeletion cancels a scheduled deletion and reports whether one was scheduled.
+func CancelAccountDeletion(userID int) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE users SET deletion_scheduled_at = NULL WHERE id = ? AND deletion_scheduled_at IS NOT NULL
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetAccountDeletionTime returns when the account is scheduled for deletion, or
+// nil if it is not.
+func GetAccountDeletionTime(userID int) (*time.Time, error) {
+	var at sql.NullInt64
+	err := DB.QueryRow(`SELECT deletion_scheduled_at FROM users WHERE id = ?`, userID).Scan(&at)
+	if err == sql.ErrNoRows || (err == nil && !at.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(at.Int64, 0).UTC()
+	return &t, nil
+}
+
+// OwnedGroup is a group owned by an account being deleted, with the member who
+// takes it over (0 if nobody is left and the group goes away with the account).
+type OwnedGroup struct {
+	ID             int64
+	Name           string
+	CoverImagePath string
+	SuccessorID    int
+}
+
+// GetOwnedGroupsForDeletion returns the groups owned by a user. The successor
+// of each group is its longest-standing other member.
+func GetOwnedGroupsForDeletion(userID int) ([]OwnedGroup, error) {
+	rows, err := DB.Query(`
+		SELECT g.id, g.name, COALESCE(g.cover_image_path, ''),
+			COALESCE((SELECT m.user_id FROM group_members m
+			          WHERE m.group_id = g.id AND m.user_id != g.owner_id
+			          ORDER BY m.joined_at, m.id LIMIT 1), 0)
+		FROM groups g WHERE g.owner_id = ?
+		ORDER BY g.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]OwnedGroup, 0)
+	for rows.Next() {
+		var g OwnedGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.CoverImagePath, &g.SuccessorID); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroupMediaPaths returns the stored paths of every uploaded file of a group:
+// its cover and the images of its posts and events.
+func GetGroupMediaPaths(groupID int64) ([]string, error) {
+	rows, err := DB.Query(`
+		SELECT cover_image_path FROM groups WHERE id = ?1 AND cover_image_path IS NOT NULL AND cover_image_path != ''
+		UNION SELECT pa.path FROM post_attachments pa JOIN posts p ON p.id = pa.post_id WHERE p.group_id = ?1
+		UNION SELECT image_path FROM group_events WHERE group_id = ?1 AND image_path IS NOT NULL AND image_path != ''
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// DeleteAccount hands the groups of a user over to their successors, deletes
+// the groups without one, and deletes the account. Everything else of the user
+// goes with it through foreign keys. It returns sql.ErrNoRows, and changes
+// nothing, if the deletion was cancelled in the meantime.
+func DeleteAccount(userID int, groups []OwnedGroup) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, g := range groups {
+		if g.SuccessorID != 0 {
+			_, err = tx.Exec(`UPDATE groups SET owner_id = ? WHERE id = ? AND owner_id = ?`, g.SuccessorID, g.ID, userID)
+		} else {
+			_, err = tx.Exec(`DELETE FROM groups WHERE id = ? AND owner_id = ?`, g.ID, userID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM users WHERE id = ? AND deletion_scheduled_at IS NOT NULL`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
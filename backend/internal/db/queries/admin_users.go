@@ -0,0 +1,167 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const adminUserColumns = `
+	u.id, u.username, u.email, u.first_name, u.last_name, COALESCE(u.avatar, ''), u.site_role, u.is_verified,
+	u.created_at, u.suspended_at, u.suspended_until, COALESCE(u.suspension_reason, ''),
+	(SELECT COUNT(*) FROM reports r WHERE r.target_user_id = u.id AND r.status IN ('open', 'triaged'))`
+
+// activeSuspension is the SQL condition "u is currently suspended".
+const activeSuspension = `(u.suspended_at IS NOT NULL AND (u.suspended_until IS NULL OR u.suspended_until > @now))`
+
+type adminUserScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAdminUser(row adminUserScanner) (models.AdminUser, error) {
+	var u models.AdminUser
+	var suspendedAt, until sql.NullInt64
+	var reason string
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.FirstName, &u.LastName, &u.Avatar, &u.SiteRole, &u.IsVerified,
+		&u.CreatedAt, &suspendedAt, &until, &reason, &u.PendingReports)
+	if err != nil {
+		return u, err
+	}
+
+	if suspendedAt.Valid {
+		s := &models.Suspension{SuspendedAt: time.Unix(suspendedAt.Int64, 0).UTC(), Reason: reason}
+		if until.Valid {
+			t := time.Unix(until.Int64, 0).UTC()
+			s.Until = &t
+		}
+		if s.Until == nil || s.Until.After(time.Now()) {
+			u.Suspension = s
+		}
+	}
+	return u, nil
+}
+
+// AdminUserFilter selects users in the site admin search. Empty fields match everyone.
+type AdminUserFilter struct {
+	Term      string // part of the username, email or name
+	Suspended bool   // only currently suspended users
+	Role      string
+	Before    int // only users with a lower ID (0 for the first page)
+}
+
+// SearchUsersForAdmin returns up to limit users matching filter, newest first.
+func SearchUsersForAdmin(filter AdminUserFilter, limit int) ([]models.AdminUser, error) {
+	rows, err := DB.Query(`
+		SELECT `+adminUserColumns+`
+		FROM users u
+		WHERE (@term = ''
+		       OR LOWER(u.username) LIKE LOWER(@like)
+		       OR LOWER(u.email) LIKE LOWER(@like)
+		       OR LOWER(u.first_name || ' ' || u.last_name) LIKE LOWER(@like))
+		  AND (@suspended = 0 OR `+activeSuspension+`)
+		  AND (@role = '' OR u.site_role = @role)
+		  AND (@before = 0 OR u.id < @before)
+		ORDER BY u.id DESC
+		LIMIT @limit
+	`, sql.Named("term", filter.Term), sql.Named("like", "%"+filter.Term+"%"), sql.Named("suspended", filter.Suspended),
+		sql.Named("now", time.Now().Unix()), sql.Named("role", filter.Role), sql.Named("before", filter.Before),
+		sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.AdminUser, 0)
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetAdminUser returns a user for the site admin tools. It returns sql.ErrNoRows if it does not exist.
+func GetAdminUser(userID int) (models.AdminUser, error) {
+	return scanAdminUser(DB.QueryRow(`SELECT `+adminUserColumns+` FROM users u WHERE u.id = ?`, userID))
+}
+
+// UserActivityCounts summarizes what a user has created.
+type UserActivityCounts struct {
+	Posts         int `json:"posts"`
+	Comments      int `json:"comments"`
+	GroupMessages int `json:"group_messages"`
+	ReportsFiled  int `json:"reports_filed"`
+	Sessions      int `json:"sessions"`
+}
+
+// GetUserActivityCounts counts the content, filed reports and open sessions of a user.
+func GetUserActivityCounts(userID int) (UserActivityCounts, error) {
+	var c UserActivityCounts
+	err := DB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM posts WHERE user_id = ?1),
+			(SELECT COUNT(*) FROM comments WHERE user_id = ?1),
+			(SELECT COUNT(*) FROM group_chat_messages WHERE user_id = ?1),
+			(SELECT COUNT(*) FROM reports WHERE reporter_id = ?1),
+			(SELECT COUNT(*) FROM sessions WHERE user_id = ?1)
+	`, userID).Scan(&c.Posts, &c.Comments, &c.GroupMessages, &c.ReportsFiled, &c.Sessions)
+	return c, err
+}
+
+// GetUserContent returns up to limit posts, comments or group messages of a user
+// (contentType "post", "comment" or "group_message"), newest first, below the ID before.
+// Privacy settings don't apply: this is for site admins only.
+func GetUserContent(userID int, contentType string, before int64, limit int) ([]models.UserContentItem, error) {
+	var query string
+	switch contentType {
+	case "post":
+		query = `SELECT id, content, privacy, NULL, group_id, created_at FROM posts`
+	case "comment":
+		query = `SELECT id, content, '', post_id, NULL, created_at FROM comments`
+	case "group_message":
+		query = `SELECT id, content, '', NULL, group_id, created_at FROM group_chat_messages`
+	default:
+		return nil, fmt.Errorf("unknown content type %q", contentType)
+	}
+
+	rows, err := DB.Query(query+`
+		WHERE user_id = ? AND (? = 0 OR id < ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, before, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.UserContentItem, 0)
+	for rows.Next() {
+		item := models.UserContentItem{Type: contentType}
+		if err := rows.Scan(&item.ID, &item.Content, &item.Privacy, &item.PostID, &item.GroupID, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil || contentType != "post" {
+		return items, err
+	}
+	rows.Close()
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	attachments, err := GetPostAttachments(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		for _, a := range attachments[items[i].ID] {
+			items[i].Attachments = append(items[i].Attachments, a.URL)
+		}
+	}
+	return items, nil
+}
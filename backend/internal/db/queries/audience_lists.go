@@ -0,0 +1,186 @@
+package queries
+
+import "backend/internal/models"
+
+const audienceListColumns = `
+	l.id,
+	l.owner_id,
+	l.name,
+	(SELECT COUNT(*) FROM audience_list_members m WHERE m.list_id = l.id) AS member_count,
+	l.created_at`
+
+func scanAudienceList(row interface{ Scan(dest ...any) error }) (models.AudienceList, error) {
+	var l models.AudienceList
+	err := row.Scan(&l.ID, &l.OwnerID, &l.Name, &l.MemberCount, &l.CreatedAt)
+	return l, err
+}
+
+// GetAudienceLists returns the audience lists of a user, by name.
+func GetAudienceLists(ownerID int) ([]models.AudienceList, error) {
+	rows, err := DB.Query(`
+		SELECT `+audienceListColumns+`
+		FROM audience_lists l
+		WHERE l.owner_id = ?
+		ORDER BY l.name COLLATE NOCASE, l.id
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]models.AudienceList, 0)
+	for rows.Next() {
+		l, err := scanAudienceList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+// GetAudienceList returns an audience list.
+// Returns sql.ErrNoRows when it does not exist.
+func GetAudienceList(listID int64) (models.AudienceList, error) {
+	return scanAudienceList(DB.QueryRow(`
+		SELECT `+audienceListColumns+`
+		FROM audience_lists l
+		WHERE l.id = ?
+	`, listID))
+}
+
+// CreateAudienceList creates an empty audience list. Names are unique per
+// owner, so a name already used fails with a UNIQUE constraint error.
+func CreateAudienceList(ownerID int, name string) (int64, error) {
+	res, err := DB.Exec(`INSERT INTO audience_lists (owner_id, name) VALUES (?, ?)`, ownerID, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RenameAudienceList changes the name of an audience list, failing like
+// CreateAudienceList when the owner has another list of that name.
+func RenameAudienceList(listID int64, name string) error {
+	_, err := DB.Exec(`UPDATE audience_lists SET name = ? WHERE id = ?`, name, listID)
+	return err
+}
+
+// DeleteAudienceList deletes an audience list. The posts shared with it are
+// left with an empty audience, visible to their author only.
+func DeleteAudienceList(listID int64) error {
+	_, err := DB.Exec(`DELETE FROM audience_lists WHERE id = ?`, listID)
+	return err
+}
+
+// GetAudienceListMembers returns the members of an audience list, with the
+// follow status of its owner toward each.
+func GetAudienceListMembers(listID int64, ownerID int) ([]models.UserSearchResult, error) {
+	rows, err := DB.Query(`
+		SELECT
+			u.id,
+			u.username,
+			u.first_name,
+			u.last_name,
+			COALESCE(u.nickname, '')   AS nickname,
+			COALESCE(u.avatar, '')     AS avatar,
+			COALESCE(u.about_me, '')   AS about_me,
+			u.is_public,
+			COALESCE(f.status, 'none') AS follow_status,
+			CASE WHEN fm.follower_id IS NOT NULL THEN 1 ELSE 0 END AS follows_me
+		FROM audience_list_members alm
+		JOIN users u ON u.id = alm.user_id
+		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
+		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
+		WHERE alm.list_id = ?
+		ORDER BY u.first_name, u.last_name
+	`, ownerID, ownerID, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanUserResults(rows)
+}
+
+// GetAudienceListMemberIDs returns the IDs of the members of an audience list.
+func GetAudienceListMemberIDs(listID int64) ([]int, error) {
+	rows, err := DB.Query(`SELECT user_id FROM audience_list_members WHERE list_id = ?`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AddAudienceListMembers adds userIDs to an audience list. Users who are not
+// accepted followers of the owner are skipped, in case they stopped following
+// since the caller checked.
+func AddAudienceListMembers(listID int64, ownerID int, userIDs []int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range userIDs {
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO audience_list_members (list_id, user_id)
+			SELECT ?, ? WHERE EXISTS(
+				SELECT 1 FROM followers WHERE follower_id = ? AND following_id = ? AND status = 'accepted'
+			)
+		`, listID, id, id, ownerID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveAudienceListMember removes a user from an audience list. It reports
+// whether they were in it.
+func RemoveAudienceListMember(listID int64, userID int) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM audience_list_members WHERE list_id = ? AND user_id = ?`, listID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetAudienceListPostIDs returns the IDs of the posts shared with an audience
+// list.
+func GetAudienceListPostIDs(listID int64) ([]int64, error) {
+	rows, err := DB.Query(`SELECT id FROM posts WHERE audience_list_id = ? AND privacy = 'selected'`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// IsAudienceListOwner reports whether userID owns the audience list.
+func IsAudienceListOwner(listID int64, userID int) (bool, error) {
+	var owns bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM audience_lists WHERE id = ? AND owner_id = ?)
+	`, listID, userID).Scan(&owns)
+	return owns, err
+}
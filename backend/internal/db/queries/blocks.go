@@ -0,0 +1,165 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"fmt"
+)
+
+// notBlockedWith returns the SQL condition "no block exists, in either direction,
+// between the user in column and the viewer". viewer is the viewer's placeholder:
+// "@viewer" for named arguments, or "?" in which case the viewer ID must be bound twice.
+func notBlockedWith(column, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS(
+		SELECT 1 FROM user_blocks ub
+		WHERE (ub.blocker_id = %[2]s AND ub.blocked_id = %[1]s)
+		   OR (ub.blocker_id = %[1]s AND ub.blocked_id = %[2]s)
+	)`, column, viewer)
+}
+
+// BlockUser records that blockerID blocks blockedID and removes what either
+// granted the other, in both directions: follows and follow requests, places in
+// the audience of posts and in audience lists, and pending group invitations.
+// None of it comes back when the block is lifted.
+func BlockUser(blockerID, blockedID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)
+	`, blockerID, blockedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM followers
+		WHERE (follower_id = ?1 AND following_id = ?2) OR (follower_id = ?2 AND following_id = ?1)
+	`, blockerID, blockedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM post_selected_followers
+		WHERE (user_id = ?2 AND post_id IN (SELECT id FROM posts WHERE user_id = ?1))
+		   OR (user_id = ?1 AND post_id IN (SELECT id FROM posts WHERE user_id = ?2))
+	`, blockerID, blockedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM audience_list_members
+		WHERE (user_id = ?2 AND list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?1))
+		   OR (user_id = ?1 AND list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?2))
+	`, blockerID, blockedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM group_invitations
+		WHERE status = 'pending'
+		  AND ((inviter_id = ?1 AND invited_user_id = ?2) OR (inviter_id = ?2 AND invited_user_id = ?1))
+	`, blockerID, blockedID); err != nil {
+		return err
+	}
+	// A block also replaces a mute
+	if _, err := tx.Exec(`DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?`, blockerID, blockedID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnblockUser removes a block. What the block removed is not restored.
+func UnblockUser(blockerID, blockedID int) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// HasBlocked reports whether blockerID blocks blockedID.
+func HasBlocked(blockerID, blockedID int) (bool, error) {
+	var blocked bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)
+	`, blockerID, blockedID).Scan(&blocked)
+	return blocked, err
+}
+
+// IsBlockedBetween reports whether either user blocks the other.
+func IsBlockedBetween(user1ID, user2ID int) (bool, error) {
+	var blocked bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ?1 AND blocked_id = ?2) OR (blocker_id = ?2 AND blocked_id = ?1)
+		)
+	`, user1ID, user2ID).Scan(&blocked)
+	return blocked, err
+}
+
+// MuteUser hides mutedID's posts from muterID's feed.
+func MuteUser(muterID, mutedID int) error {
+	_, err := DB.Exec(`INSERT OR IGNORE INTO user_mutes (muter_id, muted_id) VALUES (?, ?)`, muterID, mutedID)
+	return err
+}
+
+// UnmuteUser removes a mute and reports whether it existed.
+func UnmuteUser(muterID, mutedID int) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?`, muterID, mutedID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// HasMuted reports whether muterID mutes mutedID.
+func HasMuted(muterID, mutedID int) (bool, error) {
+	var muted bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_mutes WHERE muter_id = ? AND muted_id = ?)
+	`, muterID, mutedID).Scan(&muted)
+	return muted, err
+}
+
+// GetBlockedUsers returns the users blocked by userID, most recent first.
+func GetBlockedUsers(userID int) ([]models.UserSearchResult, error) {
+	return listRelatedUsers(`SELECT blocked_id AS user_id, created_at FROM user_blocks WHERE blocker_id = ?`, userID)
+}
+
+// GetMutedUsers returns the users muted by userID, most recent first.
+func GetMutedUsers(userID int) ([]models.UserSearchResult, error) {
+	return listRelatedUsers(`SELECT muted_id AS user_id, created_at FROM user_mutes WHERE muter_id = ?`, userID)
+}
+
+func listRelatedUsers(relation string, userID int) ([]models.UserSearchResult, error) {
+	rows, err := DB.Query(`
+		SELECT
+			u.id,
+			u.username,
+			u.first_name,
+			u.last_name,
+			COALESCE(u.nickname, '')   AS nickname,
+			COALESCE(u.avatar, '')     AS avatar,
+			COALESCE(u.about_me, '')   AS about_me,
+			u.is_public,
+			COALESCE(f.status, 'none') AS follow_status,
+			CASE WHEN fm.follower_id IS NOT NULL THEN 1 ELSE 0 END AS follows_me
+		FROM (`+relation+`) rel
+		JOIN users u ON u.id = rel.user_id
+		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
+		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
+		ORDER BY rel.created_at DESC
+	`, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results, err := scanUserResults(rows)
+	if results == nil {
+		results = make([]models.UserSearchResult, 0)
+	}
+	return results, err
+}
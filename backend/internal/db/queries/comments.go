@@ -6,15 +6,17 @@ import (
 )
 
 // GetComments returns top-level comments for a post (no replies), oldest first,
-// including a count of replies for each.
-func GetComments(postID int64) ([]models.Comment, error) {
+// including a count of replies for each. Comments of users blocked either way by
+// viewerID are left out.
+func GetComments(postID int64, viewerID int) ([]models.Comment, error) {
 	rows, err := DB.Query(`
 		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at,
 		       (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS replies_count
 		FROM comments c
 		WHERE c.post_id = ? AND c.parent_id IS NULL
+		  AND `+notBlockedWith("c.user_id", "?")+`
 		ORDER BY c.created_at ASC
-	`, postID)
+	`, postID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -31,14 +33,16 @@ func GetComments(postID int64) ([]models.Comment, error) {
 	return comments, rows.Err()
 }
 
-// GetReplies returns all replies for a given comment, oldest first.
-func GetReplies(commentID int64) ([]models.Comment, error) {
+// GetReplies returns all replies for a given comment, oldest first, leaving out
+// replies of users blocked either way by viewerID.
+func GetReplies(commentID int64, viewerID int) ([]models.Comment, error) {
 	rows, err := DB.Query(`
-		SELECT id, post_id, user_id, content, created_at, parent_id
-		FROM comments
-		WHERE parent_id = ?
-		ORDER BY created_at ASC
-	`, commentID)
+		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.parent_id
+		FROM comments c
+		WHERE c.parent_id = ?
+		  AND `+notBlockedWith("c.user_id", "?")+`
+		ORDER BY c.created_at ASC
+	`, commentID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -116,9 +120,23 @@ func UpdateComment(commentID int64, callerID int, content string) error {
 	return nil
 }
 
+// GetCommentContent returns the text of a comment (or reply).
+func GetCommentContent(commentID int64) (string, error) {
+	var content string
+	err := DB.QueryRow(`SELECT content FROM comments WHERE id = ?`, commentID).Scan(&content)
+	return content, err
+}
+
 // GetCommentCount returns the total number of comments (including replies) for a post.
 func GetCommentCount(postID int64) (int, error) {
 	var count int
 	err := DB.QueryRow(`SELECT COUNT(*) FROM comments WHERE post_id = ?`, postID).Scan(&count)
 	return count, err
 }
+
+// GetCommentPostID returns the post_id of the comment (or reply).
+func GetCommentPostID(commentID int64) (int64, error) {
+	var postID int64
+	err := DB.QueryRow(`SELECT post_id FROM comments WHERE id = ?`, commentID).Scan(&postID)
+	return postID, err
+}
@@ -0,0 +1,283 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+const dataExportColumns = `id, user_id, status, file_path, size_bytes, error, created_at, completed_at, expires_at`
+
+type dataExportScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDataExport(row dataExportScanner) (models.DataExport, error) {
+	var e models.DataExport
+	var filePath, errMsg sql.NullString
+	var size, completedAt, expiresAt sql.NullInt64
+	var createdAt int64
+	if err := row.Scan(&e.ID, &e.UserID, &e.Status, &filePath, &size, &errMsg, &createdAt, &completedAt, &expiresAt); err != nil {
+		return e, err
+	}
+	e.FilePath = filePath.String
+	e.SizeBytes = size.Int64
+	e.Error = errMsg.String
+	e.CreatedAt = time.Unix(createdAt, 0).UTC()
+	if completedAt.Valid {
+		t := time.Unix(completedAt.Int64, 0).UTC()
+		e.CompletedAt = &t
+	}
+	if expiresAt.Valid {
+		t := time.Unix(expiresAt.Int64, 0).UTC()
+		e.ExpiresAt = &t
+	}
+	return e, nil
+}
+
+// CreateDataExport queues a new export of a user's data.
+func CreateDataExport(userID int) (int64, error) {
+	res, err := DB.Exec(`INSERT INTO data_exports (user_id, created_at) VALUES (?, ?)`, userID, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetDataExport returns an export. It returns sql.ErrNoRows if it does not exist.
+func GetDataExport(id int64) (models.DataExport, error) {
+	return scanDataExport(DB.QueryRow(`SELECT `+dataExportColumns+` FROM data_exports WHERE id = ?`, id))
+}
+
+// ListDataExports returns the exports of a user, newest first.
+func ListDataExports(userID int) ([]models.DataExport, error) {
+	return queryDataExports(`SELECT `+dataExportColumns+` FROM data_exports WHERE user_id = ? ORDER BY id DESC`, userID)
+}
+
+func queryDataExports(query string, args ...any) ([]models.DataExport, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := make([]models.DataExport, 0)
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// StartDataExport moves a pending export to running. It reports false if the
+// export is gone or was already started.
+func StartDataExport(id int64) (bool, error) {
+	res, err := DB.Exec(`UPDATE data_exports SET status = 'running' WHERE id = ? AND status = 'pending'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FinishDataExport marks a running export ready for download until expiresAt.
+// It reports false if the export is gone or no longer running, e.g. because it
+// was failed as stale in the meantime.
+func FinishDataExport(id int64, filePath string, size int64, expiresAt time.Time) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE data_exports SET status = 'ready', file_path = ?, size_bytes = ?, completed_at = ?, expires_at = ?
+		WHERE id = ? AND status = 'running'
+	`, filePath, size, time.Now().Unix(), expiresAt.Unix(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FailDataExport marks an export failed.
+func FailDataExport(id int64, message string) error {
+	_, err := DB.Exec(`
+		UPDATE data_exports SET status = 'failed', error = ?, completed_at = ? WHERE id = ?
+	`, message, time.Now().Unix(), id)
+	return err
+}
+
+// FailStaleDataExports marks exports still pending or running after they were
+// created before the given time as failed, e.g. after a restart interrupted them.
+func FailStaleDataExports(before time.Time) (int64, error) {
+	res, err := DB.Exec(`
+		UPDATE data_exports SET status = 'failed', error = 'interrupted', completed_at = ?
+		WHERE status IN ('pending', 'running') AND created_at < ?
+	`, time.Now().Unix(), before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetExpiredDataExports returns the ready exports whose download link has expired.
+func GetExpiredDataExports() ([]models.DataExport, error) {
+	return queryDataExports(`
+		SELECT `+dataExportColumns+` FROM data_exports WHERE status = 'ready' AND expires_at <= ?
+	`, time.Now().Unix())
+}
+
+// ExpireDataExport marks an export expired once its file is deleted.
+func ExpireDataExport(id int64) error {
+	_, err := DB.Exec(`UPDATE data_exports SET status = 'expired', file_path = NULL WHERE id = ?`, id)
+	return err
+}
+
+// PersonalDataSet is one file of a personal data export: a query of rows
+// belonging to the user, whose only parameter is the user ID.
+type PersonalDataSet struct {
+	Name  string
+	Query string
+}
+
+// PersonalDataSets lists what goes into a personal data export. Private messages
+// include both sides of the user's conversations; group chat messages only the
+// user's own.
+var PersonalDataSets = []PersonalDataSet{
+	{"posts", `
+		SELECT p.id, p.group_id, g.name AS group_name, p.content, p.privacy, p.created_at, p.edited_at
+		FROM posts p LEFT JOIN groups g ON g.id = p.group_id
+		WHERE p.user_id = ?1 ORDER BY p.id`},
+	{"post_revisions", `
+		SELECT r.post_id, r.content, r.edited_at
+		FROM post_revisions r JOIN posts p ON p.id = r.post_id
+		WHERE p.user_id = ?1 ORDER BY r.post_id, r.id`},
+	{"post_attachments", `
+		SELECT pa.post_id, pa.position, pa.path, pa.alt_text
+		FROM post_attachments pa JOIN posts p ON p.id = pa.post_id
+		WHERE p.user_id = ?1 ORDER BY pa.post_id, pa.position, pa.id`},
+	{"comments", `
+		SELECT c.id, c.post_id, c.parent_id, c.content, c.created_at
+		FROM comments c WHERE c.user_id = ?1 ORDER BY c.id`},
+	{"likes", `
+		SELECT l.post_id, u.username AS post_author, l.created_at
+		FROM post_likes l JOIN posts p ON p.id = l.post_id JOIN users u ON u.id = p.user_id
+		WHERE l.user_id = ?1 ORDER BY l.id`},
+	{"followers", `
+		SELECT u.username, f.status, f.created_at
+		FROM followers f JOIN users u ON u.id = f.follower_id
+		WHERE f.following_id = ?1 ORDER BY f.id`},
+	{"following", `
+		SELECT u.username, f.status, f.created_at
+		FROM followers f JOIN users u ON u.id = f.following_id
+		WHERE f.follower_id = ?1 ORDER BY f.id`},
+	{"audience_lists", `
+		SELECT l.id, l.name, u.username AS member, m.added_at, l.created_at
+		FROM audience_lists l
+		LEFT JOIN audience_list_members m ON m.list_id = l.id
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE l.owner_id = ?1 ORDER BY l.id, u.username`},
+	{"groups", `
+		SELECT g.id, g.name, g.description, g.cover_image_path, g.owner_id = ?1 AS is_owner, m.joined_at
+		FROM group_members m JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = ?1 ORDER BY m.id`},
+	{"events", `
+		SELECT e.id, e.group_id, g.name AS group_name, e.title, e.description, e.event_date, e.event_time,
+			e.end_date, e.end_time, e.location, e.capacity, e.recurrence_rule, e.image_path, e.created_at
+		FROM group_events e JOIN groups g ON g.id = e.group_id
+		WHERE e.creator_id = ?1 ORDER BY e.id`},
+	{"event_responses", `
+		SELECT r.event_id, e.title AS event_title, r.occurrence_date, r.response, r.created_at, r.updated_at
+		FROM group_event_responses r JOIN group_events e ON e.id = r.event_id
+		WHERE r.user_id = ?1 ORDER BY r.id`},
+	{"private_messages", `
+		SELECT m.id, m.conversation_id, u.username AS sender, m.content, m.created_at
+		FROM private_chat_messages m
+		JOIN participants p ON p.conversation_id = m.conversation_id AND p.user_id = ?1
+		JOIN users u ON u.id = m.user_id
+		ORDER BY m.id`},
+	{"group_messages", `
+		SELECT m.id, m.group_id, g.name AS group_name, m.content, m.created_at
+		FROM group_chat_messages m JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = ?1 ORDER BY m.id`},
+	{"notifications", `
+		SELECT n.id, n.type, a.username AS actor, n.data, n.read, n.created_at
+		FROM notifications n LEFT JOIN users a ON a.id = n.actor_id
+		WHERE n.user_id = ?1 ORDER BY n.id`},
+}
+
+// GetPersonalData runs a personal data set for a user. Each row maps column
+// names to values; text stored as bytes is returned as a string.
+func GetPersonalData(set PersonalDataSet, userID int) ([]map[string]interface{}, error) {
+	rows, err := DB.Query(set.Query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[col] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// GetPersonalProfile returns the account of a user for a data export, without
+// its password hash.
+func GetPersonalProfile(userID int) (map[string]interface{}, error) {
+	rows, err := GetPersonalData(PersonalDataSet{Query: `
+		SELECT id, email, username, first_name, last_name, date_of_birth, nickname, about_me, avatar,
+			is_public, is_verified, site_role, created_at
+		FROM users WHERE id = ?1`}, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// GetUserMediaPaths returns the stored paths of the uploaded files a user owns:
+// their avatar, the images of their posts and events, and the covers of the
+// groups they own.
+func GetUserMediaPaths(userID int) ([]string, error) {
+	rows, err := DB.Query(`
+		SELECT avatar FROM users WHERE id = ?1 AND avatar IS NOT NULL AND avatar != ''
+		UNION SELECT pa.path FROM post_attachments pa JOIN posts p ON p.id = pa.post_id WHERE p.user_id = ?1
+		UNION SELECT image_path FROM group_events WHERE creator_id = ?1 AND image_path IS NOT NULL AND image_path != ''
+		UNION SELECT cover_image_path FROM groups WHERE owner_id = ?1 AND cover_image_path IS NOT NULL AND cover_image_path != ''
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
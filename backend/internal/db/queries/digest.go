@@ -0,0 +1,206 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+// DigestRecipient is a user whose email digest is due.
+type DigestRecipient struct {
+	UserID             int
+	Frequency          string
+	LastNotificationID int64
+	LastMessageID      int64
+}
+
+// DigestConversation summarises the unread messages of one sender in a conversation.
+type DigestConversation struct {
+	ConversationID  int
+	SenderUsername  string
+	SenderFirstName string
+	Count           int
+	LastMessage     string
+}
+
+// GetDigestFrequency returns the digest frequency of a user, "off" when never set.
+func GetDigestFrequency(userID int) (string, error) {
+	var frequency string
+	err := DB.QueryRow(`
+		SELECT frequency FROM notification_digests WHERE user_id = ?
+	`, userID).Scan(&frequency)
+	if err == sql.ErrNoRows {
+		return "off", nil
+	}
+	return frequency, err
+}
+
+// SetDigestFrequency changes the digest frequency of a user. Turning digests on
+// starts the window now, so the first digest does not include older activity.
+func SetDigestFrequency(userID int, frequency string) error {
+	_, err := DB.Exec(`
+		INSERT INTO notification_digests (user_id, frequency, last_sent_at, last_notification_id, last_message_id)
+		VALUES (
+			@user, @frequency, @now,
+			(SELECT COALESCE(MAX(id), 0) FROM notifications),
+			(SELECT COALESCE(MAX(id), 0) FROM private_chat_messages)
+		)
+		ON CONFLICT(user_id) DO UPDATE SET
+			frequency = excluded.frequency,
+			last_sent_at = CASE WHEN notification_digests.frequency = 'off'
+				THEN excluded.last_sent_at ELSE notification_digests.last_sent_at END,
+			last_notification_id = CASE WHEN notification_digests.frequency = 'off'
+				THEN excluded.last_notification_id ELSE notification_digests.last_notification_id END,
+			last_message_id = CASE WHEN notification_digests.frequency = 'off'
+				THEN excluded.last_message_id ELSE notification_digests.last_message_id END,
+			updated_at = CURRENT_TIMESTAMP
+	`, sql.Named("user", userID), sql.Named("frequency", frequency), sql.Named("now", time.Now().Unix()))
+	return err
+}
+
+// GetDueDigestRecipients returns the users whose last digest was sent before the
+// cutoff of their frequency.
+func GetDueDigestRecipients(cutoffs map[string]time.Time) ([]DigestRecipient, error) {
+	rows, err := DB.Query(`
+		SELECT user_id, frequency, last_notification_id, last_message_id
+		FROM notification_digests
+		WHERE (frequency = 'hourly' AND last_sent_at <= @hourly)
+		   OR (frequency = 'daily' AND last_sent_at <= @daily)
+		   OR (frequency = 'weekly' AND last_sent_at <= @weekly)
+		ORDER BY user_id
+	`,
+		sql.Named("hourly", cutoffs["hourly"].Unix()),
+		sql.Named("daily", cutoffs["daily"].Unix()),
+		sql.Named("weekly", cutoffs["weekly"].Unix()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []DigestRecipient
+	for rows.Next() {
+		var d DigestRecipient
+		if err := rows.Scan(&d.UserID, &d.Frequency, &d.LastNotificationID, &d.LastMessageID); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, d)
+	}
+	return recipients, rows.Err()
+}
+
+// GetDigestWatermarks returns the newest notification and private message IDs.
+func GetDigestWatermarks() (notificationID, messageID int64, err error) {
+	err = DB.QueryRow(`
+		SELECT (SELECT COALESCE(MAX(id), 0) FROM notifications),
+		       (SELECT COALESCE(MAX(id), 0) FROM private_chat_messages)
+	`).Scan(&notificationID, &messageID)
+	return notificationID, messageID, err
+}
+
+// RecordDigestSent moves the digest window of a user past the given watermarks.
+func RecordDigestSent(userID int, sentAt time.Time, notificationID, messageID int64) error {
+	_, err := DB.Exec(`
+		UPDATE notification_digests
+		SET last_sent_at = ?, last_notification_id = ?, last_message_id = ?
+		WHERE user_id = ?
+	`, sentAt.Unix(), notificationID, messageID, userID)
+	return err
+}
+
+// GetDigestNotifications returns up to limit unread notifications of a user with IDs
+// in (afterID, uptoID], newest first. Message notifications are left out because
+// digests list unread conversations instead.
+func GetDigestNotifications(userID int, afterID, uptoID int64, limit int) ([]models.Notification, error) {
+	rows, err := DB.Query(`
+		SELECT n.id, n.actor_id, n.type, COALESCE(n.data, ''), n.read, n.created_at,
+		       u.first_name, u.last_name, u.avatar, u.username
+		FROM notifications n
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.user_id = ? AND n.read = 0 AND n.type != 'new_message'
+		  AND n.id > ? AND n.id <= ?
+		ORDER BY n.id DESC
+		LIMIT ?
+	`, userID, afterID, uptoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		n := models.Notification{Count: 1, ActorCount: 1}
+		err := rows.Scan(&n.ID, &n.ActorID, &n.Type, &n.Data, &n.Read, &n.CreatedAt,
+			&n.Actor.FirstName, &n.Actor.LastName, &n.Actor.Avatar, &n.Actor.Username)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetDigestConversations returns the unread private messages of a user with IDs in
+// (afterID, uptoID], grouped by conversation and sender, most recent first.
+func GetDigestConversations(userID int, afterID, uptoID int64) ([]DigestConversation, error) {
+	// With MAX(), SQLite takes the bare content column from the newest message
+	rows, err := DB.Query(`
+		SELECT m.conversation_id, u.username, u.first_name, COUNT(*), m.content, MAX(m.id)
+		FROM private_chat_messages m
+		JOIN participants p ON p.conversation_id = m.conversation_id AND p.user_id = @user
+		JOIN users u ON u.id = m.user_id
+		WHERE m.user_id != @user
+		  AND m.id > p.last_read_message_id
+		  AND m.id > @after AND m.id <= @upto
+		GROUP BY m.conversation_id, m.user_id
+		ORDER BY MAX(m.id) DESC
+	`, sql.Named("user", userID), sql.Named("after", afterID), sql.Named("upto", uptoID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []DigestConversation
+	for rows.Next() {
+		var c DigestConversation
+		var lastID int64
+		if err := rows.Scan(&c.ConversationID, &c.SenderUsername, &c.SenderFirstName, &c.Count, &c.LastMessage, &lastID); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, rows.Err()
+}
+
+// MarkConversationRead marks every message of a conversation as read by userID.
+func MarkConversationRead(conversationID, userID int) error {
+	_, err := DB.Exec(`
+		UPDATE participants
+		SET last_read_message_id = (
+			SELECT COALESCE(MAX(id), 0) FROM private_chat_messages WHERE conversation_id = ?1
+		)
+		WHERE conversation_id = ?1 AND user_id = ?2
+	`, conversationID, userID)
+	return err
+}
+
+// GetOrCreateAppSecret returns the server secret stored under name, storing the
+// result of generate the first time it is requested.
+func GetOrCreateAppSecret(name string, generate func() (string, error)) (string, error) {
+	var value string
+	err := DB.QueryRow(`SELECT value FROM app_secrets WHERE name = ?`, name).Scan(&value)
+	if err != sql.ErrNoRows {
+		return value, err
+	}
+
+	value, err = generate()
+	if err != nil {
+		return "", err
+	}
+	// Another request may have created it meanwhile; the stored value wins
+	if _, err := DB.Exec(`INSERT OR IGNORE INTO app_secrets (name, value) VALUES (?, ?)`, name, value); err != nil {
+		return "", err
+	}
+	err = DB.QueryRow(`SELECT value FROM app_secrets WHERE name = ?`, name).Scan(&value)
+	return value, err
+}
@@ -0,0 +1,248 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"time"
+)
+
+// EventTimeLayout is the layout of event_date || ' ' || event_time.
+const EventTimeLayout = "2006-01-02 15:04"
+
+const groupEventColumns = `
+	ge.id,
+	ge.group_id,
+	ge.creator_id,
+	ge.title,
+	COALESCE(ge.description, '')     AS description,
+	ge.event_date || ' ' || ge.event_time AS start_time,
+	COALESCE(ge.end_date || ' ' || ge.end_time, '') AS end_time,
+	COALESCE(ge.location, '')        AS location,
+	COALESCE(ge.capacity, 0)         AS capacity,
+	COALESCE(ge.image_path, '')      AS image_path,
+	COALESCE(ge.recurrence_rule, '') AS recurrence_rule,
+	ge.created_at`
+
+type groupEventScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGroupEvent(row groupEventScanner, extra ...any) (models.GroupEvent, error) {
+	var e models.GroupEvent
+	var start, end string
+	dest := []any{
+		&e.ID, &e.GroupID, &e.CreatorID,
+		&e.Title, &e.Description, &start, &end,
+		&e.Location, &e.Capacity,
+		&e.CoverImage, &e.RecurrenceRule, &e.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return e, err
+	}
+	t, err := time.Parse(EventTimeLayout, start)
+	if err != nil {
+		return e, err
+	}
+	e.EventTime = t
+	if end != "" {
+		t, err := time.Parse(EventTimeLayout, end)
+		if err != nil {
+			return e, err
+		}
+		e.EndTime = &t
+	}
+	return e, nil
+}
+
+// GetGroupEventByID fetches a single event by ID.
+func GetGroupEventByID(eventID int64) (models.GroupEvent, error) {
+	row := DB.QueryRow(`SELECT `+groupEventColumns+` FROM group_events ge WHERE ge.id = ?`, eventID)
+	return scanGroupEvent(row)
+}
+
+// GetGroupEventsList returns every event of a group ordered by start time.
+func GetGroupEventsList(groupID int64) ([]models.GroupEvent, error) {
+	rows, err := DB.Query(`
+		SELECT `+groupEventColumns+`
+		FROM group_events ge
+		WHERE ge.group_id = ?
+		ORDER BY ge.event_date, ge.event_time
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.GroupEvent
+	for rows.Next() {
+		e, err := scanGroupEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventExceptions returns the cancelled occurrence dates (YYYY-MM-DD) of a recurring event.
+func GetEventExceptions(eventID int64) ([]string, error) {
+	rows, err := DB.Query(`
+		SELECT occurrence_date FROM group_event_exceptions
+		WHERE event_id = ?
+		ORDER BY occurrence_date
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+	return dates, rows.Err()
+}
+
+// AddEventException cancels one occurrence of a recurring event and drops its RSVPs.
+func AddEventException(eventID int64, occurrenceDate string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO group_event_exceptions (event_id, occurrence_date)
+		VALUES (?, ?)
+	`, eventID, occurrenceDate); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM group_event_responses
+		WHERE event_id = ? AND occurrence_date = ?
+	`, eventID, occurrenceDate); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveEventException restores a previously cancelled occurrence.
+func RemoveEventException(eventID int64, occurrenceDate string) error {
+	_, err := DB.Exec(`
+		DELETE FROM group_event_exceptions
+		WHERE event_id = ? AND occurrence_date = ?
+	`, eventID, occurrenceDate)
+	return err
+}
+
+// OccurrenceResponses holds the RSVP summary of one event occurrence.
+type OccurrenceResponses struct {
+	Going        int
+	NotGoing     int
+	Maybe        int
+	Waitlisted   int
+	UserResponse string
+}
+
+// GetOccurrenceResponses returns the RSVP summary of every occurrence of an event
+// that has responses, keyed by occurrence date, from userID's point of view.
+func GetOccurrenceResponses(eventID int64, userID int) (map[string]OccurrenceResponses, error) {
+	rows, err := DB.Query(`
+		SELECT
+			occurrence_date,
+			COUNT(CASE WHEN response = 'going' THEN 1 END),
+			COUNT(CASE WHEN response = 'not-going' THEN 1 END),
+			COUNT(CASE WHEN response = 'maybe' THEN 1 END),
+			COUNT(CASE WHEN response = 'waitlisted' THEN 1 END),
+			COALESCE(MAX(CASE WHEN user_id = ? THEN response END), '')
+		FROM group_event_responses
+		WHERE event_id = ?
+		GROUP BY occurrence_date
+	`, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := make(map[string]OccurrenceResponses)
+	for rows.Next() {
+		var date string
+		var s OccurrenceResponses
+		if err := rows.Scan(&date, &s.Going, &s.NotGoing, &s.Maybe, &s.Waitlisted, &s.UserResponse); err != nil {
+			return nil, err
+		}
+		summary[date] = s
+	}
+	return summary, rows.Err()
+}
+
+// GoingEvent is an event occurrence a user answered "going" to.
+type GoingEvent struct {
+	models.GroupEvent
+	GroupName      string
+	OccurrenceDate string // empty for single events
+}
+
+// GetGoingEventsForUser returns every event occurrence userID answered "going" to,
+// limited to groups the user still belongs to.
+func GetGoingEventsForUser(userID int) ([]GoingEvent, error) {
+	rows, err := DB.Query(`
+		SELECT `+groupEventColumns+`, g.name, ger.occurrence_date
+		FROM group_event_responses ger
+		JOIN group_events ge ON ge.id = ger.event_id
+		JOIN groups g ON g.id = ge.group_id
+		JOIN group_members gm ON gm.group_id = ge.group_id AND gm.user_id = ger.user_id
+		WHERE ger.user_id = ? AND ger.response = 'going'
+		ORDER BY ge.event_date, ge.event_time, ger.occurrence_date
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []GoingEvent
+	for rows.Next() {
+		var ge GoingEvent
+		if ge.GroupEvent, err = scanGroupEvent(rows, &ge.GroupName, &ge.OccurrenceDate); err != nil {
+			return nil, err
+		}
+		events = append(events, ge)
+	}
+	return events, rows.Err()
+}
+
+// GetOrCreateCalendarToken returns the user's private calendar feed token,
+// creating one on first use.
+func GetOrCreateCalendarToken(userID int) (string, error) {
+	var token string
+	err := DB.QueryRow(`SELECT token FROM calendar_tokens WHERE user_id = ?`, userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return RotateCalendarToken(userID)
+	}
+	return token, err
+}
+
+// RotateCalendarToken replaces the user's calendar feed token, revoking the old URL.
+func RotateCalendarToken(userID int) (string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = DB.Exec(`
+		INSERT INTO calendar_tokens (user_id, token) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token = excluded.token, created_at = CURRENT_TIMESTAMP
+	`, userID, token)
+	return token, err
+}
+
+// GetUserIDByCalendarToken resolves a calendar feed token to its owner.
+func GetUserIDByCalendarToken(token string) (int, error) {
+	var userID int
+	err := DB.QueryRow(`SELECT user_id FROM calendar_tokens WHERE token = ?`, token).Scan(&userID)
+	return userID, err
+}
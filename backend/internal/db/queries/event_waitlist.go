@@ -0,0 +1,169 @@
+package queries
+
+import (
+	"database/sql"
+)
+
+// SetEventResponse records userID's response to an event occurrence (occurrenceDate is
+// empty for single events) while enforcing capacity, where 0 means unlimited.
+// A "going" response to a full occurrence is stored as "waitlisted" and an empty
+// response removes the user's answer. When a "going" seat is freed, the longest
+// waiting users are promoted to "going" and returned.
+func SetEventResponse(eventID, userID int64, occurrenceDate, response string, capacity int) (stored string, promoted []int64, err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow(`
+		SELECT response FROM group_event_responses
+		WHERE event_id = ? AND user_id = ? AND occurrence_date = ?
+	`, eventID, userID, occurrenceDate).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, err
+	}
+
+	stored = response
+	if response == "going" && current != "going" && capacity > 0 {
+		going, err := countGoing(tx, eventID, occurrenceDate)
+		if err != nil {
+			return "", nil, err
+		}
+		if going >= capacity {
+			stored = "waitlisted"
+		}
+	}
+
+	switch {
+	case stored == "":
+		_, err = tx.Exec(`
+			DELETE FROM group_event_responses
+			WHERE event_id = ? AND user_id = ? AND occurrence_date = ?
+		`, eventID, userID, occurrenceDate)
+	case stored == current:
+		// Unchanged, which also keeps a waitlisted user's place in the queue
+	default:
+		_, err = tx.Exec(`
+			INSERT INTO group_event_responses (event_id, user_id, occurrence_date, response)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(event_id, user_id, occurrence_date)
+			DO UPDATE SET response = excluded.response, updated_at = CURRENT_TIMESTAMP
+		`, eventID, userID, occurrenceDate, stored)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if current == "going" && stored != "going" {
+		if promoted, err = promoteWaitlisted(tx, eventID, occurrenceDate, capacity); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return stored, promoted, tx.Commit()
+}
+
+// PromoteEventWaitlists fills free seats of every occurrence of an event from its
+// waitlist, e.g. after the capacity was raised, and returns the promoted users by
+// occurrence date.
+func PromoteEventWaitlists(eventID int64, capacity int) (map[string][]int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT occurrence_date FROM group_event_responses
+		WHERE event_id = ? AND response = 'waitlisted'
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var dates []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	promoted := make(map[string][]int64)
+	for _, d := range dates {
+		ids, err := promoteWaitlisted(tx, eventID, d, capacity)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			promoted[d] = ids
+		}
+	}
+	return promoted, tx.Commit()
+}
+
+func countGoing(tx *sql.Tx, eventID int64, occurrenceDate string) (int, error) {
+	var going int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM group_event_responses
+		WHERE event_id = ? AND occurrence_date = ? AND response = 'going'
+	`, eventID, occurrenceDate).Scan(&going)
+	return going, err
+}
+
+// promoteWaitlisted moves waitlisted users to "going", oldest first, until the
+// occurrence is full again, and returns their IDs.
+func promoteWaitlisted(tx *sql.Tx, eventID int64, occurrenceDate string, capacity int) ([]int64, error) {
+	// A negative LIMIT means no limit in SQLite, which promotes everyone when capacity is unlimited
+	free := -1
+	if capacity > 0 {
+		going, err := countGoing(tx, eventID, occurrenceDate)
+		if err != nil {
+			return nil, err
+		}
+		if free = capacity - going; free <= 0 {
+			return nil, nil
+		}
+	}
+
+	rows, err := tx.Query(`
+		SELECT user_id FROM group_event_responses
+		WHERE event_id = ? AND occurrence_date = ? AND response = 'waitlisted'
+		ORDER BY updated_at, id
+		LIMIT ?
+	`, eventID, occurrenceDate, free)
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		if _, err := tx.Exec(`
+			UPDATE group_event_responses
+			SET response = 'going', updated_at = CURRENT_TIMESTAMP
+			WHERE event_id = ? AND user_id = ? AND occurrence_date = ?
+		`, eventID, id, occurrenceDate); err != nil {
+			return nil, err
+		}
+	}
+	return userIDs, nil
+}
@@ -1,43 +1,12 @@
 package queries
 
-import "backend/internal/models"
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
 
-// GetAllPersonalPosts fetches all non-group posts, newest first.
-// Privacy filtering is handled in the application layer (see posts/handlers.go).
-func GetAllPersonalPosts() ([]models.Post, error) {
-	rows, err := DB.Query(`
-		SELECT
-			p.id,
-			p.user_id,
-			p.group_id,
-			p.content,
-			COALESCE(p.image_path, '') AS image_path,
-			COALESCE(p.privacy, 'public')  AS privacy,
-			p.created_at
-		FROM posts p
-		WHERE p.group_id IS NULL
-		ORDER BY p.created_at DESC
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var posts []models.Post
-	for rows.Next() {
-		var p models.Post
-		if err := rows.Scan(
-			&p.ID, &p.UserID, &p.GroupID,
-			&p.Content, &p.ImagePath, &p.Privacy, &p.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		posts = append(posts, p)
-	}
-	return posts, rows.Err()
-}
-
-// GetPostByID fetches a single post by ID.
+// GetPostByID fetches a single post by ID, with its attachments.
 func GetPostByID(postID int64) (models.Post, error) {
 	var p models.Post
 	err := DB.QueryRow(`
@@ -46,13 +15,25 @@ func GetPostByID(postID int64) (models.Post, error) {
 			p.user_id,
 			p.group_id,
 			p.content,
-			COALESCE(p.image_path, '') AS image_path,
 			COALESCE(p.privacy, 'public')  AS privacy,
-			p.created_at
+			p.created_at,
+			p.edited_at,
+			p.audience_list_id
 		FROM posts p
 		WHERE p.id = ?
-	`, postID).Scan(&p.ID, &p.UserID, &p.GroupID, &p.Content, &p.ImagePath, &p.Privacy, &p.CreatedAt)
-	return p, err
+	`, postID).Scan(&p.ID, &p.UserID, &p.GroupID, &p.Content, &p.Privacy, &p.CreatedAt, &p.EditedAt, &p.AudienceListID)
+	if err != nil {
+		return p, err
+	}
+	p.Edited = p.EditedAt != nil
+	return p, withAttachments(&p)
+}
+
+// GetPostCreatedAt returns when a post was created.
+func GetPostCreatedAt(postID int64) (time.Time, error) {
+	var unix int64
+	err := DB.QueryRow(`SELECT CAST(strftime('%s', created_at) AS INTEGER) FROM posts WHERE id = ?`, postID).Scan(&unix)
+	return time.Unix(unix, 0), err
 }
 
 // IsInSelectedFollowers returns true when userID is in the selected-followers
@@ -68,77 +49,141 @@ func IsInSelectedFollowers(postID int64, userID int) (bool, error) {
 	return exists, err
 }
 
-// CreatePost creates a new personal post (no group).
+// CreatePost creates a new personal post (no group) with its attachments,
+// which get their IDs set. A "selected" post is shared with the audience list
+// listID, or when it is nil with the followers in audience.
 // Returns the new post ID.
-func CreatePost(userID int, content string, imagePath *string, privacy string) (int64, error) {
-	var imageVal any
-	if imagePath != nil {
-		imageVal = *imagePath
+func CreatePost(userID int, content string, attachments []models.PostAttachment, privacy string, listID *int64, audience []int) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
 	}
-	result, err := DB.Exec(`
-		INSERT INTO posts (user_id, content, image_path, privacy)
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO posts (user_id, content, privacy, audience_list_id)
 		VALUES (?, ?, ?, ?)
-	`, userID, content, imageVal, privacy)
+	`, userID, content, privacy, listID)
+	if err != nil {
+		return 0, err
+	}
+	postID, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	if _, err := setPostAttachments(tx, postID, attachments); err != nil {
+		return 0, err
+	}
+	if privacy == "selected" && listID == nil {
+		if err := addToAudience(tx, postID, userID, audience); err != nil {
+			return 0, err
+		}
+	}
+	return postID, tx.Commit()
 }
 
-// GetPostsByUserID fetches all non-group posts by a specific user, newest first.
-// Privacy filtering is handled in the application layer.
-func GetPostsByUserID(authorID int) ([]models.Post, error) {
+// UpdatePost updates content and privacy of a post and, unless attachments is
+// nil, replaces its attachments (see setPostAttachments). A "selected" post is
+// shared with the audience list listID when it is not nil; otherwise its
+// audience is replaced by audience unless that is nil. The audience is emptied
+// when the post is no longer shared with chosen followers. It returns the URLs
+// of the attachments removed, and whether the post was edited: its text or
+// attachments changed. Edits are recorded in post_revisions and set edited_at;
+// a change of privacy alone is not an edit.
+func UpdatePost(postID int64, content string, privacy string, listID *int64, attachments []models.PostAttachment, audience []int) (removed []string, edited bool, err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var authorID int
+	var oldContent string
+	if err := tx.QueryRow(`SELECT user_id, content FROM posts WHERE id = ?`, postID).Scan(&authorID, &oldContent); err != nil {
+		return nil, false, err
+	}
+	oldAttachments, err := attachmentsState(tx, postID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE posts SET content = ?, privacy = ?, audience_list_id = ? WHERE id = ?`,
+		content, privacy, listID, postID,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	if attachments != nil {
+		if removed, err = setPostAttachments(tx, postID, attachments); err != nil {
+			return nil, false, err
+		}
+	}
+	switch {
+	case privacy != "selected" || listID != nil:
+		err = clearAudience(tx, postID)
+	case audience != nil:
+		err = replaceAudience(tx, postID, authorID, audience)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	newAttachments, err := attachmentsState(tx, postID)
+	if err != nil {
+		return nil, false, err
+	}
+	if content == oldContent && newAttachments == oldAttachments {
+		return removed, false, tx.Commit()
+	}
+
+	// The original text is recorded at the first edit
+	now := time.Now().UTC().Format(time.DateTime)
+	_, err = tx.Exec(`
+		INSERT INTO post_revisions (post_id, content, edited_at)
+		SELECT ?, ?, NULL WHERE NOT EXISTS(SELECT 1 FROM post_revisions WHERE post_id = ?)
+	`, postID, oldContent, postID)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err = tx.Exec(`INSERT INTO post_revisions (post_id, content, edited_at) VALUES (?, ?, ?)`, postID, content, now); err != nil {
+		return nil, false, err
+	}
+	if _, err = tx.Exec(`UPDATE posts SET edited_at = ? WHERE id = ?`, now, postID); err != nil {
+		return nil, false, err
+	}
+	return removed, true, tx.Commit()
+}
+
+// attachmentsState sums up the attachments of a post, to tell whether an
+// update changed them.
+func attachmentsState(tx *sql.Tx, postID int64) (string, error) {
+	var state string
+	err := tx.QueryRow(`
+		SELECT COALESCE(group_concat(path || char(31) || alt_text, char(30)), '')
+		FROM (SELECT path, alt_text FROM post_attachments WHERE post_id = ? ORDER BY position, id)
+	`, postID).Scan(&state)
+	return state, err
+}
+
+// GetPostRevisions returns the texts a post had, oldest first. It is empty
+// for posts never edited.
+func GetPostRevisions(postID int64) ([]models.PostRevision, error) {
 	rows, err := DB.Query(`
-		SELECT
-			p.id,
-			p.user_id,
-			p.group_id,
-			p.content,
-			COALESCE(p.image_path, '') AS image_path,
-			COALESCE(p.privacy, 'public') AS privacy,
-			p.created_at
-		FROM posts p
-		WHERE p.user_id = ? AND p.group_id IS NULL
-		ORDER BY p.created_at DESC
-	`, authorID)
+		SELECT id, content, edited_at FROM post_revisions WHERE post_id = ? ORDER BY id
+	`, postID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	revisions := make([]models.PostRevision, 0)
 	for rows.Next() {
-		var p models.Post
-		if err := rows.Scan(
-			&p.ID, &p.UserID, &p.GroupID,
-			&p.Content, &p.ImagePath, &p.Privacy, &p.CreatedAt,
-		); err != nil {
+		var r models.PostRevision
+		if err := rows.Scan(&r.ID, &r.Content, &r.EditedAt); err != nil {
 			return nil, err
 		}
-		posts = append(posts, p)
+		revisions = append(revisions, r)
 	}
-	return posts, rows.Err()
-}
-
-// SetSelectedFollowers saves the chosen user IDs for a "selected" privacy post.
-func SetSelectedFollowers(postID int64, userIDs []int) error {
-	for _, uid := range userIDs {
-		_, err := DB.Exec(
-			`INSERT OR IGNORE INTO post_selected_followers (post_id, user_id) VALUES (?, ?)`,
-			postID, uid,
-		)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// UpdatePost updates content and privacy of a post.
-func UpdatePost(postID int64, content string, privacy string) error {
-	_, err := DB.Exec(
-		`UPDATE posts SET content = ?, privacy = ? WHERE id = ?`,
-		content, privacy, postID,
-	)
-	return err
+	return revisions, rows.Err()
 }
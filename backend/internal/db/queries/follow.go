@@ -78,8 +78,9 @@ func GetFollowersList(userID, viewerID int) ([]models.UserSearchResult, error) {
 		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
 		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
 		WHERE fol.following_id = ? AND fol.status = 'accepted'
+		  AND `+notBlockedWith("u.id", "?")+`
 		ORDER BY u.first_name, u.last_name
-	`, viewerID, viewerID, userID)
+	`, viewerID, viewerID, userID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -107,8 +108,9 @@ func GetFollowingList(userID, viewerID int) ([]models.UserSearchResult, error) {
 		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
 		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
 		WHERE fol.follower_id = ? AND fol.status = 'accepted'
+		  AND `+notBlockedWith("u.id", "?")+`
 		ORDER BY u.first_name, u.last_name
-	`, viewerID, viewerID, userID)
+	`, viewerID, viewerID, userID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
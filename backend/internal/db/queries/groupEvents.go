@@ -4,12 +4,18 @@ import (
 	"backend/internal/models"
 )
 
-// CreateGroupEvent inserts a new event into the database and returns its ID
-func CreateGroupEvent(groupID, creatorID int64, title, description, eventDate, eventTime, imagePath string) (int64, error) {
+// CreateGroupEvent inserts a new event into the database and returns its ID.
+// Empty optional fields are stored as NULL.
+func CreateGroupEvent(p models.CreateGroupEventParams) (int64, error) {
 	res, err := DB.Exec(`
-		INSERT INTO group_events (group_id, creator_id, title, description, event_date, event_time, image_path)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, groupID, creatorID, title, description, eventDate, eventTime, imagePath)
+		INSERT INTO group_events (
+			group_id, creator_id, title, description, event_date, event_time,
+			end_date, end_time, location, capacity, image_path, recurrence_rule
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.GroupID, p.CreatorID, p.Title, p.Description, p.EventDate, p.EventTime,
+		nullableString(p.EndDate), nullableString(p.EndTime), nullableString(p.Location),
+		nullableInt(p.Capacity), p.ImagePath, nullableString(p.RecurrenceRule))
 	if err != nil {
 		return 0, err
 	}
@@ -22,6 +28,33 @@ func CreateGroupEvent(groupID, creatorID int64, title, description, eventDate, e
 	return eventID, nil
 }
 
+// UpdateGroupEvent overwrites the editable columns of an event. Responses are kept.
+func UpdateGroupEvent(eventID int64, p models.UpdateGroupEventParams) error {
+	_, err := DB.Exec(`
+		UPDATE group_events SET
+			title = ?, description = ?, event_date = ?, event_time = ?,
+			end_date = ?, end_time = ?, location = ?, capacity = ?, image_path = ?
+		WHERE id = ?
+	`, p.Title, p.Description, p.EventDate, p.EventTime,
+		nullableString(p.EndDate), nullableString(p.EndTime), nullableString(p.Location),
+		nullableInt(p.Capacity), p.ImagePath, eventID)
+	return err
+}
+
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullableInt(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
 func GetGroupEvents(groupID int64, userID int) ([]models.Event, error) {
 	rows, err := DB.Query(`
 		SELECT 
@@ -30,12 +63,17 @@ func GetGroupEvents(groupID int64, userID int) ([]models.Event, error) {
 			ge.title, 
 			COALESCE(ge.description, '') AS description,
 			ge.event_date || ' ' || ge.event_time AS start_time,
-			'' AS end_time,
+			COALESCE(ge.end_date || ' ' || ge.end_time, '') AS end_time,
+			COALESCE(ge.location, '') AS location,
+			COALESCE(ge.capacity, 0) AS capacity,
 			ge.image_path,
+			COALESCE(ge.recurrence_rule, '') AS recurrence_rule,
 			ge.created_at,
-			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND response = 'going') AS going_count,
-			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND response = 'not-going') AS not_going_count,
-			COALESCE((SELECT response FROM group_event_responses WHERE event_id = ge.id AND user_id = ?), '') AS user_response,
+			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND occurrence_date = '' AND response = 'going') AS going_count,
+			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND occurrence_date = '' AND response = 'not-going') AS not_going_count,
+			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND occurrence_date = '' AND response = 'maybe') AS maybe_count,
+			(SELECT COUNT(*) FROM group_event_responses WHERE event_id = ge.id AND occurrence_date = '' AND response = 'waitlisted') AS waitlist_count,
+			COALESCE((SELECT response FROM group_event_responses WHERE event_id = ge.id AND occurrence_date = '' AND user_id = ?), '') AS user_response,
 			ge.creator_id,
 			u.first_name,
 			u.last_name,
@@ -61,10 +99,15 @@ func GetGroupEvents(groupID int64, userID int) ([]models.Event, error) {
 			&event.Description,
 			&event.StartTime,
 			&event.EndTime,
+			&event.Location,
+			&event.Capacity,
 			&event.ImagePath,
+			&event.RecurrenceRule,
 			&event.CreatedAt,
 			&event.GoingCount,
 			&event.NotGoingCount,
+			&event.MaybeCount,
+			&event.WaitlistCount,
 			&event.UserResponse,
 			&event.CreatorID,
 			&creator.FirstName,
@@ -82,34 +125,18 @@ func GetGroupEvents(groupID int64, userID int) ([]models.Event, error) {
 	return events, rows.Err()
 }
 
-// AddEventResponse adds or updates a user's response for an event
-func AddEventResponse(eventID, userID int64, response string) error {
-	_, err := DB.Exec(`
-		INSERT INTO group_event_responses (event_id, user_id, response)
-		VALUES (?, ?, ?)
-		ON CONFLICT(event_id, user_id) DO UPDATE SET response = excluded.response
-	`, eventID, userID, response)
-	return err
-}
-
-// RemoveEventResponse deletes a user's response for an event
-func RemoveEventResponse(eventID, userID int64) error {
-	_, err := DB.Exec(`
-		DELETE FROM group_event_responses 
-		WHERE event_id = ? AND user_id = ?
-	`, eventID, userID)
-	return err
-}
-
-// GetEventResponseCounts returns how many users are "going" and "not-going" for an event
-func GetEventResponseCounts(eventID int64) (going int, notGoing int, err error) {
+// GetEventResponseCounts returns the number of responses of each kind for an event occurrence
+func GetEventResponseCounts(eventID int64, occurrenceDate string) (counts models.EventResponseCounts, err error) {
+	counts.EventID = eventID
 	err = DB.QueryRow(`
 		SELECT 
 			COUNT(CASE WHEN response = 'going' THEN 1 END),
-			COUNT(CASE WHEN response = 'not-going' THEN 1 END)
+			COUNT(CASE WHEN response = 'not-going' THEN 1 END),
+			COUNT(CASE WHEN response = 'maybe' THEN 1 END),
+			COUNT(CASE WHEN response = 'waitlisted' THEN 1 END)
 		FROM group_event_responses
-		WHERE event_id = ?
-	`, eventID).Scan(&going, &notGoing)
+		WHERE event_id = ? AND occurrence_date = ?
+	`, eventID, occurrenceDate).Scan(&counts.Going, &counts.NotGoing, &counts.Maybe, &counts.Waitlisted)
 	return
 }
 
@@ -145,13 +172,14 @@ type EventVoter struct {
 	Response  string `json:"response"`
 }
 
-func GetEventVoters(eventID int64) ([]EventVoter, error) {
+func GetEventVoters(eventID int64, occurrenceDate string) ([]EventVoter, error) {
 	rows, err := DB.Query(`
 		SELECT u.id, u.first_name, u.last_name, COALESCE(u.avatar, ''), ger.response
 		FROM group_event_responses ger
 		JOIN users u ON ger.user_id = u.id
-		WHERE ger.event_id = ?
-	`, eventID)
+		WHERE ger.event_id = ? AND ger.occurrence_date = ?
+		ORDER BY ger.updated_at, ger.id
+	`, eventID, occurrenceDate)
 	if err != nil {
 		return nil, err
 	}
@@ -207,3 +235,52 @@ func GetEventImagePath(eventID int64) (string, error) {
 	`, eventID).Scan(&imagePath)
 	return imagePath, err
 }
+
+// GetEventRespondentIDs returns the group members who gave response to an event occurrence
+func GetEventRespondentIDs(eventID int64, occurrenceDate, response string) ([]int, error) {
+	rows, err := DB.Query(`
+		SELECT ger.user_id
+		FROM group_event_responses ger
+		JOIN group_events ge ON ge.id = ger.event_id
+		JOIN group_members gm ON gm.group_id = ge.group_id AND gm.user_id = ger.user_id
+		WHERE ger.event_id = ? AND ger.occurrence_date = ? AND ger.response = ?
+		ORDER BY ger.updated_at, ger.id
+	`, eventID, occurrenceDate, response)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// GetAllEventRespondentIDs returns every user who responded to any occurrence of an event
+func GetAllEventRespondentIDs(eventID int64) ([]int, error) {
+	rows, err := DB.Query(`
+		SELECT DISTINCT user_id
+		FROM group_event_responses
+		WHERE event_id = ?
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
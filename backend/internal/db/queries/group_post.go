@@ -2,23 +2,26 @@ package queries
 
 import (
 	"backend/internal/models"
+	"database/sql"
 )
 
-// GetGroupPosts retrieves all posts for a specific group
-func GetGroupPosts(groupID int64) ([]models.Post, error) {
+// GetGroupPosts retrieves all posts for a specific group, leaving out posts of
+// users blocked either way by viewerID
+func GetGroupPosts(groupID int64, viewerID int) ([]models.Post, error) {
 	rows, err := DB.Query(`
 		SELECT 
 			id, 
 			user_id,
 			group_id, 
 			content, 
-			COALESCE(image_path, '') as image_path,
 			COALESCE(privacy, '') as privacy,
-			created_at
+			created_at,
+			edited_at
 		FROM posts
 		WHERE group_id = ?
+		  AND `+notBlockedWith("user_id", "?")+`
 		ORDER BY created_at DESC
-	`, groupID)
+	`, groupID, viewerID, viewerID)
 
 	if err != nil {
 		return nil, err
@@ -33,31 +36,77 @@ func GetGroupPosts(groupID int64) ([]models.Post, error) {
 			&post.UserID,
 			&post.GroupID,
 			&post.Content,
-			&post.ImagePath,
 			&post.Privacy,
 			&post.CreatedAt,
+			&post.EditedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		post.Edited = post.EditedAt != nil
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-	return posts, rows.Err()
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	return posts, withAttachments(refs...)
 }
 
-// CreateGroupPost creates a new post in a group
+// CreateGroupPost creates a new post in a group with its attachments, which
+// get their IDs set.
 // All group posts are public to group members only
-func CreateGroupPost(groupID int64, userID int, content string, imagePath *string) error {
-	var imagePathValue interface{}
-	if imagePath != nil {
-		imagePathValue = *imagePath
+func CreateGroupPost(groupID int64, userID int, content string, attachments []models.PostAttachment) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	_, err := DB.Exec(`
-		INSERT INTO posts (user_id, group_id, content, image_path, privacy) 
-		VALUES (?, ?, ?, ?, 'public')
-	`, userID, groupID, content, imagePathValue)
+	res, err := tx.Exec(`
+		INSERT INTO posts (user_id, group_id, content, privacy) 
+		VALUES (?, ?, ?, 'public')
+	`, userID, groupID, content)
+	if err != nil {
+		return 0, err
+	}
+	postID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := setPostAttachments(tx, postID, attachments); err != nil {
+		return 0, err
+	}
+	return postID, tx.Commit()
+}
 
-	return err
+// GetGroupPostsPage returns up to limit posts of a group, newest first, with
+// author, like and comment metadata resolved for viewerID in a single query.
+// beforeID is the cursor: only posts with a smaller ID are returned (0 = first page).
+func GetGroupPostsPage(groupID int64, viewerID int, beforeID int64, limit int) ([]models.PostWithMeta, error) {
+	rows, err := DB.Query(`
+		SELECT `+postWithMetaColumns+`
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.group_id = @group
+		  AND (@before = 0 OR p.id < @before)
+		  AND `+notBlockedWith("p.user_id", "@viewer")+`
+		ORDER BY p.id DESC
+		LIMIT @limit
+	`,
+		sql.Named("viewer", viewerID),
+		sql.Named("group", groupID),
+		sql.Named("before", beforeID),
+		sql.Named("limit", limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanPostsWithMeta(rows, limit)
 }
@@ -4,14 +4,16 @@ import (
 	"backend/internal/models"
 )
 
-// GetUsersNotInGroup returns users who are not members of the given group
-func GetUsersNotInGroup(groupID int64) ([]models.UserPublic, error) {
+// GetUsersNotInGroup returns users who are not members of the given group,
+// leaving out users blocked either way by viewerID
+func GetUsersNotInGroup(groupID int64, viewerID int) ([]models.UserPublic, error) {
 	rows, err := DB.Query(`
         SELECT u.id, u.email, u.first_name, u.last_name, COALESCE(u.nickname, '') as nickname, COALESCE(u.avatar, '') as avatar, COALESCE(u.about_me, '') as about_me, u.created_at
         FROM users u
         WHERE u.id NOT IN (SELECT user_id FROM group_members WHERE group_id = ?)
+          AND `+notBlockedWith("u.id", "?")+`
         ORDER BY u.first_name, u.last_name
-    `, groupID)
+    `, groupID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
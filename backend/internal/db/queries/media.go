@@ -0,0 +1,331 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AddMedia records an uploaded file and its variants, taking a reference on
+// each of their blobs. The blobs must already be in the media store.
+// legacyPath is the /uploads/ path of an imported file, or "".
+func AddMedia(m models.Media, variants []models.MediaVariant, legacyPath string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	acquire := func(hash string, size int64) error {
+		_, err := tx.Exec(`
+			INSERT INTO media_blobs (hash, size_bytes, ref_count, created_at) VALUES (?, ?, 1, ?)
+			ON CONFLICT (hash) DO UPDATE SET ref_count = ref_count + 1
+		`, hash, size, now)
+		return err
+	}
+
+	if err := acquire(m.Hash, m.SizeBytes); err != nil {
+		return 0, err
+	}
+	var owner, legacy interface{}
+	if m.OwnerID != nil {
+		owner = *m.OwnerID
+	}
+	if legacyPath != "" {
+		legacy = legacyPath
+	}
+	res, err := tx.Exec(`
+		INSERT INTO media (owner_id, hash, mime_type, size_bytes, width, height, duration_ms, video_codec, audio_codec, legacy_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	`, owner, m.Hash, m.MimeType, m.SizeBytes, m.Width, m.Height, m.DurationMS, m.VideoCodec, m.AudioCodec, legacy, now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, v := range variants {
+		if err := acquire(v.Hash, v.SizeBytes); err != nil {
+			return 0, err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO media_variants (media_id, name, hash, mime_type, size_bytes, width, height)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, v.Name, v.Hash, v.MimeType, v.SizeBytes, v.Width, v.Height)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// GetMedia returns a media record. It returns sql.ErrNoRows if it does not exist.
+func GetMedia(id int64) (models.Media, error) {
+	var m models.Media
+	var owner, width, height, duration sql.NullInt64
+	var createdAt int64
+	err := DB.QueryRow(`
+		SELECT id, owner_id, hash, mime_type, size_bytes, width, height,
+		       duration_ms, COALESCE(video_codec, ''), COALESCE(audio_codec, ''), created_at
+		FROM media WHERE id = ?
+	`, id).Scan(&m.ID, &owner, &m.Hash, &m.MimeType, &m.SizeBytes, &width, &height,
+		&duration, &m.VideoCodec, &m.AudioCodec, &createdAt)
+	if err != nil {
+		return m, err
+	}
+	if owner.Valid {
+		o := int(owner.Int64)
+		m.OwnerID = &o
+	}
+	if width.Valid && height.Valid {
+		w, h := int(width.Int64), int(height.Int64)
+		m.Width, m.Height = &w, &h
+	}
+	if duration.Valid {
+		m.DurationMS = &duration.Int64
+	}
+	m.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return m, nil
+}
+
+// SetMediaOwner sets the owner of a media record uploaded before its owner
+// existed, e.g. an avatar chosen at registration.
+func SetMediaOwner(id int64, ownerID int) error {
+	_, err := DB.Exec(`UPDATE media SET owner_id = ? WHERE id = ?`, ownerID, id)
+	return err
+}
+
+// DeleteMedia deletes a media record with its variants and releases their
+// blobs. It returns the hashes of the blobs that lost their last reference,
+// for the caller to delete from the media store once committed. Deleting a
+// missing record is not an error.
+func DeleteMedia(id int64) ([]string, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`DELETE FROM media_variants WHERE media_id = ? RETURNING hash`, id)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var hash string
+	err = tx.QueryRow(`DELETE FROM media WHERE id = ? RETURNING hash`, id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hashes = append(hashes, hash)
+
+	var unused []string
+	for _, hash := range hashes {
+		var refs int
+		err = tx.QueryRow(`
+			UPDATE media_blobs SET ref_count = ref_count - 1 WHERE hash = ? RETURNING ref_count
+		`, hash).Scan(&refs)
+		if err != nil {
+			return nil, err
+		}
+		if refs > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM media_blobs WHERE hash = ?`, hash); err != nil {
+			return nil, err
+		}
+		unused = append(unused, hash)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return unused, nil
+}
+
+// IsBlobReferenced reports whether a media record or variant uses the blob.
+func IsBlobReferenced(hash string) (bool, error) {
+	var used bool
+	err := DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM media_blobs WHERE hash = ?)`, hash).Scan(&used)
+	return used, err
+}
+
+// GetMediaVariants returns the variants of a media record.
+func GetMediaVariants(mediaID int64) ([]models.MediaVariant, error) {
+	rows, err := DB.Query(`
+		SELECT name, hash, mime_type, size_bytes, width, height FROM media_variants WHERE media_id = ?
+	`, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]models.MediaVariant, 0)
+	for rows.Next() {
+		var v models.MediaVariant
+		if err := rows.Scan(&v.Name, &v.Hash, &v.MimeType, &v.SizeBytes, &v.Width, &v.Height); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// GetVariantsOfMedia returns the variants of the given media records, keyed
+// by media ID.
+func GetVariantsOfMedia(mediaIDs []int64) (map[int64][]models.MediaVariant, error) {
+	variants := make(map[int64][]models.MediaVariant, len(mediaIDs))
+	if len(mediaIDs) == 0 {
+		return variants, nil
+	}
+	args := make([]interface{}, len(mediaIDs))
+	for i, id := range mediaIDs {
+		args[i] = id
+	}
+	rows, err := DB.Query(`
+		SELECT media_id, name, hash, mime_type, size_bytes, width, height FROM media_variants
+		WHERE media_id IN (?`+strings.Repeat(", ?", len(mediaIDs)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.MediaVariant
+		var mediaID int64
+		if err := rows.Scan(&mediaID, &v.Name, &v.Hash, &v.MimeType, &v.SizeBytes, &v.Width, &v.Height); err != nil {
+			return nil, err
+		}
+		variants[mediaID] = append(variants[mediaID], v)
+	}
+	return variants, rows.Err()
+}
+
+// mediaReferences lists the columns holding media URLs. Each of these tables
+// also has an indexed media_id column generated from the URL (see migration
+// 44). Owner is the SQL expression of the user a row belongs to. Visible is the SQL condition under
+// which @viewer may see the media of a row, aliased as Alias:
+//   - avatars      → everyone not blocked by the user
+//   - post media   → whoever can see the post
+//   - group covers → everyone, as groups are listed to all
+//   - event images → members of the group
+var mediaReferences = []struct {
+	Table, Alias, Column, Owner, Visible string
+}{
+	{"users", "u", "avatar", "id", `NOT EXISTS(
+		SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = u.id AND ub.blocked_id = @viewer
+	)`},
+	{"post_attachments", "pa", "path", "(SELECT user_id FROM posts WHERE posts.id = post_attachments.post_id)",
+		`EXISTS(SELECT 1 FROM posts p WHERE p.id = pa.post_id AND ` + postVisibleToViewer + `)`},
+	{"groups", "g", "cover_image_path", "owner_id", "1"},
+	{"group_events", "e", "image_path", "creator_id", `EXISTS(
+		SELECT 1 FROM group_members gm WHERE gm.group_id = e.group_id AND gm.user_id = @viewer
+	)`},
+}
+
+// IsMediaVisibleTo reports whether viewerID may see a media record: it is
+// theirs, or a row they may see refers to it. Returns sql.ErrNoRows when the
+// media does not exist.
+func IsMediaVisibleTo(mediaID int64, viewerID int) (bool, error) {
+	query := `SELECT m.owner_id IS NOT NULL AND m.owner_id = @viewer`
+	for _, ref := range mediaReferences {
+		query += fmt.Sprintf(`
+			OR EXISTS (SELECT 1 FROM %[1]s %[2]s WHERE %[2]s.media_id = m.id AND %[3]s)`,
+			ref.Table, ref.Alias, ref.Visible)
+	}
+	var visible bool
+	err := DB.QueryRow(query+` FROM media m WHERE m.id = @media`,
+		sql.Named("media", mediaID), sql.Named("viewer", viewerID)).Scan(&visible)
+	return visible, err
+}
+
+// GetUnreferencedMedia returns the IDs of media created before the given time
+// that no row refers to, e.g. uploads whose post failed to save.
+func GetUnreferencedMedia(before time.Time) ([]int64, error) {
+	query := `SELECT m.id FROM media m WHERE m.created_at < ?`
+	for _, ref := range mediaReferences {
+		query += fmt.Sprintf(`
+			AND NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.media_id = m.id)`, ref.Table)
+	}
+	rows, err := DB.Query(query, before.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// LegacyUpload is a file stored in uploads/ before media moved to the media
+// store, and the row that refers to it.
+type LegacyUpload struct {
+	Table   string
+	Column  string
+	RowID   int64
+	OwnerID int
+	Path    string
+}
+
+// GetLegacyUploads returns every row still referring to a file in uploads/.
+func GetLegacyUploads() ([]LegacyUpload, error) {
+	uploads := make([]LegacyUpload, 0)
+	for _, ref := range mediaReferences {
+		rows, err := DB.Query(fmt.Sprintf(`SELECT id, %s, %s FROM %s WHERE %[2]s LIKE '/uploads/%%'`, ref.Owner, ref.Column, ref.Table))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			u := LegacyUpload{Table: ref.Table, Column: ref.Column}
+			if err := rows.Scan(&u.RowID, &u.OwnerID, &u.Path); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			uploads = append(uploads, u)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return uploads, nil
+}
+
+// ReplaceLegacyUpload points a row at its imported media, unless it changed in
+// the meantime. It reports whether the row was updated.
+func ReplaceLegacyUpload(u LegacyUpload, url string) (bool, error) {
+	res, err := DB.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ? AND %[2]s = ?`, u.Table, u.Column), url, u.RowID, u.Path)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,133 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+// IsSiteAdmin reports whether the user has the site admin role.
+func IsSiteAdmin(userID int) (bool, error) {
+	var role string
+	err := DB.QueryRow(`SELECT site_role FROM users WHERE id = ?`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return role == models.SiteRoleAdmin, err
+}
+
+// GetActiveSuspension returns the user's suspension, or nil if the account is
+// not suspended or the suspension has expired.
+func GetActiveSuspension(userID int) (*models.Suspension, error) {
+	var suspendedAt, until sql.NullInt64
+	var reason sql.NullString
+	err := DB.QueryRow(`
+		SELECT suspended_at, suspended_until, suspension_reason FROM users WHERE id = ?
+	`, userID).Scan(&suspendedAt, &until, &reason)
+	if err == sql.ErrNoRows || (err == nil && !suspendedAt.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &models.Suspension{SuspendedAt: time.Unix(suspendedAt.Int64, 0).UTC(), Reason: reason.String}
+	if until.Valid {
+		t := time.Unix(until.Int64, 0).UTC()
+		if !t.After(time.Now()) {
+			return nil, nil
+		}
+		s.Until = &t
+	}
+	return s, nil
+}
+
+// SuspendUser suspends an account until the given time (nil for indefinitely)
+// and deletes its sessions.
+func SuspendUser(userID int, reason string, until *time.Time) error {
+	var untilUnix any
+	if until != nil {
+		untilUnix = until.Unix()
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE users SET suspended_at = ?, suspended_until = ?, suspension_reason = ? WHERE id = ?
+	`, time.Now().Unix(), untilUnix, reason, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddModerationLog appends an entry to the moderation audit log. details must be JSON.
+func AddModerationLog(adminID *int, action, targetType string, targetID int64, reportID *int64, details string) error {
+	_, err := DB.Exec(`
+		INSERT INTO moderation_log (admin_id, action, target_type, target_id, report_id, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, adminID, action, targetType, targetID, reportID, details, time.Now().Unix())
+	return err
+}
+
+// GetModerationLog returns up to limit log entries, newest first, starting below
+// the entry ID before (0 for the first page). An empty targetType matches every target.
+func GetModerationLog(targetType string, targetID, before int64, limit int) ([]models.ModerationLogEntry, error) {
+	rows, err := DB.Query(`
+		SELECT id, admin_id, action, target_type, target_id, report_id, details, created_at
+		FROM moderation_log
+		WHERE (@type = '' OR (target_type = @type AND target_id = @target))
+		  AND (@before = 0 OR id < @before)
+		ORDER BY id DESC
+		LIMIT @limit
+	`, sql.Named("type", targetType), sql.Named("target", targetID), sql.Named("before", before), sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.ModerationLogEntry, 0)
+	for rows.Next() {
+		var e models.ModerationLogEntry
+		var details string
+		var createdAt int64
+		if err := rows.Scan(&e.ID, &e.AdminID, &e.Action, &e.TargetType, &e.TargetID, &e.ReportID, &details, &createdAt); err != nil {
+			return nil, err
+		}
+		e.Details = []byte(details)
+		e.CreatedAt = time.Unix(createdAt, 0).UTC()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SetSiteRole changes the site role of a user. It returns sql.ErrNoRows if the user does not exist.
+func SetSiteRole(userID int, role string) error {
+	res, err := DB.Exec(`UPDATE users SET site_role = ? WHERE id = ?`, role, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UnsuspendUser lifts a suspension and reports whether one was active.
+func UnsuspendUser(userID int) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL
+		WHERE id = ? AND suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > ?)
+	`, userID, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,226 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+// IsActionableNotificationType reports whether notifications of this type ask the
+// user to respond. They are always kept in-app, so requests cannot get lost.
+func IsActionableNotificationType(notificationType string) bool {
+	for _, t := range nonPrunableNotificationTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNotificationPreferences returns the channel settings of every configurable
+// notification type. Channels default to enabled.
+func GetNotificationPreferences(userID int) ([]models.NotificationPreference, error) {
+	rows, err := DB.Query(`
+		SELECT type, channel, enabled FROM notification_preferences WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disabled := make(map[string]bool)
+	for rows.Next() {
+		var nType, channel string
+		var enabled bool
+		if err := rows.Scan(&nType, &channel, &enabled); err != nil {
+			return nil, err
+		}
+		if !enabled {
+			disabled[nType+":"+channel] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		prefs = append(prefs, models.NotificationPreference{
+			Type:  t,
+			InApp: !disabled[t+":"+models.ChannelInApp] || IsActionableNotificationType(t),
+			Push:  !disabled[t+":"+models.ChannelPush],
+			Email: !disabled[t+":"+models.ChannelEmail],
+		})
+	}
+	return prefs, nil
+}
+
+// SetNotificationPreferences stores channel settings, keyed by type and then channel.
+// Types and channels must already be validated.
+func SetNotificationPreferences(userID int, settings map[string]map[string]bool) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for nType, channels := range settings {
+		for channel, enabled := range channels {
+			_, err := tx.Exec(`
+				INSERT INTO notification_preferences (user_id, type, channel, enabled)
+				VALUES (?, ?, ?, ?)
+				ON CONFLICT(user_id, type, channel)
+				DO UPDATE SET enabled = excluded.enabled, updated_at = CURRENT_TIMESTAMP
+			`, userID, nType, channel, enabled)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetNotificationDelivery returns the channels on which userID receives a notification
+// of the given type about a group or conversation (targetType is empty when the
+// notification has no such target). A muted target silences every channel, except
+// that actionable notifications are still stored in-app.
+func GetNotificationDelivery(userID int, notificationType, targetType string, targetID int64) (models.NotificationPreference, error) {
+	delivery := models.NotificationPreference{Type: notificationType, InApp: true, Push: true, Email: true}
+
+	rows, err := DB.Query(`
+		SELECT channel, enabled FROM notification_preferences
+		WHERE user_id = ? AND type = ?
+	`, userID, notificationType)
+	if err != nil {
+		return delivery, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var channel string
+		var enabled bool
+		if err := rows.Scan(&channel, &enabled); err != nil {
+			return delivery, err
+		}
+		switch channel {
+		case models.ChannelInApp:
+			delivery.InApp = enabled
+		case models.ChannelPush:
+			delivery.Push = enabled
+		case models.ChannelEmail:
+			delivery.Email = enabled
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return delivery, err
+	}
+
+	if targetType != "" {
+		muted, err := IsNotificationTargetMuted(userID, targetType, targetID)
+		if err != nil {
+			return delivery, err
+		}
+		if muted {
+			delivery.InApp, delivery.Push, delivery.Email = false, false, false
+		}
+	}
+
+	if IsActionableNotificationType(notificationType) {
+		delivery.InApp = true
+	}
+	return delivery, nil
+}
+
+// MuteNotifications mutes a group or conversation for userID until the given time,
+// or indefinitely when until is nil. Muting again replaces the period.
+func MuteNotifications(userID int, targetType string, targetID int64, until *time.Time) error {
+	var mutedUntil any
+	if until != nil {
+		mutedUntil = until.Unix()
+	}
+	_, err := DB.Exec(`
+		INSERT INTO notification_mutes (user_id, target_type, target_id, muted_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, target_type, target_id)
+		DO UPDATE SET muted_until = excluded.muted_until, created_at = CURRENT_TIMESTAMP
+	`, userID, targetType, targetID, mutedUntil)
+	return err
+}
+
+// UnmuteNotifications removes a mute and reports whether one existed.
+func UnmuteNotifications(userID int, targetType string, targetID int64) (bool, error) {
+	res, err := DB.Exec(`
+		DELETE FROM notification_mutes
+		WHERE user_id = ? AND target_type = ? AND target_id = ?
+	`, userID, targetType, targetID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetActiveNotificationMutes returns the mutes of userID that have not expired.
+func GetActiveNotificationMutes(userID int) ([]models.NotificationMute, error) {
+	rows, err := DB.Query(`
+		SELECT target_type, target_id, muted_until, created_at
+		FROM notification_mutes
+		WHERE user_id = ? AND (muted_until IS NULL OR muted_until > ?)
+		ORDER BY created_at DESC
+	`, userID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mutes := make([]models.NotificationMute, 0)
+	for rows.Next() {
+		var m models.NotificationMute
+		var mutedUntil sql.NullInt64
+		if err := rows.Scan(&m.TargetType, &m.TargetID, &mutedUntil, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		if mutedUntil.Valid {
+			t := time.Unix(mutedUntil.Int64, 0).UTC()
+			m.MutedUntil = &t
+		}
+		mutes = append(mutes, m)
+	}
+	return mutes, rows.Err()
+}
+
+// IsNotificationTargetMuted reports whether userID currently mutes the group or conversation.
+func IsNotificationTargetMuted(userID int, targetType string, targetID int64) (bool, error) {
+	var muted bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM notification_mutes
+			WHERE user_id = ? AND target_type = ? AND target_id = ?
+			  AND (muted_until IS NULL OR muted_until > ?)
+		)
+	`, userID, targetType, targetID, time.Now().Unix()).Scan(&muted)
+	return muted, err
+}
+
+// GetMutingUserIDs returns the users currently muting the group or conversation.
+func GetMutingUserIDs(targetType string, targetID int64) (map[int]bool, error) {
+	rows, err := DB.Query(`
+		SELECT user_id FROM notification_mutes
+		WHERE target_type = ? AND target_id = ?
+		  AND (muted_until IS NULL OR muted_until > ?)
+	`, targetType, targetID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs[id] = true
+	}
+	return userIDs, rows.Err()
+}
@@ -1,5 +1,11 @@
 package queries
 
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
 var nonPrunableNotificationTypes = []string{
 	"follow_request",
 	"group_invitation",
@@ -7,73 +13,128 @@ var nonPrunableNotificationTypes = []string{
 }
 
 func CreateNotification(userID int, actorID *int, notificationType string, data string) (int64, error) {
+	return CreateGroupedNotification(userID, actorID, notificationType, data, "")
+}
+
+// CreateGroupedNotification stores a notification that collapses with the user's other
+// notifications of the same groupKey. An empty groupKey never collapses.
+func CreateGroupedNotification(userID int, actorID *int, notificationType, data, groupKey string) (int64, error) {
 	result, err := DB.Exec(`
-		INSERT INTO notifications (user_id, actor_id, type, data)
-		VALUES (?, ?, ?, ?)
-	`, userID, actorID, notificationType, data)
+		INSERT INTO notifications (user_id, actor_id, type, data, group_key)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+	`, userID, actorID, notificationType, data, groupKey)
 	if err != nil {
 		return 0, err
 	}
 	return result.LastInsertId()
 }
 
-func GetNotifications(userID int) ([]map[string]interface{}, error) {
+// GetNotificationsPage returns up to limit inbox entries older than the entry with ID
+// before (0 for the first page), newest first. Unread and read notifications of a
+// group collapse separately, so new activity never hides in an already read entry.
+func GetNotificationsPage(userID int, before int64, limit int) ([]models.Notification, error) {
 	rows, err := DB.Query(`
-		SELECT n.id, n.actor_id, n.type, n.data, n.read, n.created_at,
+		WITH entries AS (
+			SELECT MAX(id) AS id, COUNT(*) AS cnt, COUNT(DISTINCT actor_id) AS actor_count
+			FROM notifications
+			WHERE user_id = ?1
+			GROUP BY COALESCE(group_key, 'id:' || id), read
+		)
+		SELECT n.id, n.actor_id, n.type, COALESCE(n.data, ''), n.read, n.created_at,
+		       COALESCE(n.group_key, ''), e.cnt, e.actor_count,
 		       u.first_name, u.last_name, u.avatar, u.username
-		FROM notifications n
+		FROM entries e
+		JOIN notifications n ON n.id = e.id
 		LEFT JOIN users u ON n.actor_id = u.id
-		WHERE user_id = ?
-		ORDER BY n.created_at DESC
-	`, userID)
+		WHERE ?2 = 0 OR e.id < ?2
+		ORDER BY e.id DESC
+		LIMIT ?3
+	`, userID, before, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	notifications := make([]map[string]interface{}, 0)
+	notifications := make([]models.Notification, 0)
 	for rows.Next() {
-		var id int
-		var actorID *int
-		var nType, data string
-		var read int
-		var createdAt string
-		var firstName, lastName, avatar, username *string
-		err := rows.Scan(&id, &actorID, &nType, &data, &read, &createdAt, &firstName, &lastName, &avatar, &username)
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.ActorID, &n.Type, &n.Data, &n.Read, &n.CreatedAt,
+			&n.GroupKey, &n.Count, &n.ActorCount,
+			&n.Actor.FirstName, &n.Actor.LastName, &n.Actor.Avatar, &n.Actor.Username)
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		notif := map[string]interface{}{
-			"id":         id,
-			"actor_id":   actorID,
-			"type":       nType,
-			"data":       data,
-			"read":       read,
-			"created_at": createdAt,
-			"actor": map[string]interface{}{
-				"first_name": firstName,
-				"last_name":  lastName,
-				"avatar":     avatar,
-				"username":   username,
-			},
+	for i, n := range notifications {
+		if n.Count < 2 {
+			continue
+		}
+		actors, err := getNotificationGroupActors(userID, n.GroupKey, n.Read, maxGroupActors)
+		if err != nil {
+			return nil, err
 		}
-		notifications = append(notifications, notif)
+		notifications[i].Actors = actors
 	}
 	return notifications, nil
 }
 
+// maxGroupActors bounds the actors listed for a collapsed inbox entry
+const maxGroupActors = 3
+
+// getNotificationGroupActors returns the most recent distinct actors of a notification group.
+func getNotificationGroupActors(userID int, groupKey string, read int, limit int) ([]models.NotificationActor, error) {
+	rows, err := DB.Query(`
+		SELECT u.first_name, u.last_name, u.avatar, u.username
+		FROM notifications n
+		JOIN users u ON u.id = n.actor_id
+		WHERE n.user_id = ? AND n.group_key = ? AND n.read = ?
+		GROUP BY n.actor_id
+		ORDER BY MAX(n.id) DESC
+		LIMIT ?
+	`, userID, groupKey, read, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actors := make([]models.NotificationActor, 0, limit)
+	for rows.Next() {
+		var a models.NotificationActor
+		if err := rows.Scan(&a.FirstName, &a.LastName, &a.Avatar, &a.Username); err != nil {
+			return nil, err
+		}
+		actors = append(actors, a)
+	}
+	return actors, rows.Err()
+}
+
+// CountUnreadNotifications returns the number of unread notifications of a user.
+func CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read = 0
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks a notification as read, together with the unread
+// notifications collapsed into the same inbox entry.
 func MarkNotificationRead(userID int, notificationID int) error {
 	_, err := DB.Exec(`
 		UPDATE notifications
 		SET read = 1
-		WHERE id = ? AND user_id = ?
-	`, notificationID, userID)
-	if err != nil {
-		return err
-	}
-
-	return PruneReadNotifications(userID)
+		WHERE user_id = ?1
+		  AND (id = ?2 OR (read = 0 AND group_key = (
+		  	SELECT group_key FROM notifications WHERE id = ?2 AND user_id = ?1
+		  )))
+	`, userID, notificationID)
+	return err
 }
 
 func MarkAllNotificationsRead(userID int) error {
@@ -82,11 +143,7 @@ func MarkAllNotificationsRead(userID int) error {
 		SET read = 1
 		WHERE user_id = ?
 	`, userID)
-	if err != nil {
-		return err
-	}
-
-	return PruneReadNotifications(userID)
+	return err
 }
 
 // GetNotificationByID retrieves a single notification by ID
@@ -130,27 +187,51 @@ func DeleteNotification(notificationID int64, userID int) error {
 	return err
 }
 
-// PruneReadNotifications keeps only the 20 most recent read, non-request notifications.
+// PruneReadNotifications deletes read notifications beyond the keep most recent ones
+// and those older than maxAge. A zero keep or maxAge disables that limit.
 // Actionable request notifications are excluded from pruning.
-func PruneReadNotifications(userID int) error {
-	_, err := DB.Exec(`
-		DELETE FROM notifications
-		WHERE user_id = ?
-		  AND read = 1
-		  AND type NOT IN (?, ?, ?)
-		  AND id NOT IN (
-		  	SELECT id
-		  	FROM notifications
-		  	WHERE user_id = ?
-		  	  AND read = 1
-		  	  AND type NOT IN (?, ?, ?)
-		  	ORDER BY datetime(created_at) DESC, id DESC
-		  	LIMIT 20
-		  )
-	`, userID,
-		nonPrunableNotificationTypes[0], nonPrunableNotificationTypes[1], nonPrunableNotificationTypes[2],
-		userID,
-		nonPrunableNotificationTypes[0], nonPrunableNotificationTypes[1], nonPrunableNotificationTypes[2],
-	)
-	return err
+func PruneReadNotifications(userID int, keep int, maxAge time.Duration) error {
+	if keep > 0 {
+		_, err := DB.Exec(`
+			DELETE FROM notifications
+			WHERE user_id = @user
+			  AND read = 1
+			  AND type NOT IN (@t1, @t2, @t3)
+			  AND id NOT IN (
+			  	SELECT id
+			  	FROM notifications
+			  	WHERE user_id = @user
+			  	  AND read = 1
+			  	  AND type NOT IN (@t1, @t2, @t3)
+			  	ORDER BY datetime(created_at) DESC, id DESC
+			  	LIMIT @keep
+			  )
+		`, sql.Named("user", userID), sql.Named("keep", keep),
+			sql.Named("t1", nonPrunableNotificationTypes[0]),
+			sql.Named("t2", nonPrunableNotificationTypes[1]),
+			sql.Named("t3", nonPrunableNotificationTypes[2]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if maxAge > 0 {
+		_, err := DB.Exec(`
+			DELETE FROM notifications
+			WHERE user_id = @user
+			  AND read = 1
+			  AND type NOT IN (@t1, @t2, @t3)
+			  AND datetime(created_at) < datetime(@cutoff)
+		`, sql.Named("user", userID),
+			sql.Named("cutoff", time.Now().Add(-maxAge).UTC().Format("2006-01-02 15:04:05")),
+			sql.Named("t1", nonPrunableNotificationTypes[0]),
+			sql.Named("t2", nonPrunableNotificationTypes[1]),
+			sql.Named("t3", nonPrunableNotificationTypes[2]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,142 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"strings"
+)
+
+// GetPostAttachments returns the attachments of the given posts in order,
+// keyed by post ID. Posts without attachments are left out.
+func GetPostAttachments(postIDs []int64) (map[int64][]models.PostAttachment, error) {
+	attachments := make(map[int64][]models.PostAttachment, len(postIDs))
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+	args := make([]interface{}, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+	rows, err := DB.Query(`
+		SELECT id, post_id, path, alt_text FROM post_attachments
+		WHERE post_id IN (?`+strings.Repeat(", ?", len(postIDs)-1)+`)
+		ORDER BY post_id, position, id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.PostAttachment
+		var postID int64
+		if err := rows.Scan(&a.ID, &postID, &a.URL, &a.AltText); err != nil {
+			return nil, err
+		}
+		attachments[postID] = append(attachments[postID], a)
+	}
+	return attachments, rows.Err()
+}
+
+// withAttachments loads the attachments of posts and sets their ImagePath.
+func withAttachments(posts ...*models.Post) error {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	attachments, err := GetPostAttachments(ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		p.Attachments = attachments[p.ID]
+		if p.Attachments == nil {
+			p.Attachments = []models.PostAttachment{}
+		}
+		if len(p.Attachments) > 0 {
+			p.ImagePath = p.Attachments[0].URL
+		}
+	}
+	return nil
+}
+
+// GetPostAttachmentPaths returns the media URLs of the attachments of a post.
+func GetPostAttachmentPaths(postID int64) ([]string, error) {
+	rows, err := DB.Query(`SELECT path FROM post_attachments WHERE post_id = ? ORDER BY position, id`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// setPostAttachments makes attachments the attachments of a post, in that
+// order. Attachments with an ID are kept, with their alt text updated; those
+// without are added and get their ID set. It returns the URLs of the
+// attachments removed, which the caller releases. IDs of attachments of other
+// posts are ignored.
+func setPostAttachments(tx *sql.Tx, postID int64, attachments []models.PostAttachment) ([]string, error) {
+	kept := make(map[int64]bool, len(attachments))
+	for _, a := range attachments {
+		if a.ID != 0 {
+			kept[a.ID] = true
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id, path FROM post_attachments WHERE post_id = ?`, postID)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var removedIDs []int64
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !kept[id] {
+			removed = append(removed, path)
+			removedIDs = append(removedIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range removedIDs {
+		if _, err := tx.Exec(`DELETE FROM post_attachments WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, a := range attachments {
+		if a.ID != 0 {
+			_, err = tx.Exec(`
+				UPDATE post_attachments SET position = ?, alt_text = ? WHERE id = ? AND post_id = ?
+			`, i, a.AltText, a.ID, postID)
+		} else {
+			var res sql.Result
+			res, err = tx.Exec(`
+				INSERT INTO post_attachments (post_id, position, path, alt_text) VALUES (?, ?, ?, ?)
+			`, postID, i, a.URL, a.AltText)
+			if err == nil {
+				attachments[i].ID, err = res.LastInsertId()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,158 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"strings"
+)
+
+// GetPostAudience returns the users a "selected" post is shared with, with
+// the follow status of its author toward each.
+func GetPostAudience(postID int64, authorID int) ([]models.UserSearchResult, error) {
+	rows, err := DB.Query(`
+		SELECT
+			u.id,
+			u.username,
+			u.first_name,
+			u.last_name,
+			COALESCE(u.nickname, '')   AS nickname,
+			COALESCE(u.avatar, '')     AS avatar,
+			COALESCE(u.about_me, '')   AS about_me,
+			u.is_public,
+			COALESCE(f.status, 'none') AS follow_status,
+			CASE WHEN fm.follower_id IS NOT NULL THEN 1 ELSE 0 END AS follows_me
+		FROM post_selected_followers psf
+		JOIN users u ON u.id = psf.user_id
+		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
+		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
+		WHERE psf.post_id = ?
+		ORDER BY u.first_name, u.last_name
+	`, authorID, authorID, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanUserResults(rows)
+}
+
+// GetNonFollowers returns those of userIDs who are not accepted followers of
+// userID, in the order given.
+func GetNonFollowers(userID int, userIDs []int) ([]int, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(userIDs)+1)
+	args = append(args, userID)
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	rows, err := DB.Query(`
+		SELECT follower_id FROM followers
+		WHERE following_id = ? AND status = 'accepted'
+		  AND follower_id IN (?`+strings.Repeat(", ?", len(userIDs)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followers := make(map[int]bool, len(userIDs))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		followers[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var others []int
+	for _, id := range userIDs {
+		if !followers[id] {
+			others = append(others, id)
+		}
+	}
+	return others, nil
+}
+
+// ReplacePostAudience makes userIDs the audience of a "selected" post.
+func ReplacePostAudience(postID int64, authorID int, userIDs []int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceAudience(tx, postID, authorID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddToPostAudience adds userIDs to the audience of a "selected" post.
+func AddToPostAudience(postID int64, authorID int, userIDs []int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addToAudience(tx, postID, authorID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveFromPostAudience removes a user from the audience of a post. It
+// reports whether they were in it.
+func RemoveFromPostAudience(postID int64, userID int) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM post_selected_followers WHERE post_id = ? AND user_id = ?`, postID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// replaceAudience removes the members of the audience of a post not in
+// userIDs and adds the others.
+func replaceAudience(tx *sql.Tx, postID int64, authorID int, userIDs []int) error {
+	args := []interface{}{postID}
+	keep := ""
+	if len(userIDs) > 0 {
+		keep = ` AND user_id NOT IN (?` + strings.Repeat(", ?", len(userIDs)-1) + `)`
+		for _, id := range userIDs {
+			args = append(args, id)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM post_selected_followers WHERE post_id = ?`+keep, args...); err != nil {
+		return err
+	}
+	return addToAudience(tx, postID, authorID, userIDs)
+}
+
+// addToAudience adds userIDs to the audience of a post. Users who are not
+// accepted followers of the author are skipped, in case they stopped following
+// since the caller checked.
+func addToAudience(tx *sql.Tx, postID int64, authorID int, userIDs []int) error {
+	for _, id := range userIDs {
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO post_selected_followers (post_id, user_id)
+			SELECT ?, ? WHERE EXISTS(
+				SELECT 1 FROM followers WHERE follower_id = ? AND following_id = ? AND status = 'accepted'
+			)
+		`, postID, id, id, authorID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearAudience removes every member of the audience of a post, whose
+// privacy is no longer "selected".
+func clearAudience(tx *sql.Tx, postID int64) error {
+	_, err := tx.Exec(`DELETE FROM post_selected_followers WHERE post_id = ?`, postID)
+	return err
+}
@@ -59,10 +59,3 @@ func GetPostGroupID(postID int64) (*int64, error) {
 	err := DB.QueryRow(`SELECT group_id FROM posts WHERE id = ?`, postID).Scan(&groupID)
 	return groupID, err
 }
-
-// GetPostImagePath returns the image_path of the post (if it has one)
-func GetPostImagePath(postID int64) (*string, error) {
-	var imagePath *string
-	err := DB.QueryRow(`SELECT image_path FROM posts WHERE id = ?`, postID).Scan(&imagePath)
-	return imagePath, err
-}
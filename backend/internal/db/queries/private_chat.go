@@ -10,15 +10,20 @@ import (
 
 // CanMessage checks if two users can message each other.
 // Returns true if at least one user follows the other with accepted status,
-// OR if the recipient (user2) has a public profile.
+// OR if the recipient (user2) has a public profile, unless either user blocks the other.
 func CanMessage(user1ID, user2ID int) (bool, error) {
 	// Ensure no self-chat
 	if user1ID == user2ID {
 		return false, nil
 	}
 
+	blocked, err := IsBlockedBetween(user1ID, user2ID)
+	if err != nil || blocked {
+		return false, err
+	}
+
 	var canMsg bool
-	err := DB.QueryRow(`
+	err = DB.QueryRow(`
 		SELECT (
 			EXISTS(
 				SELECT 1 FROM followers
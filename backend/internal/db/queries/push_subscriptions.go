@@ -0,0 +1,74 @@
+package queries
+
+import (
+	"backend/internal/models"
+)
+
+// UpsertPushSubscription stores a subscription for userID, or updates the keys
+// of an endpoint userID already subscribed. Returns sql.ErrNoRows when another
+// user holds the endpoint: it stays theirs until they unsubscribe or it expires.
+func UpsertPushSubscription(userID int, endpoint, p256dh, auth, userAgent string) (int64, error) {
+	_, err := DB.Exec(`
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			user_id = excluded.user_id,
+			p256dh = excluded.p256dh,
+			auth = excluded.auth,
+			user_agent = excluded.user_agent
+		WHERE push_subscriptions.user_id = excluded.user_id
+	`, userID, endpoint, p256dh, auth, userAgent)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = DB.QueryRow(`SELECT id FROM push_subscriptions WHERE endpoint = ? AND user_id = ?`, endpoint, userID).Scan(&id)
+	return id, err
+}
+
+// GetPushSubscriptions returns the push subscriptions of a user, newest first.
+func GetPushSubscriptions(userID int) ([]models.PushSubscription, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, endpoint, p256dh, auth, user_agent, created_at, last_used_at
+		FROM push_subscriptions
+		WHERE user_id = ?
+		ORDER BY id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]models.PushSubscription, 0)
+	for rows.Next() {
+		var s models.PushSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.UserAgent, &s.CreatedAt, &s.LastUsedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// DeletePushSubscription removes a subscription of userID and reports whether it existed.
+func DeletePushSubscription(userID int, id int64) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM push_subscriptions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeletePushSubscriptionByEndpoint removes a subscription the push service reported as gone.
+func DeletePushSubscriptionByEndpoint(endpoint string) error {
+	_, err := DB.Exec(`DELETE FROM push_subscriptions WHERE endpoint = ?`, endpoint)
+	return err
+}
+
+// TouchPushSubscription records a successful delivery.
+func TouchPushSubscription(id int64) error {
+	_, err := DB.Exec(`UPDATE push_subscriptions SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,270 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReportTarget is the reported content (or profile) as it currently exists.
+// Only the fields matching its type are set.
+type ReportTarget struct {
+	AuthorID       int
+	Content        string
+	PostID         int64 // comments: the post they belong to
+	ConversationID int   // private messages
+	GroupID        int   // group messages
+}
+
+// GetReportTarget loads the content a report points at. It returns sql.ErrNoRows
+// if it does not exist (anymore).
+func GetReportTarget(targetType string, targetID int64) (ReportTarget, error) {
+	var t ReportTarget
+	var err error
+	switch targetType {
+	case "post":
+		err = DB.QueryRow(`
+			SELECT user_id, content || COALESCE((
+				SELECT group_concat(char(10) || '[image] ' || path, '')
+				FROM (SELECT path FROM post_attachments WHERE post_id = posts.id ORDER BY position, id)
+			), '')
+			FROM posts WHERE id = ?
+		`, targetID).Scan(&t.AuthorID, &t.Content)
+	case "comment":
+		err = DB.QueryRow(`SELECT user_id, content, post_id FROM comments WHERE id = ?`, targetID).
+			Scan(&t.AuthorID, &t.Content, &t.PostID)
+	case "private_message":
+		err = DB.QueryRow(`SELECT user_id, content, conversation_id FROM private_chat_messages WHERE id = ?`, targetID).
+			Scan(&t.AuthorID, &t.Content, &t.ConversationID)
+	case "group_message":
+		err = DB.QueryRow(`SELECT user_id, content, group_id FROM group_chat_messages WHERE id = ?`, targetID).
+			Scan(&t.AuthorID, &t.Content, &t.GroupID)
+	case "user":
+		err = DB.QueryRow(`
+			SELECT id, username || CASE WHEN COALESCE(about_me, '') != '' THEN char(10) || about_me ELSE '' END
+			FROM users WHERE id = ?
+		`, targetID).Scan(&t.AuthorID, &t.Content)
+	default:
+		err = fmt.Errorf("unknown report target type %q", targetType)
+	}
+	return t, err
+}
+
+// DeleteReportTarget removes reported content and reports whether it still existed.
+// Replies of a removed comment are removed with it.
+func DeleteReportTarget(targetType string, targetID int64) (bool, error) {
+	var table string
+	switch targetType {
+	case "post":
+		table = "posts"
+	case "comment":
+		table = "comments"
+	case "private_message":
+		table = "private_chat_messages"
+	case "group_message":
+		table = "group_chat_messages"
+	default:
+		return false, fmt.Errorf("%s reports have no content to remove", targetType)
+	}
+	res, err := DB.Exec(`DELETE FROM `+table+` WHERE id = ?`, targetID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// HasPendingReport reports whether reporterID already has an open or triaged report of the target.
+func HasPendingReport(reporterID int, targetType string, targetID int64) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM reports
+			WHERE reporter_id = ? AND target_type = ? AND target_id = ? AND status IN ('open', 'triaged')
+		)
+	`, reporterID, targetType, targetID).Scan(&exists)
+	return exists, err
+}
+
+// CreateReport files a new open report.
+func CreateReport(report models.Report) (int64, error) {
+	now := time.Now().Unix()
+	res, err := DB.Exec(`
+		INSERT INTO reports (reporter_id, target_type, target_id, target_user_id, reason, details, content_snapshot, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, report.ReporterID, report.TargetType, report.TargetID, report.TargetUserID, report.Reason, report.Details,
+		report.ContentSnapshot, now, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+const reportColumns = `r.id, r.reporter_id, r.target_type, r.target_id, r.target_user_id, r.reason, r.details,
+	r.content_snapshot, r.status, r.assignee_id, r.resolution, r.resolution_note, r.created_at, r.updated_at, r.closed_at`
+
+type reportScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row reportScanner, extra ...any) (models.Report, error) {
+	var r models.Report
+	var createdAt, updatedAt int64
+	var closedAt sql.NullInt64
+	dest := []any{&r.ID, &r.ReporterID, &r.TargetType, &r.TargetID, &r.TargetUserID, &r.Reason, &r.Details,
+		&r.ContentSnapshot, &r.Status, &r.AssigneeID, &r.Resolution, &r.ResolutionNote, &createdAt, &updatedAt, &closedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return r, err
+	}
+	r.CreatedAt = time.Unix(createdAt, 0).UTC()
+	r.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	if closedAt.Valid {
+		t := time.Unix(closedAt.Int64, 0).UTC()
+		r.ClosedAt = &t
+	}
+	return r, nil
+}
+
+// GetReport returns a report. It returns sql.ErrNoRows if it does not exist.
+func GetReport(id int64) (models.Report, error) {
+	return scanReport(DB.QueryRow(`SELECT `+reportColumns+` FROM reports r WHERE r.id = ?`, id))
+}
+
+// ReportFilter selects reports in the admin listing. Empty fields match everything;
+// Status "pending" matches open and triaged reports.
+type ReportFilter struct {
+	Status     string
+	TargetType string
+	Reason     string
+	Before     int64 // only reports with a lower ID (0 for the first page)
+}
+
+// ListReports returns up to limit reports matching filter, newest first, each
+// with the number of pending reports of the same target.
+func ListReports(filter ReportFilter, limit int) ([]models.Report, error) {
+	rows, err := DB.Query(`
+		SELECT `+reportColumns+`,
+			(SELECT COUNT(*) FROM reports o
+			 WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status IN ('open', 'triaged'))
+		FROM reports r
+		WHERE (@status = ''
+		       OR (@status = 'pending' AND r.status IN ('open', 'triaged'))
+		       OR r.status = @status)
+		  AND (@type = '' OR r.target_type = @type)
+		  AND (@reason = '' OR r.reason = @reason)
+		  AND (@before = 0 OR r.id < @before)
+		ORDER BY r.id DESC
+		LIMIT @limit
+	`, sql.Named("status", filter.Status), sql.Named("type", filter.TargetType), sql.Named("reason", filter.Reason),
+		sql.Named("before", filter.Before), sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]models.Report, 0)
+	for rows.Next() {
+		var pending int
+		report, err := scanReport(rows, &pending)
+		if err != nil {
+			return nil, err
+		}
+		report.PendingReports = pending
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// GetReportsByTarget returns every report of a target, newest first.
+func GetReportsByTarget(targetType string, targetID int64) ([]models.Report, error) {
+	return queryReports(`SELECT `+reportColumns+` FROM reports r WHERE r.target_type = ? AND r.target_id = ? ORDER BY r.id DESC`,
+		targetType, targetID)
+}
+
+// GetReportsByReporter returns the reports filed by a user, newest first, without snapshots.
+func GetReportsByReporter(reporterID int) ([]models.Report, error) {
+	reports, err := queryReports(`SELECT `+reportColumns+` FROM reports r WHERE r.reporter_id = ? ORDER BY r.id DESC`, reporterID)
+	for i := range reports {
+		reports[i].ContentSnapshot = ""
+		reports[i].ResolutionNote = nil
+		reports[i].AssigneeID = nil
+	}
+	return reports, err
+}
+
+func queryReports(query string, args ...any) ([]models.Report, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]models.Report, 0)
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// TriageReport marks an open report as being reviewed by adminID.
+// It reports false if the report was not open.
+func TriageReport(id int64, adminID int) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE reports SET status = 'triaged', assignee_id = ?, updated_at = ?
+		WHERE id = ? AND status = 'open'
+	`, adminID, time.Now().Unix(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CloseTargetReports closes every pending report of a target with the given status
+// ("resolved" or "dismissed") and returns them as they were before closing.
+func CloseTargetReports(targetType string, targetID int64, adminID int, status, resolution, note string) ([]models.Report, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT `+reportColumns+` FROM reports r
+		WHERE r.target_type = ? AND r.target_id = ? AND r.status IN ('open', 'triaged')
+		ORDER BY r.id
+	`, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	var closed []models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		closed = append(closed, report)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	_, err = tx.Exec(`
+		UPDATE reports
+		SET status = ?, resolution = ?, resolution_note = NULLIF(?, ''), assignee_id = COALESCE(assignee_id, ?),
+			updated_at = ?, closed_at = ?
+		WHERE target_type = ? AND target_id = ? AND status IN ('open', 'triaged')
+	`, status, resolution, note, adminID, now, now, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return closed, tx.Commit()
+}
@@ -0,0 +1,123 @@
+package queries
+
+import (
+	"time"
+)
+
+// ScheduledJob is a pending background job as stored in scheduled_jobs.
+type ScheduledJob struct {
+	ID      int64
+	Kind    string
+	Key     string
+	Payload string
+	RunAt   time.Time
+}
+
+// UpsertScheduledJob stores a pending job under key. An existing job with the same
+// key is rescheduled unless it is currently running.
+func UpsertScheduledJob(kind, key, payload string, runAt time.Time) error {
+	_, err := DB.Exec(`
+		INSERT INTO scheduled_jobs (kind, job_key, payload, run_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(job_key) DO UPDATE SET
+			kind = excluded.kind,
+			payload = excluded.payload,
+			run_at = excluded.run_at,
+			status = 'pending',
+			last_error = NULL,
+			finished_at = NULL
+		WHERE scheduled_jobs.status != 'running'
+	`, kind, key, payload, runAt.Unix())
+	return err
+}
+
+// CancelScheduledJobs cancels every pending job whose key starts with keyPrefix.
+func CancelScheduledJobs(keyPrefix string) (int64, error) {
+	res, err := DB.Exec(`
+		UPDATE scheduled_jobs
+		SET status = 'cancelled', finished_at = CURRENT_TIMESTAMP
+		WHERE status = 'pending' AND substr(job_key, 1, length(?1)) = ?1
+	`, keyPrefix)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetDueScheduledJobs returns up to limit pending jobs whose run time has passed, oldest first.
+func GetDueScheduledJobs(now time.Time, limit int) ([]ScheduledJob, error) {
+	rows, err := DB.Query(`
+		SELECT id, kind, job_key, payload, run_at
+		FROM scheduled_jobs
+		WHERE status = 'pending' AND run_at <= ?
+		ORDER BY run_at, id
+		LIMIT ?
+	`, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []ScheduledJob
+	for rows.Next() {
+		var j ScheduledJob
+		var runAt int64
+		if err := rows.Scan(&j.ID, &j.Kind, &j.Key, &j.Payload, &runAt); err != nil {
+			return nil, err
+		}
+		j.RunAt = time.Unix(runAt, 0)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimScheduledJob marks a pending job as running. It reports false when another
+// worker claimed or cancelled the job first.
+func ClaimScheduledJob(id int64) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE scheduled_jobs SET status = 'running'
+		WHERE id = ? AND status = 'pending'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// FinishScheduledJob records the outcome of a running job.
+func FinishScheduledJob(id int64, jobErr error) error {
+	status, lastError := "done", any(nil)
+	if jobErr != nil {
+		status, lastError = "failed", jobErr.Error()
+	}
+	_, err := DB.Exec(`
+		UPDATE scheduled_jobs
+		SET status = ?, last_error = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, status, lastError, id)
+	return err
+}
+
+// FailInterruptedJobs marks jobs left running by a previous process as failed,
+// so they are never executed twice.
+func FailInterruptedJobs() (int64, error) {
+	res, err := DB.Exec(`
+		UPDATE scheduled_jobs
+		SET status = 'failed', last_error = 'interrupted by server restart', finished_at = CURRENT_TIMESTAMP
+		WHERE status = 'running'
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteFinishedJobs removes done, failed and cancelled jobs finished before cutoff.
+func DeleteFinishedJobs(cutoff time.Time) error {
+	_, err := DB.Exec(`
+		DELETE FROM scheduled_jobs
+		WHERE status IN ('done', 'failed', 'cancelled') AND finished_at < ?
+	`, cutoff.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
@@ -4,8 +4,8 @@ import (
 	"backend/internal/models"
 )
 
-// GetSuggestedUsers returns 5 random users excluding the current user,
-// with the viewer's follow status included for each result.
+// GetSuggestedUsers returns 5 random users excluding the current user and users
+// blocked either way, with the viewer's follow status included for each result.
 func GetSuggestedUsers(currentUserID int) ([]models.UserSearchResult, error) {
 	rows, err := DB.Query(`
 		SELECT
@@ -23,9 +23,10 @@ func GetSuggestedUsers(currentUserID int) ([]models.UserSearchResult, error) {
 		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
 		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
 		WHERE u.id != ?
+		  AND `+notBlockedWith("u.id", "?")+`
 		ORDER BY RANDOM()
 		LIMIT 5
-	`, currentUserID, currentUserID, currentUserID)
+	`, currentUserID, currentUserID, currentUserID, currentUserID, currentUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +34,8 @@ func GetSuggestedUsers(currentUserID int) ([]models.UserSearchResult, error) {
 	return scanUserResults(rows)
 }
 
-// SearchUsers returns up to 50 users matching the search term, excluding the current user,
-// with the viewer's follow status included for each result.
+// SearchUsers returns up to 50 users matching the search term, excluding the current user
+// and users blocked either way, with the viewer's follow status included for each result.
 func SearchUsers(term string, currentUserID int) ([]models.UserSearchResult, error) {
 	like := "%" + term + "%"
 	rows, err := DB.Query(`
@@ -53,6 +54,7 @@ func SearchUsers(term string, currentUserID int) ([]models.UserSearchResult, err
 		LEFT JOIN followers f  ON f.follower_id  = ? AND f.following_id = u.id
 		LEFT JOIN followers fm ON fm.follower_id = u.id AND fm.following_id = ? AND fm.status = 'accepted'
 		WHERE u.id != ?
+		  AND `+notBlockedWith("u.id", "?")+`
 		  AND (
 			    LOWER(u.username)              LIKE LOWER(?)
 			 OR LOWER(u.first_name)            LIKE LOWER(?)
@@ -61,7 +63,7 @@ func SearchUsers(term string, currentUserID int) ([]models.UserSearchResult, err
 		  )
 		ORDER BY u.first_name, u.last_name
 		LIMIT 50
-	`, currentUserID, currentUserID, currentUserID, like, like, like, like)
+	`, currentUserID, currentUserID, currentUserID, currentUserID, currentUserID, like, like, like, like)
 	if err != nil {
 		return nil, err
 	}
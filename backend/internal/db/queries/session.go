@@ -45,3 +45,12 @@ func DeleteSession(sessionID string) error {
 	_, err := DB.Exec(`DELETE FROM sessions WHERE id = ?`, sessionID)
 	return err
 }
+
+// DeleteUserSessions logs a user out everywhere and returns how many sessions were deleted.
+func DeleteUserSessions(userID int) (int64, error) {
+	res, err := DB.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
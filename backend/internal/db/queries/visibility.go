@@ -0,0 +1,176 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// postVisibleToViewer is the SQL form of the post visibility policy (see the
+// policy package). It expects the post aliased as p and a named @viewer argument:
+//   - group posts    → members of the group only
+//   - own posts      → always visible to the author
+//   - public         → everyone
+//   - followers      → accepted followers of the author
+//   - selected       → the members of its audience list, or without a list
+//     the users in post_selected_followers, who still follow the author
+//
+// Posts are never visible between users when one of them blocks the other.
+var postVisibleToViewer = `(
+	` + notBlockedWith("p.user_id", "@viewer") + ` AND CASE
+		WHEN p.group_id IS NOT NULL THEN EXISTS(
+			SELECT 1 FROM group_members gm
+			WHERE gm.group_id = p.group_id AND gm.user_id = @viewer
+		)
+		WHEN p.user_id = @viewer THEN 1
+		WHEN p.privacy = 'public' THEN 1
+		WHEN p.privacy = 'followers' THEN EXISTS(
+			SELECT 1 FROM followers f
+			WHERE f.follower_id = @viewer AND f.following_id = p.user_id AND f.status = 'accepted'
+		)
+		WHEN p.privacy = 'selected' AND p.audience_list_id IS NOT NULL THEN EXISTS(
+			SELECT 1 FROM audience_list_members alm
+			JOIN followers f ON f.follower_id = alm.user_id AND f.following_id = p.user_id AND f.status = 'accepted'
+			WHERE alm.list_id = p.audience_list_id AND alm.user_id = @viewer
+		)
+		WHEN p.privacy = 'selected' THEN EXISTS(
+			SELECT 1 FROM post_selected_followers psf
+			JOIN followers f ON f.follower_id = psf.user_id AND f.following_id = p.user_id AND f.status = 'accepted'
+			WHERE psf.post_id = p.id AND psf.user_id = @viewer
+		)
+		ELSE 0
+	END
+)`
+
+// postWithMetaColumns selects a post (aliased p), its author (aliased u) and the
+// engagement counters for @viewer, in the order expected by scanPostsWithMeta.
+// Attachments are loaded by scanPostsWithMeta once the rows are read.
+const postWithMetaColumns = `
+	p.id,
+	p.user_id,
+	p.group_id,
+	p.content,
+	COALESCE(p.privacy, 'public') AS privacy,
+	p.created_at,
+	p.edited_at,
+	u.id,
+	u.email,
+	u.username,
+	u.first_name,
+	u.last_name,
+	COALESCE(u.date_of_birth, '') AS date_of_birth,
+	COALESCE(u.nickname, '')      AS nickname,
+	COALESCE(u.avatar, '')        AS avatar,
+	COALESCE(u.about_me, '')      AS about_me,
+	u.is_public,
+	u.is_verified,
+	u.created_at,
+	(SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id) AS likes,
+	EXISTS(SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = @viewer) AS is_liked,
+	(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count`
+
+func scanPostsWithMeta(rows *sql.Rows, capacity int) ([]models.PostWithMeta, error) {
+	posts := make([]models.PostWithMeta, 0, capacity)
+	for rows.Next() {
+		var p models.PostWithMeta
+		var author models.User
+		if err := rows.Scan(
+			&p.ID, &p.UserID, &p.GroupID,
+			&p.Content, &p.Privacy, &p.CreatedAt, &p.EditedAt,
+			&author.ID, &author.Email, &author.Username,
+			&author.FirstName, &author.LastName, &author.DateOfBirth,
+			&author.Nickname, &author.Avatar, &author.AboutMe,
+			&author.IsPublic, &author.IsVerified, &author.CreatedAt,
+			&p.Likes, &p.IsLiked, &p.CommentsCount,
+		); err != nil {
+			return nil, err
+		}
+		p.Author = &author
+		p.Edited = p.EditedAt != nil
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i].Post
+	}
+	return posts, withAttachments(refs...)
+}
+
+// IsPostVisibleTo reports whether viewerID may see the post.
+// Returns sql.ErrNoRows when the post does not exist.
+func IsPostVisibleTo(postID int64, viewerID int) (bool, error) {
+	var visible bool
+	err := DB.QueryRow(`
+		SELECT `+postVisibleToViewer+`
+		FROM posts p
+		WHERE p.id = @post
+	`, sql.Named("post", postID), sql.Named("viewer", viewerID)).Scan(&visible)
+	return visible, err
+}
+
+// GetPostViewers returns those of userIDs who may see the post, in one query.
+func GetPostViewers(postID int64, userIDs []int) ([]int, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	candidates, err := json.Marshal(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The policy is evaluated for each candidate in place of @viewer
+	rows, err := DB.Query(`
+		SELECT v.value
+		FROM posts p, json_each(@candidates) v
+		WHERE p.id = @post AND `+strings.ReplaceAll(postVisibleToViewer, "@viewer", "v.value"),
+		sql.Named("post", postID), sql.Named("candidates", string(candidates)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetVisiblePersonalPosts returns a page of non-group posts visible to viewerID,
+// newest first, with author and engagement metadata.
+// When authorID is non-zero only that user's posts are returned; otherwise (the
+// feed) posts of users muted by viewerID are left out.
+func GetVisiblePersonalPosts(viewerID, authorID, limit, offset int) ([]models.PostWithMeta, error) {
+	rows, err := DB.Query(`
+		SELECT `+postWithMetaColumns+`
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.group_id IS NULL
+		  AND (@author = 0 OR p.user_id = @author)
+		  AND (@author != 0 OR NOT EXISTS(
+			SELECT 1 FROM user_mutes um WHERE um.muter_id = @viewer AND um.muted_id = p.user_id
+		  ))
+		  AND `+postVisibleToViewer+`
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT @limit OFFSET @offset
+	`,
+		sql.Named("viewer", viewerID),
+		sql.Named("author", authorID),
+		sql.Named("limit", limit),
+		sql.Named("offset", offset),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanPostsWithMeta(rows, limit)
+}
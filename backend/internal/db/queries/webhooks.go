@@ -0,0 +1,252 @@
+package queries
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const webhookColumns = `id, user_id, group_id, url, secret, events, active, created_at, updated_at`
+
+type webhookScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row webhookScanner) (models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	err := row.Scan(&hook.ID, &hook.UserID, &hook.GroupID, &hook.URL, &hook.Secret, &events, &hook.Active, &hook.CreatedAt, &hook.UpdatedAt)
+	hook.Events = strings.Split(events, ",")
+	return hook, err
+}
+
+// CreateWebhook registers a webhook of a user account (groupID nil) or of a group.
+func CreateWebhook(userID int, groupID *int64, url, secret string, events []string) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO webhooks (user_id, group_id, url, secret, events)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, groupID, url, secret, strings.Join(events, ","))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetWebhook returns a webhook, including its secret. It returns sql.ErrNoRows if it does not exist.
+func GetWebhook(id int64) (models.Webhook, error) {
+	return scanWebhook(DB.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+}
+
+// ListWebhooks returns the webhooks of a group, or of userID's account when groupID is nil.
+func ListWebhooks(userID int, groupID *int64) ([]models.Webhook, error) {
+	var rows *sql.Rows
+	var err error
+	if groupID != nil {
+		rows, err = DB.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE group_id = ? ORDER BY id`, *groupID)
+	} else {
+		rows, err = DB.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? AND group_id IS NULL ORDER BY id`, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// GetSubscribedWebhooks returns the active webhooks subscribed to event, of a
+// group or of userID's account when groupID is nil.
+func GetSubscribedWebhooks(userID int, groupID *int64, event string) ([]models.Webhook, error) {
+	hooks, err := ListWebhooks(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := hooks[:0]
+	for _, hook := range hooks {
+		if !hook.Active {
+			continue
+		}
+		for _, e := range hook.Events {
+			if e == event {
+				subscribed = append(subscribed, hook)
+				break
+			}
+		}
+	}
+	return subscribed, nil
+}
+
+// UpdateWebhook changes the URL, events and active flag of a webhook.
+func UpdateWebhook(id int64, url string, events []string, active bool) error {
+	_, err := DB.Exec(`
+		UPDATE webhooks
+		SET url = ?, events = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, url, strings.Join(events, ","), active, id)
+	return err
+}
+
+// SetWebhookSecret replaces the signing secret of a webhook.
+func SetWebhookSecret(id int64, secret string) error {
+	_, err := DB.Exec(`UPDATE webhooks SET secret = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, secret, id)
+	return err
+}
+
+// DeleteWebhook removes a webhook together with its delivery log.
+func DeleteWebhook(id int64) error {
+	_, err := DB.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	return err
+}
+
+// CreateWebhookDelivery queues an event for a webhook, to be attempted at runAt.
+func CreateWebhookDelivery(webhookID int64, event, payload string, runAt time.Time) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, webhookID, event, payload, runAt.Unix(), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at,
+	response_status, response_body, last_error, duration_ms, created_at, finished_at`
+
+func scanWebhookDelivery(row webhookScanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	var nextAttemptAt, finishedAt sql.NullInt64
+	var createdAt int64
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &nextAttemptAt,
+		&d.ResponseStatus, &d.ResponseBody, &d.LastError, &d.DurationMs, &createdAt, &finishedAt)
+	if err != nil {
+		return d, err
+	}
+	d.Payload = []byte(payload)
+	d.CreatedAt = time.Unix(createdAt, 0).UTC()
+	if nextAttemptAt.Valid {
+		t := time.Unix(nextAttemptAt.Int64, 0).UTC()
+		d.NextAttemptAt = &t
+	}
+	if finishedAt.Valid {
+		t := time.Unix(finishedAt.Int64, 0).UTC()
+		d.FinishedAt = &t
+	}
+	return d, nil
+}
+
+// GetWebhookDelivery returns a delivery. It returns sql.ErrNoRows if it does not exist.
+func GetWebhookDelivery(id int64) (models.WebhookDelivery, error) {
+	return scanWebhookDelivery(DB.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+}
+
+// GetWebhookDeliveries returns up to limit deliveries of a webhook, newest first,
+// starting below the delivery ID before (0 for the first page).
+func GetWebhookDeliveries(webhookID, before int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := DB.Query(`
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = ? AND (? = 0 OR id < ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`, webhookID, before, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// WebhookAttempt is the outcome of one delivery attempt.
+type WebhookAttempt struct {
+	ResponseStatus int // 0 when no response was received
+	ResponseBody   string
+	Error          string
+	Duration       time.Duration
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt. The delivery stays
+// pending when nextAttempt is set; otherwise it ends with status.
+func RecordWebhookAttempt(id int64, attempt WebhookAttempt, status string, nextAttempt *time.Time) error {
+	var responseStatus, responseBody, lastError, next, finished any
+	if attempt.ResponseStatus != 0 {
+		responseStatus, responseBody = attempt.ResponseStatus, attempt.ResponseBody
+	}
+	if attempt.Error != "" {
+		lastError = attempt.Error
+	}
+	if nextAttempt != nil {
+		next = nextAttempt.Unix()
+	} else {
+		finished = time.Now().Unix()
+	}
+
+	_, err := DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, response_status = ?,
+			response_body = ?, last_error = ?, duration_ms = ?, finished_at = ?
+		WHERE id = ?
+	`, status, next, responseStatus, responseBody, lastError, attempt.Duration.Milliseconds(), finished, id)
+	return err
+}
+
+// FailWebhookDelivery ends a pending delivery without attempting it.
+func FailWebhookDelivery(id int64, reason string) error {
+	_, err := DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'failed', next_attempt_at = NULL, last_error = ?, finished_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, reason, time.Now().Unix(), id)
+	return err
+}
+
+// GetPendingWebhookDeliveries returns every delivery still waiting for an attempt.
+func GetPendingWebhookDeliveries() ([]models.WebhookDelivery, error) {
+	rows, err := DB.Query(`SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = 'pending' ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// DeleteFinishedWebhookDeliveries prunes the delivery log of entries finished before cutoff.
+func DeleteFinishedWebhookDeliveries(cutoff time.Time) (int64, error) {
+	res, err := DB.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE status != 'pending' AND finished_at < ?
+	`, cutoff.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,153 @@
+// Package deletion deletes accounts on request, after a grace period during
+// which logging in cancels the deletion.
+package deletion
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/media"
+	activity "backend/internal/notifications"
+	"backend/internal/scheduler"
+	"backend/internal/ws"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	jobKind      = "account_deletion"
+	defaultGrace = 14 * 24 * time.Hour
+)
+
+// Register installs the account deletion job. It must be called before scheduler.Run.
+func Register() {
+	scheduler.Register(jobKind, run)
+}
+
+// grace is how long a deleted account can still be recovered by
+// logging in (ACCOUNT_DELETION_GRACE). "0s" deletes accounts right away.
+func grace() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ACCOUNT_DELETION_GRACE")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultGrace
+}
+
+// jobKey ends with a colon so that cancelling user 1 leaves user 10 alone.
+func jobKey(userID int) string {
+	return fmt.Sprintf("%s:%d:", jobKind, userID)
+}
+
+type jobPayload struct {
+	UserID int `json:"user_id"`
+}
+
+// Schedule schedules the deletion of an account after the grace period,
+// and ends its sessions and socket. It returns when the deletion will run.
+func Schedule(userID int) (time.Time, error) {
+	at := time.Now().Add(grace()).Truncate(time.Second)
+	if err := queries.ScheduleAccountDeletion(userID, at); err != nil {
+		return at, err
+	}
+	if err := scheduler.Schedule(jobKind, jobKey(userID), at, jobPayload{UserID: userID}); err != nil {
+		return at, err
+	}
+	ws.DisconnectUser(userID, "account_deleted", "Your account is scheduled for deletion")
+	return at, nil
+}
+
+// Cancel cancels a scheduled deletion and reports whether one was scheduled.
+func Cancel(userID int) (bool, error) {
+	cancelled, err := queries.CancelAccountDeletion(userID)
+	if err != nil || !cancelled {
+		return false, err
+	}
+	return true, scheduler.Cancel(jobKey(userID))
+}
+
+func run(payload []byte) error {
+	var p jobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	// The job outlives a cancellation if the scheduler missed it
+	at, err := queries.GetAccountDeletionTime(p.UserID)
+	if err != nil || at == nil || at.After(time.Now()) {
+		return err
+	}
+	return deleteAccount(p.UserID)
+}
+
+// deleteAccount deletes an account with everything it owns: groups without
+// another member are deleted, the others are handed to their longest-standing
+// member, and the media of the user and of deleted groups is released.
+func deleteAccount(userID int) error {
+	groups, err := queries.GetOwnedGroupsForDeletion(userID)
+	if err != nil {
+		return err
+	}
+
+	// Collected before the rows are gone. Covers of handed over groups stay
+	paths, err := queries.GetUserMediaPaths(userID)
+	if err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, g := range groups {
+		if g.SuccessorID != 0 {
+			kept[g.CoverImagePath] = true
+			continue
+		}
+		groupPaths, err := queries.GetGroupMediaPaths(g.ID)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, groupPaths...)
+	}
+	exports, err := queries.ListDataExports(userID)
+	if err != nil {
+		return err
+	}
+
+	err = queries.DeleteAccount(userID, groups)
+	if err == sql.ErrNoRows {
+		return nil // cancelled by a login in the meantime
+	}
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	for _, stored := range paths {
+		if kept[stored] || removed[stored] {
+			continue
+		}
+		removed[stored] = true
+		media.Release(stored)
+	}
+	for _, e := range exports {
+		if e.FilePath != "" {
+			if err := os.Remove(e.FilePath); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("deletion: failed to remove export %d of deleted user %d: %v\n", e.ID, userID, err)
+			}
+		}
+	}
+
+	ws.DisconnectUser(userID, "account_deleted", "Your account has been deleted")
+
+	for _, g := range groups {
+		if g.SuccessorID == 0 {
+			continue
+		}
+		err := activity.NotifyRecentActivity(g.SuccessorID, nil, "group_ownership",
+			activity.GroupOwnershipTransferred(g.Name),
+			map[string]interface{}{"group_id": g.ID})
+		if err != nil {
+			fmt.Printf("deletion: failed to notify new owner of group %d: %v\n", g.ID, err)
+		}
+	}
+
+	fmt.Printf("deletion: deleted user %d (%d file(s), %d group(s) handed over or deleted)\n", userID, len(removed), len(groups))
+	return nil
+}
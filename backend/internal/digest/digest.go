@@ -0,0 +1,336 @@
+// Package digest emails users a summary of their unread notifications and private
+// messages, hourly, daily or weekly as they choose. An hourly scheduler job sends
+// every digest that is due.
+package digest
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/scheduler"
+	"backend/internal/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	jobKind = "email_digest"
+	// maxItems bounds the notifications listed in one digest
+	maxItems = 20
+	// slack lets a digest go out on the hourly run even when the previous one was sent late in its run
+	slack      = 5 * time.Minute
+	secretName = "digest_unsubscribe_key"
+)
+
+// Periods maps each digest frequency to the time between two digests.
+var Periods = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// Register installs the hourly digest job. Call it before scheduler.Run.
+func Register() {
+	scheduler.RegisterPeriodic(jobKind, time.Hour, run)
+}
+
+// IsFrequency reports whether f is a valid digest frequency.
+func IsFrequency(f string) bool {
+	_, ok := Periods[f]
+	return ok || f == "off"
+}
+
+// apiURL is the public address of the backend, used in unsubscribe links (APP_BASE_URL).
+func apiURL() string {
+	if u := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"); u != "" {
+		return u
+	}
+	return "http://localhost:8080"
+}
+
+// frontendURL is the address of the web app, used to link to the inbox (FRONTEND_URL).
+func frontendURL() string {
+	if u := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"); u != "" {
+		return u
+	}
+	return "http://localhost:3000"
+}
+
+func signature(userID int) (string, error) {
+	key, err := queries.GetOrCreateAppSecret(secretName, utils.GenerateToken)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "digest-unsubscribe:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// UnsubscribeURL returns the signed link that turns off the digests of a user
+// without logging in.
+func UnsubscribeURL(userID int) (string, error) {
+	sig, err := signature(userID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/api/notifications/digest/unsubscribe?user=%d&sig=%s", apiURL(), userID, url.QueryEscape(sig)), nil
+}
+
+// VerifyUnsubscribe reports whether sig is the unsubscribe signature of userID.
+func VerifyUnsubscribe(userID int, sig string) bool {
+	expected, err := signature(userID)
+	if err != nil {
+		fmt.Printf("digest: failed to load signing key: %v\n", err)
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+func run([]byte) error {
+	now := time.Now()
+	cutoffs := make(map[string]time.Time, len(Periods))
+	for frequency, period := range Periods {
+		cutoffs[frequency] = now.Add(-period + slack)
+	}
+
+	recipients, err := queries.GetDueDigestRecipients(cutoffs)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	notificationMark, messageMark, err := queries.GetDigestWatermarks()
+	if err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		if err := send(r, now, notificationMark, messageMark); err != nil {
+			fmt.Printf("digest: failed to send digest to user %d: %v\n", r.UserID, err)
+		}
+	}
+	return nil
+}
+
+// send emails one digest covering activity up to the watermarks.
+func send(r queries.DigestRecipient, now time.Time, notificationMark, messageMark int64) error {
+	// The window moves first, so a failed email is skipped rather than resent every hour
+	if err := queries.RecordDigestSent(r.UserID, now, notificationMark, messageMark); err != nil {
+		return err
+	}
+
+	user, err := queries.GetUserByID(r.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.IsVerified {
+		return nil
+	}
+
+	prefs, err := queries.GetNotificationPreferences(r.UserID)
+	if err != nil {
+		return err
+	}
+	emailEnabled := make(map[string]bool, len(prefs))
+	for _, p := range prefs {
+		emailEnabled[p.Type] = p.Email
+	}
+
+	candidates, err := queries.GetDigestNotifications(r.UserID, r.LastNotificationID, notificationMark, 5*maxItems)
+	if err != nil {
+		return err
+	}
+	var notifications []models.Notification
+	for _, n := range candidates {
+		// Types that cannot be configured follow the default, which is enabled
+		if enabled, known := emailEnabled[n.Type]; !known || enabled {
+			notifications = append(notifications, n)
+		}
+	}
+
+	var conversations []queries.DigestConversation
+	if emailEnabled["new_message"] {
+		all, err := queries.GetDigestConversations(r.UserID, r.LastMessageID, messageMark)
+		if err != nil {
+			return err
+		}
+		for _, c := range all {
+			muted, err := queries.IsNotificationTargetMuted(r.UserID, "conversation", int64(c.ConversationID))
+			if err != nil {
+				return err
+			}
+			if !muted {
+				conversations = append(conversations, c)
+			}
+		}
+	}
+
+	if len(notifications) == 0 && len(conversations) == 0 {
+		return nil
+	}
+
+	unsubscribe, err := UnsubscribeURL(r.UserID)
+	if err != nil {
+		return err
+	}
+
+	d := digest{
+		Name:          user.FirstName,
+		Frequency:     r.Frequency,
+		Notifications: notifications,
+		Conversations: conversations,
+		InboxURL:      frontendURL() + "/notifications",
+		Unsubscribe:   unsubscribe,
+	}
+	return utils.SendAlternativeEmail(user.Email, d.subject(), d.text(), d.html(), map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribe + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	})
+}
+
+type digest struct {
+	Name          string
+	Frequency     string
+	Notifications []models.Notification
+	Conversations []queries.DigestConversation
+	InboxURL      string
+	Unsubscribe   string
+}
+
+func (d digest) subject() string {
+	var parts []string
+	if n := len(d.Notifications); n > 0 {
+		parts = append(parts, plural(n, "notification", "notifications"))
+	}
+	messages := 0
+	for _, c := range d.Conversations {
+		messages += c.Count
+	}
+	if messages > 0 {
+		parts = append(parts, plural(messages, "unread message", "unread messages"))
+	}
+	return "You have " + strings.Join(parts, " and ")
+}
+
+// lines returns the digest entries as plain text, capped at maxItems notifications.
+func (d digest) lines() (conversations, notifications []string, more int) {
+	for _, c := range d.Conversations {
+		conversations = append(conversations, fmt.Sprintf("@%s sent you %s: \"%s\"",
+			c.SenderUsername, plural(c.Count, "message", "messages"), excerpt(c.LastMessage)))
+	}
+	for i, n := range d.Notifications {
+		if i == maxItems {
+			more = len(d.Notifications) - maxItems
+			break
+		}
+		var data map[string]interface{}
+		_ = json.Unmarshal([]byte(n.Data), &data)
+		if message, _ := data["message"].(string); message != "" {
+			notifications = append(notifications, message)
+		}
+	}
+	return conversations, notifications, more
+}
+
+func (d digest) text() string {
+	conversations, notifications, more := d.lines()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\nHere is what you missed:\n", d.Name)
+	if len(conversations) > 0 {
+		b.WriteString("\nMessages\n")
+		for _, line := range conversations {
+			b.WriteString("- " + line + "\n")
+		}
+	}
+	if len(notifications) > 0 {
+		b.WriteString("\nNotifications\n")
+		for _, line := range notifications {
+			b.WriteString("- " + line + "\n")
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, "- and %d more\n", more)
+		}
+	}
+	fmt.Fprintf(&b, "\nOpen your notifications: %s\n", d.InboxURL)
+	fmt.Fprintf(&b, "\nYou receive this %s digest because you turned it on. Unsubscribe: %s\n", d.Frequency, d.Unsubscribe)
+	return b.String()
+}
+
+func (d digest) html() string {
+	conversations, notifications, more := d.lines()
+
+	list := func(title string, lines []string, more int) string {
+		if len(lines) == 0 {
+			return ""
+		}
+		var b strings.Builder
+		b.WriteString(`<h3 style="color:#f4f4f5;font-size:15px;margin:24px 0 8px;">` + title + `</h3><ul style="color:#a1a1aa;font-size:14px;padding-left:20px;margin:0;">`)
+		for _, line := range lines {
+			b.WriteString(`<li style="margin:0 0 6px;">` + html.EscapeString(line) + `</li>`)
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, `<li style="margin:0 0 6px;">and %d more</li>`, more)
+		}
+		b.WriteString(`</ul>`)
+		return b.String()
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="margin:0;padding:0;background:#09090b;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%%" cellpadding="0" cellspacing="0" style="background:#09090b;padding:40px 0;">
+    <tr><td align="center">
+      <table width="520" cellpadding="0" cellspacing="0" style="background:#111113;border:1px solid #27272a;border-radius:16px;overflow:hidden;">
+        <tr>
+          <td style="padding:32px 40px;">
+            <p style="color:#a1a1aa;font-size:15px;margin:0;">Hi <strong style="color:#f4f4f5;">%s</strong>, here is what you missed.</p>
+            %s
+            %s
+            <p style="margin:28px 0 0;"><a href="%s" style="color:#00d1b2;font-weight:700;text-decoration:none;">Open your notifications</a></p>
+          </td>
+        </tr>
+        <tr>
+          <td style="border-top:1px solid #27272a;padding:20px 40px;text-align:center;">
+            <p style="color:#52525b;font-size:12px;margin:0;">You receive this %s digest because you turned it on. <a href="%s" style="color:#71717a;">Unsubscribe</a></p>
+          </td>
+        </tr>
+      </table>
+    </td></tr>
+  </table>
+</body>
+</html>`,
+		html.EscapeString(d.Name),
+		list("Messages", conversations, 0),
+		list("Notifications", notifications, more),
+		html.EscapeString(d.InboxURL),
+		d.Frequency,
+		html.EscapeString(d.Unsubscribe),
+	)
+}
+
+// excerpt shortens a message for the digest.
+func excerpt(s string) string {
+	const max = 80
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -5,6 +5,7 @@ import (
 	"backend/internal/models"
 	activity "backend/internal/notifications"
 	"backend/internal/utils"
+	"backend/internal/webhooks"
 	"backend/internal/ws"
 	"fmt"
 	"net/http"
@@ -38,6 +39,14 @@ func FollowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if blocked, err := queries.IsBlockedBetween(currentUserID, target.ID); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to follow user"})
+		return
+	} else if blocked {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "You can't follow this user"})
+		return
+	}
+
 	status, err := queries.FollowUser(currentUserID, target.ID, target.IsPublic)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to follow user"})
@@ -45,9 +54,14 @@ func FollowHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	message := activity.FollowAcceptedForSender(target.Username).Message
+	event := webhooks.EventFollowerCreated
 	if status == "pending" {
 		message = activity.FollowRequestSent(target.Username).Message
+		event = webhooks.EventFollowRequestCreated
 	}
+	webhooks.EmitAccountEvent(target.ID, event, currentUserID, map[string]interface{}{
+		"follower_id": currentUserID,
+	})
 
 	// Notify the target user in real-time
 	follower, err := queries.GetUserByID(currentUserID)
@@ -262,6 +276,9 @@ func HandleFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 		})
 
 		if action == "accept" {
+			webhooks.EmitAccountEvent(userID, webhooks.EventFollowerCreated, requesterID, map[string]interface{}{
+				"follower_id": requesterID,
+			})
 			_, _ = activity.StoreRecentActivity(requesterID, &userID, "follow_update", activity.FollowAcceptedForSender(target.Username), map[string]interface{}{
 				"target_username": target.Username,
 				"status":          "accepted",
@@ -2,11 +2,13 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
+	"backend/internal/reminders"
 	"backend/internal/utils"
 	"backend/internal/ws"
+	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 )
 
@@ -106,18 +108,12 @@ func DeleteAnEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// delete image if exists
-	if imagePath != "" {
-		err = os.Remove(imagePath)
-		if err != nil {
-			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
-				Success: false,
-				Message: "Failed to delete event image",
-			})
-			return
-		}
+	if err := reminders.CancelEvent(eventID); err != nil {
+		fmt.Println("Error cancelling event reminders:", err)
 	}
 
+	media.Release(imagePath)
+
 	// Broadcast the deletion
 	ws.BroadcastToGroup(groupID, "event_deleted", map[string]interface{}{
 		"eventId": eventID,
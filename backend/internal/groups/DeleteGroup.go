@@ -2,13 +2,11 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
-	"fmt"
 	"net/http"
-	"os"
 	"strconv"
-	"strings"
 )
 
 func DeleteGroup(w http.ResponseWriter, r *http.Request) {
@@ -68,8 +66,15 @@ func DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get cover image path before deleting
-	coverImagePath := group.CoverImagePath
+	// Collect the cover and post and event images before the rows are gone
+	mediaPaths, err := queries.GetGroupMediaPaths(groupID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to delete group",
+		})
+		return
+	}
 
 	// Delete the group (CASCADE will handle members, posts, events, etc.)
 	err = queries.DeleteGroup(groupID)
@@ -81,13 +86,8 @@ func DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete cover image from disk if it exists
-	if coverImagePath != "" {
-		filePath := strings.TrimPrefix(coverImagePath, "/")
-		if err := os.Remove(filePath); err != nil {
-			// Log error but don't fail the request since group is already deleted
-			fmt.Printf("Failed to delete group cover image %s: %v\n", filePath, err)
-		}
+	for _, path := range mediaPaths {
+		media.Release(path)
 	}
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
@@ -0,0 +1,283 @@
+package groups
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/media"
+	"backend/internal/models"
+	activity "backend/internal/notifications"
+	"backend/internal/reminders"
+	"backend/internal/utils"
+	"backend/internal/ws"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// eventChange describes one edited field of an event, as sent to respondents.
+type eventChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// EditAnEvent updates an event in place so its responses are kept. Only the event
+// creator or the group owner may edit it. Form fields that are omitted keep their
+// current value; every respondent is notified with the list of changes.
+func EditAnEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+
+	event, ok := loadEventForMember(w, r, userID)
+	if !ok {
+		return
+	}
+	if !authorizeEventManager(w, event, userID) {
+		return
+	}
+
+	title := formValueOr(r, "title", event.Title)
+	description := formValueOr(r, "description", event.Description)
+	if resp := ValidateEventText(title, description); resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
+		return
+	}
+
+	currentDate := event.EventTime.Format(occurrenceDateLayout)
+	eventDate := formValueOr(r, "event_date", currentDate)
+	eventTime := formValueOr(r, "event_time", event.EventTime.Format("15:04"))
+	startsAt := event.EventTime
+	startChanged := eventDate+" "+eventTime != event.EventTime.Format(queries.EventTimeLayout)
+	if startChanged {
+		// Occurrence RSVPs are keyed by date, so a series cannot be moved to other days
+		if event.RecurrenceRule != "" && eventDate != currentDate {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+				Success: false,
+				Message: "The start date of a recurring event cannot be changed; cancel single occurrences instead",
+			})
+			return
+		}
+		var resp *models.GenericResponse
+		if startsAt, resp = ValidateEventDateTime(eventDate, eventTime); resp != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, *resp)
+			return
+		}
+	}
+
+	// The end keeps its distance from the start unless it is edited too
+	var endDate, endTime string
+	if event.EndTime != nil {
+		end := startsAt.Add(event.EndTime.Sub(event.EventTime))
+		endDate, endTime = end.Format(occurrenceDateLayout), end.Format("15:04")
+	}
+	if v, set := formField(r, "end_time"); set && v == "" {
+		endDate = ""
+	}
+	endDate, endTime, resp := ValidateEventEnd(startsAt, formValueOr(r, "end_date", endDate), formValueOr(r, "end_time", endTime))
+	if resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
+		return
+	}
+
+	location, capacity, resp := ValidateEventDetails(
+		formValueOr(r, "location", event.Location),
+		formValueOr(r, "capacity", capacityText(event.Capacity, "")),
+	)
+	if resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
+		return
+	}
+
+	imagePath := event.CoverImage
+	file, handler, err := r.FormFile("coverImage")
+	if err == nil {
+		defer file.Close()
+
+		urls, err := media.SaveUploadedFile(file, handler, "groups", userID)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+			return
+		}
+		imagePath = urls.Original
+	} else if r.FormValue("remove_cover") == "true" {
+		imagePath = ""
+	}
+
+	newEnd := ""
+	if endTime != "" {
+		newEnd = endDate + " " + endTime
+	}
+	oldEnd := ""
+	if event.EndTime != nil {
+		oldEnd = event.EndTime.Format(queries.EventTimeLayout)
+	}
+
+	var changes []eventChange
+	addChange := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, eventChange{Field: field, Old: old, New: new})
+		}
+	}
+	addChange("title", event.Title, title)
+	addChange("description", event.Description, description)
+	addChange("start_time", event.EventTime.Format(queries.EventTimeLayout), startsAt.Format(queries.EventTimeLayout))
+	addChange("end_time", oldEnd, newEnd)
+	addChange("location", event.Location, location)
+	addChange("capacity", capacityText(event.Capacity, "unlimited"), capacityText(capacity, "unlimited"))
+	addChange("cover_image", event.CoverImage, imagePath)
+
+	if len(changes) == 0 {
+		utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Nothing to update",
+			"changes": changes,
+		})
+		return
+	}
+
+	err = queries.UpdateGroupEvent(event.ID, models.UpdateGroupEventParams{
+		Title:       title,
+		Description: description,
+		EventDate:   startsAt.Format(occurrenceDateLayout),
+		EventTime:   startsAt.Format("15:04"),
+		EndDate:     endDate,
+		EndTime:     endTime,
+		Location:    location,
+		Capacity:    capacity,
+		ImagePath:   imagePath,
+	})
+	if err != nil {
+		fmt.Println("Error updating event:", err)
+		if imagePath != event.CoverImage {
+			media.Release(imagePath)
+		}
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update event"})
+		return
+	}
+
+	// Replace the old cover image
+	if imagePath != event.CoverImage {
+		media.Release(event.CoverImage)
+	}
+
+	updated, err := queries.GetGroupEventByID(event.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch updated event"})
+		return
+	}
+
+	if startChanged {
+		if err := reminders.CancelEvent(event.ID); err != nil {
+			fmt.Println("Error cancelling event reminders:", err)
+		}
+		if err := reminders.ScheduleEvent(updated); err != nil {
+			fmt.Println("Error scheduling event reminders:", err)
+		}
+	}
+
+	// A larger or removed capacity frees seats for waitlisted users
+	if event.Capacity > 0 && (capacity == 0 || capacity > event.Capacity) {
+		promoted, err := queries.PromoteEventWaitlists(event.ID, capacity)
+		if err != nil {
+			fmt.Println("Error promoting waitlisted users:", err)
+		}
+		for occurrenceDate, userIDs := range promoted {
+			notifyWaitlistPromotions(updated, occurrenceDate, userIDs)
+		}
+	}
+
+	notifyEventRespondents(updated, userID, changes)
+
+	ws.BroadcastToGroup(updated.GroupID, "event_updated", map[string]interface{}{
+		"groupId": updated.GroupID,
+		"eventId": updated.ID,
+		"event":   updated,
+		"changes": changes,
+	})
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Event updated successfully",
+		"event":   updated,
+		"changes": changes,
+	})
+}
+
+// notifyEventRespondents sends the change list to everyone who answered the event,
+// except the editor.
+func notifyEventRespondents(event models.GroupEvent, editorID int, changes []eventChange) {
+	respondents, err := queries.GetAllEventRespondentIDs(event.ID)
+	if err != nil {
+		fmt.Println("Error fetching event respondents:", err)
+		return
+	}
+
+	summary := make([]string, 0, len(changes))
+	for _, c := range changes {
+		summary = append(summary, describeEventChange(c))
+	}
+	text := activity.EventUpdated(event.ID, event.Title, summary)
+
+	for _, respondentID := range respondents {
+		if respondentID == editorID {
+			continue
+		}
+		_ = activity.NotifyRecentActivity(respondentID, &editorID, "event_updated", text, map[string]interface{}{
+			"event_id":    event.ID,
+			"event_title": event.Title,
+			"group_id":    event.GroupID,
+			"changes":     changes,
+		})
+	}
+}
+
+func describeEventChange(c eventChange) string {
+	name := strings.ReplaceAll(c.Field, "_", " ")
+	switch {
+	case c.Field == "description":
+		return "description updated"
+	case c.Field == "cover_image" && c.New != "":
+		return "cover image updated"
+	case c.New == "":
+		return name + " removed"
+	default:
+		return fmt.Sprintf("%s changed to '%s'", name, c.New)
+	}
+}
+
+// formField returns the submitted value of a form field and whether it was sent.
+func formField(r *http.Request, key string) (string, bool) {
+	if r.MultipartForm == nil {
+		return "", false
+	}
+	values, ok := r.MultipartForm.Value[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// formValueOr returns the submitted value of a form field, or current when it was not sent.
+func formValueOr(r *http.Request, key, current string) string {
+	if v, ok := formField(r, key); ok {
+		return v
+	}
+	return current
+}
+
+func capacityText(capacity int, unlimited string) string {
+	if capacity == 0 {
+		return unlimited
+	}
+	return strconv.Itoa(capacity)
+}
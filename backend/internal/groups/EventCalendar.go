@@ -0,0 +1,213 @@
+package groups
+
+import (
+	"backend/internal/calendar"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toCalendarEvent converts a stored event into a VEVENT describing the whole series.
+func toCalendarEvent(event models.GroupEvent) (calendar.Event, error) {
+	entry := calendar.Event{
+		UID:         fmt.Sprintf("event-%d@social-network", event.ID),
+		Summary:     event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		Start:       event.EventTime,
+		Created:     event.CreatedAt,
+		Rule:        event.RecurrenceRule,
+	}
+	if event.EndTime != nil {
+		entry.End = *event.EndTime
+	}
+	if event.RecurrenceRule == "" {
+		return entry, nil
+	}
+
+	exceptions, err := queries.GetEventExceptions(event.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, ex := range exceptions {
+		day, err := time.Parse(occurrenceDateLayout, ex)
+		if err != nil {
+			continue
+		}
+		entry.ExDates = append(entry.ExDates, occurrenceStart(event.EventTime, day))
+	}
+	return entry, nil
+}
+
+func writeICS(w http.ResponseWriter, filename, name string, events []calendar.Event) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if err := calendar.WriteCalendar(w, name, events); err != nil {
+		fmt.Println("Error writing calendar:", err)
+	}
+}
+
+// ExportEventICS serves a single event (with its recurrence) as an .ics file.
+func ExportEventICS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	event, ok := loadEventForMember(w, r, userID)
+	if !ok {
+		return
+	}
+
+	entry, err := toCalendarEvent(event)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to export event"})
+		return
+	}
+
+	writeICS(w, fmt.Sprintf("event-%d.ics", event.ID), event.Title, []calendar.Event{entry})
+}
+
+// ExportGroupEventsICS serves every event of a group as an .ics file.
+func ExportGroupEventsICS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	groupID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid group ID"})
+		return
+	}
+
+	group, err := queries.GetGroupByID(groupID)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Group not found"})
+		return
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch group"})
+		return
+	}
+
+	isMember, err := queries.IsUserGroupMember(int(groupID), userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Error checking membership"})
+		return
+	}
+	if !isMember {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "You must be a member to view group events"})
+		return
+	}
+
+	events, err := queries.GetGroupEventsList(groupID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch events"})
+		return
+	}
+
+	entries := make([]calendar.Event, 0, len(events))
+	for _, event := range events {
+		entry, err := toCalendarEvent(event)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to export events"})
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	writeICS(w, fmt.Sprintf("group-%d-events.ics", groupID), group.Name+" events", entries)
+}
+
+// GetCalendarToken returns the private URL of the user's "going" events feed.
+func GetCalendarToken(w http.ResponseWriter, r *http.Request) {
+	respondCalendarToken(w, r, queries.GetOrCreateCalendarToken)
+}
+
+// RotateCalendarToken revokes the current feed URL and issues a new one.
+func RotateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	respondCalendarToken(w, r, queries.RotateCalendarToken)
+}
+
+func respondCalendarToken(w http.ResponseWriter, r *http.Request, issue func(userID int) (string, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	token, err := issue(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to issue calendar token"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"token":    token,
+		"feed_url": "/api/calendar/feed/" + token + ".ics",
+	})
+}
+
+// CalendarFeed serves the events a user answered "going" to. It is authenticated
+// by the private token in the URL so calendar apps can subscribe to it.
+func CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+	userID, err := queries.GetUserIDByCalendarToken(token)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	going, err := queries.GetGoingEventsForUser(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]calendar.Event, 0, len(going))
+	for _, ge := range going {
+		entry := calendar.Event{
+			UID:         fmt.Sprintf("event-%d@social-network", ge.ID),
+			Summary:     ge.Title,
+			Description: ge.GroupName + ": " + ge.Description,
+			Location:    ge.Location,
+			Start:       ge.EventTime,
+			Created:     ge.CreatedAt,
+		}
+		if ge.EndTime != nil {
+			entry.End = *ge.EndTime
+		}
+		if ge.OccurrenceDate != "" {
+			// Recurring events only contribute the occurrences the user is going to
+			day, err := time.Parse(occurrenceDateLayout, ge.OccurrenceDate)
+			if err != nil {
+				continue
+			}
+			entry.UID = fmt.Sprintf("event-%d-%s@social-network", ge.ID, ge.OccurrenceDate)
+			entry.Start = occurrenceStart(ge.EventTime, day)
+			if ge.EndTime != nil {
+				entry.End = entry.Start.Add(ge.EndTime.Sub(ge.EventTime))
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	writeICS(w, "going.ics", "My events", entries)
+}
@@ -0,0 +1,358 @@
+package groups
+
+import (
+	"backend/internal/calendar"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/internal/ws"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	occurrenceDateLayout = "2006-01-02"
+	// Default and maximum window for occurrence listings
+	defaultOccurrenceWindow = 90 * 24 * time.Hour
+	maxOccurrenceWindow     = 366 * 24 * time.Hour
+)
+
+// ParseRecurrence turns the "recurrence" form value ("", "none", "weekly",
+// "monthly" or "custom" with an "rrule" value) into a canonical RRULE value.
+// An empty string means the event does not repeat. The event start must itself
+// be an occurrence of the rule.
+func ParseRecurrence(kind, custom string, start time.Time) (string, *models.GenericResponse) {
+	var raw string
+	switch kind {
+	case "", "none":
+		if custom == "" {
+			return "", nil
+		}
+		raw = custom
+	case "weekly":
+		raw = "FREQ=WEEKLY"
+	case "monthly":
+		raw = "FREQ=MONTHLY"
+	case "custom":
+		raw = custom
+	default:
+		return "", &models.GenericResponse{
+			Success: false,
+			Message: "Recurrence must be 'none', 'weekly', 'monthly' or 'custom'",
+		}
+	}
+
+	rule, err := calendar.ParseRule(raw)
+	if err != nil {
+		return "", &models.GenericResponse{
+			Success: false,
+			Message: "Invalid recurrence rule: " + err.Error(),
+		}
+	}
+	if len(rule.Between(start, start, start, 1)) == 0 {
+		return "", &models.GenericResponse{
+			Success: false,
+			Message: "Event date must be the first occurrence of the recurrence rule",
+		}
+	}
+	return rule.String(), nil
+}
+
+// isEventOccurrence reports whether date (YYYY-MM-DD) is a scheduled, non-cancelled
+// occurrence of a recurring event.
+func isEventOccurrence(event models.GroupEvent, date string) (bool, error) {
+	day, err := time.Parse(occurrenceDateLayout, date)
+	if err != nil {
+		return false, nil
+	}
+	rule, err := calendar.ParseRule(event.RecurrenceRule)
+	if err != nil {
+		return false, err
+	}
+
+	at := occurrenceStart(event.EventTime, day)
+	if len(rule.Between(event.EventTime, at, at, 1)) == 0 {
+		return false, nil
+	}
+
+	exceptions, err := queries.GetEventExceptions(event.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, ex := range exceptions {
+		if ex == date {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// occurrenceStart returns the start of the occurrence on day of a series starting at start.
+func occurrenceStart(start, day time.Time) time.Time {
+	hour, min, sec := start.Clock()
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, 0, start.Location())
+}
+
+// loadEventForMember parses the {id} path value, loads the event and checks that
+// userID belongs to its group. On failure the error response is written and ok is false.
+func loadEventForMember(w http.ResponseWriter, r *http.Request, userID int) (event models.GroupEvent, ok bool) {
+	eventID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid event ID"})
+		return event, false
+	}
+
+	event, err = queries.GetGroupEventByID(eventID)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Event not found"})
+		return event, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch event"})
+		return event, false
+	}
+
+	return event, checkEventMember(w, event, userID)
+}
+
+// checkEventMember checks that userID belongs to the group of the event. On
+// failure the error response is written and ok is false.
+func checkEventMember(w http.ResponseWriter, event models.GroupEvent, userID int) bool {
+	isMember, err := queries.IsUserGroupMember(int(event.GroupID), userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Error checking membership"})
+		return false
+	}
+	if !isMember {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "You must be a member to view this event"})
+		return false
+	}
+	return true
+}
+
+// GetEventOccurrences lists the occurrences of a recurring event between the
+// optional "from" and "to" query dates (YYYY-MM-DD), with per-occurrence RSVPs.
+func GetEventOccurrences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	event, ok := loadEventForMember(w, r, userID)
+	if !ok {
+		return
+	}
+	if event.RecurrenceRule == "" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Event does not repeat"})
+		return
+	}
+
+	from := time.Now().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(occurrenceDateLayout, v)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid from date"})
+			return
+		}
+		from = t
+	}
+	to := from.Add(defaultOccurrenceWindow)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(occurrenceDateLayout, v)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid to date"})
+			return
+		}
+		// "to" is inclusive
+		to = t.Add(24*time.Hour - time.Second)
+	}
+	if to.Before(from) || to.Sub(from) > maxOccurrenceWindow {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Date range must be positive and at most one year"})
+		return
+	}
+
+	rule, err := calendar.ParseRule(event.RecurrenceRule)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Invalid stored recurrence rule"})
+		return
+	}
+	exceptions, err := queries.GetEventExceptions(event.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch exceptions"})
+		return
+	}
+	cancelled := make(map[string]bool, len(exceptions))
+	for _, ex := range exceptions {
+		cancelled[ex] = true
+	}
+	responses, err := queries.GetOccurrenceResponses(event.ID, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch responses"})
+		return
+	}
+
+	type occurrence struct {
+		Date          string `json:"occurrence_date"`
+		StartTime     string `json:"start_time"`
+		EndTime       string `json:"end_time,omitempty"`
+		Cancelled     bool   `json:"cancelled"`
+		GoingCount    int    `json:"going_count"`
+		NotGoingCount int    `json:"not_going_count"`
+		MaybeCount    int    `json:"maybe_count"`
+		WaitlistCount int    `json:"waitlist_count"`
+		UserResponse  string `json:"user_response,omitempty"`
+	}
+
+	times := rule.Between(event.EventTime, from, to, calendar.MaxCount)
+	occurrences := make([]occurrence, 0, len(times))
+	for _, t := range times {
+		date := t.Format(occurrenceDateLayout)
+		summary := responses[date]
+		o := occurrence{
+			Date:          date,
+			StartTime:     t.Format(queries.EventTimeLayout),
+			Cancelled:     cancelled[date],
+			GoingCount:    summary.Going,
+			NotGoingCount: summary.NotGoing,
+			MaybeCount:    summary.Maybe,
+			WaitlistCount: summary.Waitlisted,
+			UserResponse:  summary.UserResponse,
+		}
+		if event.EndTime != nil {
+			o.EndTime = t.Add(event.EndTime.Sub(event.EventTime)).Format(queries.EventTimeLayout)
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"event_id":        event.ID,
+		"recurrence_rule": event.RecurrenceRule,
+		"capacity":        event.Capacity,
+		"occurrences":     occurrences,
+	})
+}
+
+// canManageEvent reports whether userID created the event or owns its group.
+func canManageEvent(event models.GroupEvent, userID int) (bool, error) {
+	if event.CreatorID == int64(userID) {
+		return true, nil
+	}
+	ownerID, err := queries.GetGroupOwnerIDByEventID(event.ID)
+	if err != nil {
+		return false, err
+	}
+	return ownerID == int64(userID), nil
+}
+
+// AddEventException cancels a single occurrence of a recurring event.
+// Body: {"occurrence_date": "YYYY-MM-DD"}
+func AddEventException(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var req struct {
+		OccurrenceDate string `json:"occurrence_date"`
+	}
+	if err := utils.ParseJSON(r, &req); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	event, ok := loadEventForMember(w, r, userID)
+	if !ok {
+		return
+	}
+	if !authorizeEventManager(w, event, userID) {
+		return
+	}
+	if event.RecurrenceRule == "" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Event does not repeat"})
+		return
+	}
+
+	isOccurrence, err := isEventOccurrence(event, req.OccurrenceDate)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to check occurrence"})
+		return
+	}
+	if !isOccurrence {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Date is not a scheduled occurrence of this event"})
+		return
+	}
+
+	if err := queries.AddEventException(event.ID, req.OccurrenceDate); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to cancel occurrence"})
+		return
+	}
+
+	ws.BroadcastToGroup(event.GroupID, "event_occurrence_cancelled", map[string]interface{}{
+		"eventId":        event.ID,
+		"groupId":        event.GroupID,
+		"occurrenceDate": req.OccurrenceDate,
+	})
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Occurrence cancelled"})
+}
+
+// RemoveEventException restores a cancelled occurrence of a recurring event.
+func RemoveEventException(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	date := r.PathValue("date")
+	if _, err := time.Parse(occurrenceDateLayout, date); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid occurrence date"})
+		return
+	}
+
+	event, ok := loadEventForMember(w, r, userID)
+	if !ok {
+		return
+	}
+	if !authorizeEventManager(w, event, userID) {
+		return
+	}
+
+	if err := queries.RemoveEventException(event.ID, date); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to restore occurrence"})
+		return
+	}
+
+	ws.BroadcastToGroup(event.GroupID, "event_occurrence_restored", map[string]interface{}{
+		"eventId":        event.ID,
+		"groupId":        event.GroupID,
+		"occurrenceDate": date,
+	})
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Occurrence restored"})
+}
+
+// authorizeEventManager writes a 403 unless userID may manage the event.
+func authorizeEventManager(w http.ResponseWriter, event models.GroupEvent, userID int) bool {
+	allowed, err := canManageEvent(event, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to verify permissions"})
+		return false
+	}
+	if !allowed {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Forbidden: You do not own this event"})
+		return false
+	}
+	return true
+}
@@ -82,6 +82,24 @@ func InviteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Blocked users can't invite each other
+	blocked, err := queries.IsBlockedBetween(inviterID, inviteeID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Error checking invitee",
+		})
+		return
+	}
+
+	if blocked {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+			Success: false,
+			Message: "You can't invite this user",
+		})
+		return
+	}
+
 	// Check if invitee is already a member
 	isAlreadyMember, err := queries.IsUserGroupMember(int(groupID), inviteeID)
 	if err != nil {
@@ -137,6 +155,7 @@ func InviteUser(w http.ResponseWriter, r *http.Request) {
 			// Log error but don't fail the request
 			println("Failed to delete join request:", err.Error())
 		}
+		emitMemberJoined(groupID, inviteeID, "join_request")
 
 		// Send notification to the user that they were accepted
 		group, _ := queries.GetGroupByID(groupID)
@@ -294,6 +313,7 @@ func HandleInvitation(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		emitMemberJoined(groupID, userID, "invitation")
 	}
 
 	// Delete the invitation
@@ -5,6 +5,7 @@ import (
 	"backend/internal/models"
 	activity "backend/internal/notifications"
 	"backend/internal/utils"
+	"backend/internal/webhooks"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -118,6 +119,7 @@ func JoinGroup(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			fmt.Printf("Failed to delete pending invitation: %v\n", err)
 		}
+		emitMemberJoined(groupIDInt, userID, "invitation")
 
 		utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
 			Success: true,
@@ -136,6 +138,11 @@ func JoinGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	webhooks.EmitGroupEvent(groupIDInt, webhooks.EventJoinRequestCreated, userID, map[string]interface{}{
+		"group_id": groupIDInt,
+		"user_id":  userID,
+	})
+
 	// Get user details to send in notification
 	user, err := queries.GetUserByID(userID)
 	if err == nil {
@@ -167,3 +174,13 @@ func JoinGroup(w http.ResponseWriter, r *http.Request) {
 		Message: activity.GroupJoinRequestSent(group.Name).Message,
 	})
 }
+
+// emitMemberJoined tells the group's webhooks that userID joined, via an
+// accepted "invitation" or an approved "join_request".
+func emitMemberJoined(groupID int64, userID int, via string) {
+	webhooks.EmitGroupEvent(groupID, webhooks.EventMemberJoined, userID, map[string]interface{}{
+		"group_id": groupID,
+		"user_id":  userID,
+		"via":      via,
+	})
+}
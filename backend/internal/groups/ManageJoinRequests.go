@@ -188,6 +188,7 @@ func HandleJoinRequest(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		emitMemberJoined(joinRequest.GroupID, joinRequest.UserID, "join_request")
 		approvedText := activity.GroupJoinApproved(group.Name)
 		message = approvedText.Message
 		requester, _ := queries.GetUserByID(joinRequest.UserID)
@@ -2,10 +2,10 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -88,8 +88,8 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the image path before deleting
-	imagePath, err := queries.GetPostImagePath(postID)
+	// Get the attachments before deleting
+	paths, err := queries.GetPostAttachmentPaths(postID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -108,12 +108,8 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the image file from disk if it exists
-	if imagePath != nil && *imagePath != "" {
-		// Remove leading slash to get relative path from project root
-		filePath := strings.TrimPrefix(*imagePath, "/")
-		if err := os.Remove(filePath); err != nil {
-		}
+	for _, path := range paths {
+		media.Release(path)
 	}
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
@@ -3,6 +3,7 @@ package groups
 import (
 	"backend/internal/db/queries"
 	"backend/internal/models"
+	"backend/internal/policy"
 	"backend/internal/utils"
 	"net/http"
 	"strconv"
@@ -48,6 +49,32 @@ func PostLike(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post, err := queries.GetPostByID(postID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	// Only viewers allowed to interact with the post may like it
+	canInteract, err := policy.CanInteract(userID, post)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to verify access",
+		})
+		return
+	}
+	if !canInteract {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+			Success: false,
+			Message: "Access denied",
+		})
+		return
+	}
+
 	// Toggle the like
 	isLiked, err := queries.TogglePostLike(postID, userID)
 	if err != nil {
@@ -76,7 +76,7 @@ func GetPotentialInvitees(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := queries.GetUsersNotInGroup(groupID)
+	users, err := queries.GetUsersNotInGroup(groupID, userID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
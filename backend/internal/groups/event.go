@@ -2,15 +2,23 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
+	activity "backend/internal/notifications"
+	"backend/internal/reminders"
 	"backend/internal/utils"
+	"backend/internal/webhooks"
 	"backend/internal/ws"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// maxEventDuration bounds the time between an event's start and end.
+const maxEventDuration = 7 * 24 * time.Hour
+
 func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -83,21 +91,26 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if title == "" || len(title) > 20 {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Event title is required and must be 20 characters or less",
-		})
+	if resp := ValidateEventText(title, description); resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
 		return
 	}
-	if description == "" || len(description) > 150 {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Event description is required and must be 150 characters or less",
-		})
+	startsAt, resp := ValidateEventDateTime(eventDate, eventTime)
+	if resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
 		return
 	}
-	_, resp := ValidateEventDateTime(eventDate, eventTime)
+	recurrenceRule, resp := ParseRecurrence(r.FormValue("recurrence"), r.FormValue("rrule"), startsAt)
+	if resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
+		return
+	}
+	endDate, endTime, resp := ValidateEventEnd(startsAt, r.FormValue("end_date"), r.FormValue("end_time"))
+	if resp != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, *resp)
+		return
+	}
+	location, capacity, resp := ValidateEventDetails(r.FormValue("location"), r.FormValue("capacity"))
 	if resp != nil {
 		utils.RespondJSON(w, http.StatusBadRequest, *resp)
 		return
@@ -108,8 +121,7 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 
-		// Save cover image using utility function
-		path, err := utils.SaveUploadedFile(file, handler, "groups")
+		urls, err := media.SaveUploadedFile(file, handler, "groups", userID)
 		if err != nil {
 			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 				Success: false,
@@ -117,12 +129,25 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		coverImagePath = path
+		coverImagePath = urls.Original
 	}
-	// Convert groupID to int64 and call CreateGroupEvent with DB, groupID, userID, title, description, date, time, cover image path
+	// Convert groupID to int64 and store the event
 	groupID64 := int64(groupID)
 	userID64 := int64(userID)
-	eventID, err := queries.CreateGroupEvent(groupID64, userID64, title, description, eventDate, eventTime, coverImagePath)
+	eventID, err := queries.CreateGroupEvent(models.CreateGroupEventParams{
+		GroupID:        groupID64,
+		CreatorID:      userID64,
+		Title:          title,
+		Description:    description,
+		EventDate:      eventDate,
+		EventTime:      eventTime,
+		EndDate:        endDate,
+		EndTime:        endTime,
+		Location:       location,
+		Capacity:       capacity,
+		ImagePath:      coverImagePath,
+		RecurrenceRule: recurrenceRule,
+	})
 	if err != nil {
 		fmt.Println("Error creating event:", err)
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
@@ -132,6 +157,12 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if created, err := queries.GetGroupEventByID(eventID); err == nil {
+		if err := reminders.ScheduleEvent(created); err != nil {
+			fmt.Println("Error scheduling event reminders:", err)
+		}
+	}
+
 	// Prepare event data for WebSocket notification
 	// Fetch creator details for the broadcast
 	creator, err := queries.GetUserByID(int(userID64))
@@ -146,16 +177,19 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newEvent := models.Event{
-		ID:          eventID,
-		GroupID:     groupID64,
-		Title:       title,
-		Description: description,
-		StartTime:   eventDate + " " + eventTime,
-		EndTime:     "", // Assumed empty for now as per schema
-		ImagePath:   coverImagePath,
-		CreatedAt:   time.Now().Format("2006-01-02 15:04:05"),
-		CreatorID:   userID64,
-		Creator:     creatorPublic,
+		ID:             eventID,
+		GroupID:        groupID64,
+		Title:          title,
+		Description:    description,
+		StartTime:      eventDate + " " + eventTime,
+		EndTime:        strings.TrimSpace(endDate + " " + endTime),
+		Location:       location,
+		Capacity:       capacity,
+		ImagePath:      coverImagePath,
+		RecurrenceRule: recurrenceRule,
+		CreatedAt:      time.Now().Format("2006-01-02 15:04:05"),
+		CreatorID:      userID64,
+		Creator:        creatorPublic,
 	}
 
 	// Notify group members
@@ -163,6 +197,9 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 		"groupId": groupID64,
 		"event":   newEvent,
 	})
+	webhooks.EmitGroupEvent(groupID64, webhooks.EventEventCreated, userID, map[string]interface{}{
+		"event": newEvent,
+	})
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
 		Success: true,
@@ -170,6 +207,23 @@ func CreateAnEvent(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ValidateEventText checks the required title and description of an event.
+func ValidateEventText(title, description string) *models.GenericResponse {
+	if title == "" || len(title) > 20 {
+		return &models.GenericResponse{
+			Success: false,
+			Message: "Event title is required and must be 20 characters or less",
+		}
+	}
+	if description == "" || len(description) > 150 {
+		return &models.GenericResponse{
+			Success: false,
+			Message: "Event description is required and must be 150 characters or less",
+		}
+	}
+	return nil
+}
+
 func ValidateEventDateTime(date, timeStr string) (time.Time, *models.GenericResponse) {
 	if date == "" {
 		return time.Time{}, &models.GenericResponse{
@@ -202,6 +256,70 @@ func ValidateEventDateTime(date, timeStr string) (time.Time, *models.GenericResp
 	return dt, nil
 }
 
+// ValidateEventEnd checks the optional end of an event starting at start. The end
+// date defaults to the start date; both are returned empty when no end time is set.
+func ValidateEventEnd(start time.Time, endDate, endTime string) (string, string, *models.GenericResponse) {
+	if endTime == "" {
+		if endDate != "" {
+			return "", "", &models.GenericResponse{
+				Success: false,
+				Message: "Event end time is required when an end date is set",
+			}
+		}
+		return "", "", nil
+	}
+	if endDate == "" {
+		endDate = start.Format("2006-01-02")
+	}
+
+	end, err := time.Parse("2006-01-02 15:04", endDate+" "+endTime)
+	if err != nil {
+		return "", "", &models.GenericResponse{
+			Success: false,
+			Message: "Invalid end date or time format",
+		}
+	}
+	if !end.After(start) {
+		return "", "", &models.GenericResponse{
+			Success: false,
+			Message: "Event must end after it starts",
+		}
+	}
+	if end.Sub(start) > maxEventDuration {
+		return "", "", &models.GenericResponse{
+			Success: false,
+			Message: "Event cannot last longer than 7 days",
+		}
+	}
+
+	return endDate, endTime, nil
+}
+
+// ValidateEventDetails checks the optional location text and capacity
+// (an empty capacity means unlimited and is returned as 0).
+func ValidateEventDetails(location, capacityStr string) (string, int, *models.GenericResponse) {
+	location = strings.TrimSpace(location)
+	if len(location) > 100 {
+		return "", 0, &models.GenericResponse{
+			Success: false,
+			Message: "Event location must be 100 characters or less",
+		}
+	}
+
+	if capacityStr == "" {
+		return location, 0, nil
+	}
+	capacity, err := strconv.Atoi(capacityStr)
+	if err != nil || capacity < 1 || capacity > 10000 {
+		return "", 0, &models.GenericResponse{
+			Success: false,
+			Message: "Event capacity must be a number between 1 and 10000",
+		}
+	}
+
+	return location, capacity, nil
+}
+
 func RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		utils.RespondJSON(w, http.StatusMethodNotAllowed, models.GenericResponse{
@@ -213,8 +331,9 @@ func RespondToEvent(w http.ResponseWriter, r *http.Request) {
 
 	// Helper struct for request body
 	type ResponseRequest struct {
-		EventID  int64  `json:"event_id"`
-		Response string `json:"response"`
+		EventID        int64  `json:"event_id"`
+		Response       string `json:"response"`
+		OccurrenceDate string `json:"occurrence_date"` // required for recurring events
 	}
 
 	var req ResponseRequest
@@ -227,10 +346,10 @@ func RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate response
-	if req.Response != "going" && req.Response != "not-going" && req.Response != "" {
+	if req.Response != "going" && req.Response != "not-going" && req.Response != "maybe" && req.Response != "" {
 		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 			Success: false,
-			Message: "Response must be 'going', 'not-going', or empty to remove",
+			Message: "Response must be 'going', 'not-going', 'maybe', or empty to remove",
 		})
 		return
 	}
@@ -238,13 +357,41 @@ func RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(int)
 	userID64 := int64(userID)
 
-	var err error
-	if req.Response == "" {
-		err = queries.RemoveEventResponse(req.EventID, userID64)
+	event, err := queries.GetGroupEventByID(req.EventID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{
+			Success: false,
+			Message: "Event not found",
+		})
+		return
+	}
+	if !checkEventMember(w, event, userID) {
+		return
+	}
+
+	// Recurring events take RSVPs per occurrence, single events ignore the date
+	if event.RecurrenceRule == "" {
+		req.OccurrenceDate = ""
 	} else {
-		err = queries.AddEventResponse(req.EventID, userID64, req.Response)
+		isOccurrence, err := isEventOccurrence(event, req.OccurrenceDate)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+				Success: false,
+				Message: "Failed to check occurrence",
+			})
+			return
+		}
+		if !isOccurrence {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+				Success: false,
+				Message: "occurrence_date must be a scheduled occurrence of this event",
+			})
+			return
+		}
 	}
 
+	// "going" turns into "waitlisted" when the event is full
+	stored, promoted, err := queries.SetEventResponse(req.EventID, userID64, req.OccurrenceDate, req.Response, event.Capacity)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -254,30 +401,50 @@ func RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get updated counts
-	going, notGoing, err := queries.GetEventResponseCounts(req.EventID)
+	counts, err := queries.GetEventResponseCounts(req.EventID, req.OccurrenceDate)
 	if err != nil {
 		fmt.Printf("Error getting counts: %v\n", err)
 	}
 
-	groupID, err := queries.GetGroupIDByEventID(req.EventID)
-	if err == nil {
-		// Broadcast update
-		ws.BroadcastToGroup(groupID, "event_response_update", map[string]interface{}{
-			"eventId":       req.EventID,
-			"groupId":       groupID,
-			"userId":        userID,
-			"response":      req.Response,
-			"goingCount":    going,
-			"notGoingCount": notGoing,
-		})
-	}
+	// Broadcast update
+	ws.BroadcastToGroup(event.GroupID, "event_response_update", map[string]interface{}{
+		"eventId":         req.EventID,
+		"groupId":         event.GroupID,
+		"userId":          userID,
+		"response":        stored,
+		"occurrenceDate":  req.OccurrenceDate,
+		"goingCount":      counts.Going,
+		"notGoingCount":   counts.NotGoing,
+		"maybeCount":      counts.Maybe,
+		"waitlistCount":   counts.Waitlisted,
+		"promotedUserIds": promoted,
+	})
 
-	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
-		Success: true,
-		Message: "Response recorded",
+	notifyWaitlistPromotions(event, req.OccurrenceDate, promoted)
+
+	message := "Response recorded"
+	if stored == "waitlisted" {
+		message = "Event is full, you have been added to the waitlist"
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"response": stored,
 	})
 }
 
+// notifyWaitlistPromotions tells users moved from the waitlist to "going" in real time.
+func notifyWaitlistPromotions(event models.GroupEvent, occurrenceDate string, promoted []int64) {
+	for _, promotedID := range promoted {
+		_ = activity.NotifyRecentActivity(int(promotedID), nil, "event_waitlist_promoted", activity.EventWaitlistPromoted(event.Title, occurrenceDate), map[string]interface{}{
+			"event_id":        event.ID,
+			"group_id":        event.GroupID,
+			"occurrence_date": occurrenceDate,
+			"status":          "going",
+		})
+	}
+}
+
 func GetEventResponsesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.RespondJSON(w, http.StatusMethodNotAllowed, models.GenericResponse{
@@ -305,7 +472,21 @@ func GetEventResponsesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voters, err := queries.GetEventVoters(int64(eventID))
+	userID, _ := utils.GetUserIDFromContext(r)
+	event, err := queries.GetGroupEventByID(int64(eventID))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{
+			Success: false,
+			Message: "Event not found",
+		})
+		return
+	}
+	if !checkEventMember(w, event, userID) {
+		return
+	}
+
+	// occurrence_date selects one occurrence of a recurring event
+	voters, err := queries.GetEventVoters(int64(eventID), r.URL.Query().Get("occurrence_date"))
 	if err != nil {
 		fmt.Printf("Error fetching voters: %v\n", err)
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
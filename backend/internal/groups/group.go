@@ -2,6 +2,7 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
 	"net/http"
@@ -78,12 +79,12 @@ func CreateGroup(w http.ResponseWriter, r *http.Request) {
 
 	// Handle cover image file upload (optional)
 	var coverImagePath string
+	var coverURLs *models.MediaURLs
 	file, handler, err := r.FormFile("coverImage")
 	if err == nil {
 		defer file.Close()
 
-		// Save cover image using utility function
-		path, err := utils.SaveUploadedFile(file, handler, "groups")
+		urls, err := media.SaveUploadedFile(file, handler, "groups", session.UserID)
 		if err != nil {
 			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 				Success: false,
@@ -91,7 +92,8 @@ func CreateGroup(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		coverImagePath = path
+		coverURLs = &urls
+		coverImagePath = urls.Original
 	}
 
 	// Create the group in the database
@@ -115,9 +117,10 @@ func CreateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Group created successfully",
-		"groupId": groupID,
+		"success":    true,
+		"message":    "Group created successfully",
+		"groupId":    groupID,
+		"coverImage": coverURLs,
 	})
 }
 
@@ -2,6 +2,7 @@ package groups
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"encoding/json"
 	"net/http"
@@ -58,10 +59,15 @@ func GetGroupInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get posts
-	posts, err := queries.GetGroupPosts(groupID)
+	posts, err := queries.GetGroupPosts(groupID, userID)
 	if err != nil {
 		posts = []models.Post{} // Empty array on error
 	}
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	media.ResolveSizes(refs...)
 
 	// Get events
 	events, err := queries.GetGroupEvents(groupID, userID)
@@ -1,11 +1,15 @@
 package groups
 
 import (
+	"backend/internal/contentfilter"
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
+	"backend/internal/webhooks"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
@@ -59,25 +63,25 @@ func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var imagePath *string
-	// Handle optional image upload
-	file, handler, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-		// Save image to uploads/posts
-		path, err := utils.SaveUploadedFile(file, handler, "posts")
-		if err != nil {
-			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
-				Success: false,
-				Message: err.Error(),
-			})
-			return
-		}
-		imagePath = &path
+	decision := contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindGroupPost, Text: content})
+	if decision.Rejected() {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+		return
+	}
+
+	attachments, err := media.SaveAttachments(r.MultipartForm, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
 	}
 
 	// Create post in database
-	if err := queries.CreateGroupPost(groupIDInt, userID, content, imagePath); err != nil {
+	postID, err := queries.CreateGroupPost(groupIDInt, userID, content, attachments)
+	if err != nil {
+		media.ReleaseAttachments(attachments)
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
 			Message: "Failed to create post",
@@ -85,12 +89,27 @@ func CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.RespondJSON(w, http.StatusCreated, models.GenericResponse{
-		Success: true,
-		Message: "Post created successfully",
+	decision.Report("post", postID, userID, content)
+
+	post := models.Post{ID: postID, UserID: userID, GroupID: &groupIDInt, Content: content, Attachments: attachments, CreatedAt: time.Now().UTC().Format("2006-01-02 15:04:05")}
+	if len(attachments) > 0 {
+		post.ImagePath = attachments[0].URL
+	}
+	webhooks.EmitGroupEvent(groupIDInt, webhooks.EventPostCreated, userID, map[string]interface{}{
+		"post": post,
+	})
+
+	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":     true,
+		"message":     "Post created successfully",
+		"post_id":     postID,
+		"attachments": attachments,
 	})
 }
 
+// GetGroupPosts handles GET /api/groups/posts?groupID=&cursor=&limit=
+// Returns a page of group posts, newest first. cursor is the ID of the last
+// post of the previous page; next_cursor is 0 when there are no more posts.
 func GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -130,8 +149,24 @@ func GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch posts from database
-	posts, err := queries.GetGroupPosts(groupID)
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 20 {
+			limit = v
+		}
+	}
+	var cursor int64
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		if v, err := strconv.ParseInt(c, 10, 64); err == nil && v > 0 {
+			cursor = v
+		}
+	}
+
+	// Get current user ID for like status
+	userID, _ := utils.GetUserIDFromContext(r)
+
+	// Fetch one extra row to know whether another page exists
+	posts, err := queries.GetGroupPostsPage(groupID, userID, cursor, limit+1)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -140,48 +175,36 @@ func GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get current user ID for like status
-	userID, _ := utils.GetUserIDFromContext(r)
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i].Post
+	}
+	media.ResolveSizes(refs...)
 
-	// Fetch author details and like info for each post
-	type PostWithAuthor struct {
-		models.Post
-		Author   *models.User `json:"author"`
-		Likes    int          `json:"likes"`
-		IsLiked  bool         `json:"is_liked"`
-		Comments int          `json:"comments"`
+	// Comments is kept alongside comments_count for existing group clients
+	type GroupPost struct {
+		models.PostWithMeta
+		Comments int `json:"comments"`
 	}
 
-	postsWithAuthors := make([]PostWithAuthor, 0, len(posts))
+	result := make([]GroupPost, 0, len(posts))
 	for _, post := range posts {
-		user, err := queries.GetUserByID(post.UserID)
-		if err != nil {
-			// Skip posts with missing authors
-			continue
-		}
-
-		// Get like count
-		likesCount, _ := queries.GetPostLikesCount(post.ID)
-
-		// Check if current user liked this post
-		isLiked := false
-		if userID != 0 {
-			isLiked, _ = queries.IsPostLikedByUser(post.ID, userID)
-		}
-
-		commentsCount, _ := queries.GetCommentCount(post.ID)
+		result = append(result, GroupPost{PostWithMeta: post, Comments: post.CommentsCount})
+	}
 
-		postsWithAuthors = append(postsWithAuthors, PostWithAuthor{
-			Post:     post,
-			Author:   &user,
-			Likes:    likesCount,
-			IsLiked:  isLiked,
-			Comments: commentsCount,
-		})
+	var nextCursor int64
+	if hasMore && len(posts) > 0 {
+		nextCursor = posts[len(posts)-1].ID
 	}
 
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"posts":   postsWithAuthors,
+		"success":     true,
+		"posts":       result,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
 	})
 }
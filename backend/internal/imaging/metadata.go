@@ -0,0 +1,304 @@
+// Package imaging prepares uploaded images: it removes metadata such as EXIF
+// location tags without re-encoding, applies the camera orientation, and makes
+// smaller copies. Everything is pure Go.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errMalformed = errors.New("imaging: malformed image")
+
+// StripMetadata returns the image with its metadata removed, and the EXIF
+// orientation it carried (1, the default, if none). Pixel data is left as is.
+// GIF, JPEG, PNG and WebP are supported; other types are returned unchanged.
+func StripMetadata(data []byte, mimeType string) ([]byte, int, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	case "image/gif":
+		out, err := stripGIF(data)
+		return out, 1, err
+	}
+	return data, 1, nil
+}
+
+// stripJPEG drops EXIF, XMP, IPTC, MPF and comment segments, and everything
+// after the end of the image, where cameras append preview frames and MPF
+// secondary images with EXIF of their own. JFIF (APP0), ICC profiles (APP2)
+// and the Adobe segment (APP14) are kept, as they affect how the image is decoded.
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 1, errMalformed
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	orientation := 1
+
+	i := 2
+	for i < len(data) {
+		if i+1 >= len(data) || data[i] != 0xFF {
+			return nil, 1, errMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xD9 { // end of image
+			out.Write(data[i : i+2])
+			return out.Bytes(), orientation, nil
+		}
+		// Markers without a payload
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write(data[i : i+2])
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return nil, 1, errMalformed
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end < i+4 || end > len(data) {
+			return nil, 1, errMalformed
+		}
+		payload := data[i+4 : end]
+
+		if marker == 0xDA { // start of scan
+			// The image data runs to the next marker other than a restart; 0xFF00
+			// is an escaped 0xFF byte. Progressive images have several scans.
+			j := end
+			for j+1 < len(data) {
+				if next := data[j+1]; data[j] == 0xFF && next != 0x00 && next != 0xFF && (next < 0xD0 || next > 0xD7) {
+					break
+				}
+				j++
+			}
+			if j+1 >= len(data) { // truncated: no end of image
+				out.Write(data[i:])
+				return out.Bytes(), orientation, nil
+			}
+			out.Write(data[i:j])
+			i = j
+			continue
+		}
+		keep := true
+		switch {
+		case marker == 0xE1:
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				if o := exifOrientation(payload[6:]); o != 0 {
+					orientation = o
+				}
+			}
+			keep = false
+		case marker == 0xE2:
+			keep = bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+		case marker == 0xE0, marker == 0xEE:
+		case marker >= 0xE3 && marker <= 0xEF, marker == 0xFE:
+			keep = false
+		}
+		if keep {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return nil, 1, errMalformed
+}
+
+// exifOrientation reads the orientation tag (0x0112) of a TIFF-encoded EXIF
+// block. It returns 0 if there is none.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// pngMetadata lists the PNG chunks that carry text, EXIF or timestamps.
+var pngMetadata = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, int, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, 1, errMalformed
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.WriteString(signature)
+	orientation := 1
+
+	i := len(signature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, 1, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		kind := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if end > len(data) {
+			return nil, 1, errMalformed
+		}
+		if kind == "eXIf" {
+			if o := exifOrientation(data[i+8 : i+8+length]); o != 0 {
+				orientation = o
+			}
+		}
+		if !pngMetadata[kind] {
+			out.Write(data[i:end])
+		}
+		i = end
+		if kind == "IEND" {
+			break
+		}
+	}
+	return out.Bytes(), orientation, nil
+}
+
+// stripWebP drops the EXIF and XMP chunks of an extended WebP file and clears
+// their flags in the VP8X header. Simple WebP files carry no metadata.
+func stripWebP(data []byte) ([]byte, int, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, 1, errMalformed
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	orientation := 1
+
+	i := 12
+	for i+8 <= len(data) {
+		kind := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end == len(data)+1 && size%2 == 1 { // unpadded last chunk
+			end = len(data)
+		}
+		if end > len(data) {
+			return nil, 1, errMalformed
+		}
+		switch kind {
+		case "EXIF":
+			exif := data[i+8 : i+8+size]
+			exif = bytes.TrimPrefix(exif, []byte("Exif\x00\x00"))
+			if o := exifOrientation(exif); o != 0 {
+				orientation = o
+			}
+		case "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	b := out.Bytes()
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b, orientation, nil
+}
+
+// stripGIF drops comment extensions and XMP application extensions. Other
+// application extensions, such as the animation loop count, are kept.
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, errMalformed
+	}
+	i := 13
+	if data[10]&0x80 != 0 { // global color table
+		i += 3 << (int(data[10]&0x07) + 1)
+	}
+	if i > len(data) {
+		return nil, errMalformed
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+
+	// subBlocks returns the end of the data sub-blocks starting at j
+	subBlocks := func(j int) (int, error) {
+		for j < len(data) {
+			n := int(data[j])
+			j++
+			if n == 0 {
+				return j, nil
+			}
+			j += n
+		}
+		return 0, errMalformed
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x3B: // trailer
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+		case 0x21: // extension
+			if i+2 > len(data) {
+				return nil, errMalformed
+			}
+			label := data[i+1]
+			end, err := subBlocks(i + 2)
+			if err != nil {
+				return nil, err
+			}
+			drop := label == 0xFE ||
+				(label == 0xFF && i+14 <= len(data) && string(data[i+3:i+14]) == "XMP DataXMP")
+			if !drop {
+				out.Write(data[i:end])
+			}
+			i = end
+		case 0x2C: // image
+			j := i + 10
+			if j > len(data) {
+				return nil, errMalformed
+			}
+			if data[i+9]&0x80 != 0 { // local color table
+				j += 3 << (int(data[i+9]&0x07) + 1)
+			}
+			end, err := subBlocks(j + 1) // after the LZW code size
+			if err != nil {
+				return nil, err
+			}
+			out.Write(data[i:end])
+			i = end
+		default:
+			return nil, errMalformed
+		}
+	}
+	// Missing trailer: keep what was read, as decoders do
+	out.WriteByte(0x3B)
+	return out.Bytes(), nil
+}
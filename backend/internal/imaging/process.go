@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds the size of the images that are decoded, so a small file
+// can't claim gigabytes of memory.
+const MaxPixels = 50_000_000
+
+var (
+	// ErrInvalid is returned for files that are not a readable image.
+	ErrInvalid = errors.New("file is not a valid image")
+	// ErrTooLarge is returned for images with more than MaxPixels pixels.
+	ErrTooLarge = errors.New("image dimensions are too large")
+)
+
+const (
+	originalQuality = 92 // when a rotated original is re-encoded
+	variantQuality  = 82
+)
+
+// Size is a variant to generate: the image scaled down to fit in a square of
+// MaxSide pixels.
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// Variant is a scaled-down copy of an image.
+type Variant struct {
+	Name     string
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// Result is a processed image. Data and MimeType replace the upload.
+type Result struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+	Variants []Variant
+}
+
+// Process strips the metadata of an image, turns it upright if its EXIF
+// orientation says so, and makes a variant for each size smaller than the
+// image. Sizes must be ordered from largest to smallest. Images that decode
+// only partially, such as animated WebP, are kept without variants.
+func Process(data []byte, mimeType string, sizes []Size) (Result, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, ErrInvalid
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return Result{}, ErrInvalid
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return Result{}, ErrTooLarge
+	}
+
+	stripped, orientation, err := StripMetadata(data, mimeType)
+	if err != nil {
+		return Result{}, ErrInvalid
+	}
+	res := Result{Data: stripped, MimeType: mimeType, Width: cfg.Width, Height: cfg.Height}
+
+	img, _, err := image.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return res, nil
+	}
+	src := toRGBA(img)
+
+	// The orientation went with the metadata, so it is applied to the pixels.
+	// JPEGs stay JPEGs; other formats are rare here and become PNG
+	if orientation != 1 {
+		src = orient(src, orientation)
+		var buf bytes.Buffer
+		if mimeType == "image/jpeg" {
+			err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: originalQuality})
+		} else {
+			err = png.Encode(&buf, src)
+			res.MimeType = "image/png"
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		res.Data = buf.Bytes()
+		res.Width, res.Height = src.Bounds().Dx(), src.Bounds().Dy()
+	}
+
+	variants, err := Variants(src, sizes)
+	if err != nil {
+		return Result{}, err
+	}
+	res.Variants = variants
+	return res, nil
+}
+
+// Variants makes a copy of img for each size smaller than it, largest first.
+func Variants(img image.Image, sizes []Size) ([]Variant, error) {
+	src := toRGBA(img)
+	var variants []Variant
+	for _, size := range sizes {
+		w, h := fit(src.Bounds().Dx(), src.Bounds().Dy(), size.MaxSide)
+		if w == src.Bounds().Dx() && h == src.Bounds().Dy() {
+			continue // already small enough
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+		v, err := Encode(size.Name, dst)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+		src = dst // smaller sizes scale from this one, which is faster
+	}
+	return variants, nil
+}
+
+// Encode encodes img as a variant named name: a JPEG, or a PNG if it has
+// transparency.
+func Encode(name string, img image.Image) (Variant, error) {
+	rgba := toRGBA(img)
+	v := Variant{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	var buf bytes.Buffer
+	var err error
+	if rgba.Opaque() {
+		v.MimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: variantQuality})
+	} else {
+		v.MimeType = "image/png"
+		err = png.Encode(&buf, rgba)
+	}
+	if err != nil {
+		return Variant{}, err
+	}
+	v.Data = buf.Bytes()
+	return v, nil
+}
+
+// fit returns the dimensions of a w×h image scaled down to fit in a square of
+// maxSide pixels, keeping its aspect ratio. Smaller images keep their size.
+func fit(w, h, maxSide int) (int, int) {
+	if w <= maxSide && h <= maxSide {
+		return w, h
+	}
+	if w >= h {
+		return maxSide, max(1, h*maxSide/w)
+	}
+	return max(1, w*maxSide/h), maxSide
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// orient applies an EXIF orientation (2 to 8) to an image.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // upside down
+				dx, dy = w-1-x, h-1-y
+			case 4: // upside down, mirrored
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counterclockwise
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			si := y*src.Stride + x*4
+			di := dy*dst.Stride + dx*4
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
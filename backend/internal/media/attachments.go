@@ -0,0 +1,102 @@
+package media
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"mime/multipart"
+)
+
+const (
+	// MaxAttachments is the most images and videos a post can carry.
+	MaxAttachments = 4
+	// MaxAltText bounds the alt text of an attachment.
+	MaxAltText = 1000
+)
+
+// SaveAttachment stores a file attached to a post by ownerID.
+// Errors are meant for the uploader.
+func SaveAttachment(fh *multipart.FileHeader, altText string, ownerID int) (models.PostAttachment, error) {
+	if len(altText) > MaxAltText {
+		return models.PostAttachment{}, fmt.Errorf("alt text must be at most %d characters", MaxAltText)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return models.PostAttachment{}, errors.New("failed to read uploaded file")
+	}
+	defer file.Close()
+	urls, err := SaveUploadedFile(file, fh, "posts", ownerID)
+	if err != nil {
+		return models.PostAttachment{}, err
+	}
+	return models.PostAttachment{URL: urls.Original, AltText: altText, Sizes: urls}, nil
+}
+
+// SaveAttachments stores the files of a post form: the "attachments" files in
+// order, each described by the "alt_text" value at the same position, or the
+// single "image" sent by older clients. Files saved before an error are
+// released. Errors are meant for the uploader.
+func SaveAttachments(form *multipart.Form, ownerID int) ([]models.PostAttachment, error) {
+	files := form.File["attachments"]
+	if len(files) == 0 {
+		files = form.File["image"]
+	}
+	if len(files) > MaxAttachments {
+		return nil, fmt.Errorf("a post can have at most %d attachments", MaxAttachments)
+	}
+	altTexts := form.Value["alt_text"]
+
+	attachments := make([]models.PostAttachment, 0, len(files))
+	for i, fh := range files {
+		var altText string
+		if i < len(altTexts) {
+			altText = altTexts[i]
+		}
+		a, err := SaveAttachment(fh, altText, ownerID)
+		if err != nil {
+			ReleaseAttachments(attachments)
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// ReleaseAttachments releases the media of attachments, e.g. of a post that
+// failed to save.
+func ReleaseAttachments(attachments []models.PostAttachment) {
+	for _, a := range attachments {
+		Release(a.URL)
+	}
+}
+
+// ResolveSizes sets the URLs of each size of the attachments of posts,
+// looking the variants of all of them up at once. Failures are logged and
+// leave every size at the original.
+func ResolveSizes(posts ...*models.Post) {
+	var ids []int64
+	for _, p := range posts {
+		for _, a := range p.Attachments {
+			if id, ok := ID(a.URL); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	variants, err := queries.GetVariantsOfMedia(ids)
+	if err != nil {
+		fmt.Printf("media: failed to look up variants: %v\n", err)
+	}
+
+	for _, p := range posts {
+		for i := range p.Attachments {
+			a := &p.Attachments[i]
+			id, _ := ID(a.URL)
+			vs := variants[id]
+			for j := range vs {
+				vs[j].URL = VariantURL(id, vs[j].Name, vs[j].MimeType)
+			}
+			a.Sizes = urls(models.Media{URL: a.URL}, vs)
+		}
+	}
+}
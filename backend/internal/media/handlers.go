@@ -0,0 +1,161 @@
+package media
+
+import (
+	"backend/internal/models"
+	"backend/internal/policy"
+	"backend/internal/storage"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Serve handles GET /media/{file}, the original of an upload. It takes a
+// session cookie of a user who may see the media, or a signed URL.
+func Serve(w http.ResponseWriter, r *http.Request) {
+	url := "/media/" + r.PathValue("file")
+	id, ok := ID(url)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !authorize(w, r, id) {
+		return
+	}
+	m, obj, err := Open(url)
+	if err == nil && m.URL != url {
+		obj.Close()
+		err = sql.ErrNoRows
+	}
+	if !serveable(w, r, url, err) {
+		return
+	}
+	defer obj.Close()
+	serveObject(w, r, m.MimeType, m.Hash, m.CreatedAt, obj)
+}
+
+// ServeVariant handles GET /media/{id}/{file}, a scaled-down copy of an image
+// such as /media/12/thumbnail.jpg. Access is that of the original.
+func ServeVariant(w http.ResponseWriter, r *http.Request) {
+	url := "/media/" + r.PathValue("id") + "/" + r.PathValue("file")
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	if !authorize(w, r, id) {
+		return
+	}
+	name, _, _ := strings.Cut(r.PathValue("file"), ".")
+	v, obj, err := OpenVariant(id, name)
+	if err == nil && v.URL != url {
+		obj.Close()
+		err = sql.ErrNoRows
+	}
+	if !serveable(w, r, url, err) {
+		return
+	}
+	defer obj.Close()
+	serveObject(w, r, v.MimeType, v.Hash, obj.ModTime(), obj)
+}
+
+// serveable answers requests for media that can't be opened and reports
+// whether the response is still to be written.
+func serveable(w http.ResponseWriter, r *http.Request, url string, err error) bool {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return false
+	}
+	if err != nil {
+		fmt.Printf("media: failed to open %s: %v\n", url, err)
+		http.Error(w, "Failed to open media", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// serveObject writes stored content. The content behind a URL never changes,
+// so responses can be cached for good, by the browser only, and revalidated by
+// ETag; ranges are supported for video seeking.
+func serveObject(w http.ResponseWriter, r *http.Request, mimeType, hash string, modTime time.Time, obj storage.Object) {
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", modTime, obj)
+}
+
+// maxSignedURLs bounds the URLs signed in one request.
+const maxSignedURLs = 100
+
+// SignURLs handles POST /api/media/signed-urls
+// Body: { "urls": ["/media/12.jpg", "/media/12/thumbnail.jpg"] }. It returns
+// the URLs signed so they load without cookies until expires_at, keyed by the
+// URLs asked for. Media the user can't see is left out.
+func SignURLs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	var req struct {
+		URLs []string `json:"urls"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	if len(req.URLs) > maxSignedURLs {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false, Message: fmt.Sprintf("At most %d URLs can be signed at once", maxSignedURLs),
+		})
+		return
+	}
+
+	expires := signedURLExpiry(time.Now())
+	signed := make(map[string]string, len(req.URLs))
+	for _, u := range req.URLs {
+		id, ok := mediaID(u)
+		if !ok {
+			continue
+		}
+		visible, err := policy.CanViewMedia(userID, id)
+		if err == sql.ErrNoRows || (err == nil && !visible) {
+			continue
+		}
+		if err == nil {
+			signed[u], err = signURL(u, id, expires)
+		}
+		if err != nil {
+			fmt.Printf("media: failed to sign %s for user %d: %v\n", u, userID, err)
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to sign URLs"})
+			return
+		}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"urls":       signed,
+		"expires_at": expires.Unix(),
+	})
+}
+
+// mediaID returns the media ID of the URL of an original or a variant.
+func mediaID(url string) (int64, bool) {
+	if id, ok := ID(url); ok {
+		return id, true
+	}
+	rest, ok := strings.CutPrefix(url, "/media/")
+	if !ok {
+		return 0, false
+	}
+	idPart, file, ok := strings.Cut(rest, "/")
+	if !ok || file == "" || strings.Contains(file, "/") {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	return id, err == nil && id > 0
+}
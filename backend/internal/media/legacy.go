@@ -0,0 +1,84 @@
+package media
+
+import (
+	"backend/internal/db/queries"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportLegacyUploads moves files uploaded before the media store into it and
+// points the rows using them at their new URLs. The files are left in
+// uploads/, so the migration can be rolled back. Rows whose file is gone keep
+// their old path. It runs at every start and only finds rows not yet imported.
+func ImportLegacyUploads() error {
+	uploads, err := queries.GetLegacyUploads()
+	if err != nil {
+		return err
+	}
+
+	imported, missing := 0, 0
+	for _, u := range uploads {
+		err := importLegacyUpload(u)
+		if errors.Is(err, os.ErrNotExist) {
+			missing++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("import %s: %w", u.Path, err)
+		}
+		imported++
+	}
+	if imported > 0 || missing > 0 {
+		fmt.Printf("media: imported %d legacy upload(s), %d missing\n", imported, missing)
+	}
+	return nil
+}
+
+func importLegacyUpload(u queries.LegacyUpload) error {
+	name := filepath.Clean(strings.TrimPrefix(u.Path, "/"))
+	if !strings.HasPrefix(filepath.ToSlash(name), "uploads/") {
+		return os.ErrNotExist
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	m, _, err := save(f, info.Size(), legacyType(f, name), u.OwnerID, u.Path)
+	if err != nil {
+		return err
+	}
+	replaced, err := queries.ReplaceLegacyUpload(u, m.URL)
+	if err != nil || !replaced {
+		Release(m.URL)
+	}
+	return err
+}
+
+// legacyType sniffs the type of an old upload like SaveUploadedFile does,
+// falling back to its extension.
+func legacyType(f *os.File, name string) string {
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(f, sniff)
+	f.Seek(0, io.SeekStart)
+	if contentType := http.DetectContentType(sniff[:n]); len(extensions[contentType]) > 0 {
+		return contentType
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for mimeType := range extensions {
+		if hasExtension(mimeType, ext) {
+			return mimeType
+		}
+	}
+	return "application/octet-stream"
+}
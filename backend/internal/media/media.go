@@ -0,0 +1,517 @@
+// Package media keeps uploaded files. Every upload gets a media record with its
+// owner, type, size and dimensions, and is served at /media/{id}{ext} to the
+// users who may see what it belongs to (see policy.CanViewMedia). Images
+// are stripped of their metadata and get scaled-down variants, served at
+// /media/{id}/{variant}{ext}; videos are checked, bounded in length and get a
+// poster image. Content is stored once per distinct file in the media store,
+// under the SHA-256 of its bytes, and deleted when the last record using it is
+// released.
+package media
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/imaging"
+	"backend/internal/models"
+	"backend/internal/scheduler"
+	"backend/internal/storage"
+	"backend/internal/video"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cleanupJob = "media_cleanup"
+	// unreferencedAfter is how long an upload may stay unused before it is
+	// deleted, e.g. when the post it was sent with failed to save
+	unreferencedAfter = 24 * time.Hour
+)
+
+var store storage.MediaStore
+
+// Init opens the media store configured by MEDIA_STORE. It must be called
+// before anything is saved or served.
+func Init() error {
+	s, err := storage.FromEnv()
+	if err != nil {
+		return err
+	}
+	store = s
+	return nil
+}
+
+// Register installs the cleanup of unused uploads. It must be called before scheduler.Run.
+func Register() {
+	scheduler.RegisterPeriodic(cleanupJob, 6*time.Hour, runCleanup)
+}
+
+// allowedTypes lists the file types each upload destination accepts.
+var allowedTypes = map[string]map[string]bool{
+	"avatars": {
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/webp": true,
+		"image/gif":  true,
+	},
+	"groups": {
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/webp": true,
+		"image/gif":  true,
+	},
+	"posts": {
+		"image/jpeg":      true,
+		"image/png":       true,
+		"image/webp":      true,
+		"image/gif":       true,
+		"video/mp4":       true,
+		"video/webm":      true,
+		"video/quicktime": true,
+	},
+}
+
+// extensions lists the file extensions of each type. The first one is used in URLs.
+var extensions = map[string][]string{
+	"image/jpeg":      {".jpg", ".jpeg"},
+	"image/png":       {".png"},
+	"image/webp":      {".webp"},
+	"image/gif":       {".gif"},
+	"video/mp4":       {".mp4"},
+	"video/webm":      {".webm"},
+	"video/quicktime": {".mov", ".qt"},
+}
+
+func hasExtension(mimeType, ext string) bool {
+	for _, e := range extensions[mimeType] {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// URL returns where a media record is served. The extension lets clients tell
+// images from videos.
+func URL(id int64, mimeType string) string {
+	ext := ".bin"
+	if exts := extensions[mimeType]; len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("/media/%d%s", id, ext)
+}
+
+// VariantURL returns where a variant of a media record is served.
+func VariantURL(mediaID int64, name, mimeType string) string {
+	return fmt.Sprintf("/media/%d/%s%s", mediaID, name, extensions[mimeType][0])
+}
+
+// ID returns the media ID of a URL made by URL. It reports false for anything
+// else, such as files uploaded before the media store.
+func ID(url string) (int64, bool) {
+	name, ok := strings.CutPrefix(url, "/media/")
+	if !ok {
+		return 0, false
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	id, err := strconv.ParseInt(name, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// sizes are the variants made of uploaded images, largest first.
+var sizes = []imaging.Size{
+	{Name: "medium", MaxSide: 1280},
+	{Name: "thumbnail", MaxSide: 320},
+}
+
+// blobKey is where the content with the given hash lives in the store.
+func blobKey(hash string) string {
+	return hash[:2] + "/" + hash
+}
+
+// SaveUploadedFile checks an uploaded file against what the destination
+// ("avatars", "groups" or "posts") accepts, stores it for ownerID and returns
+// its URLs. Images are stripped of their metadata and get medium and thumbnail
+// copies; videos get a poster image and its thumbnail. ownerID may be 0 when
+// the owner does not exist yet; see SetOwner. Errors are meant for the uploader.
+func SaveUploadedFile(file multipart.File, header *multipart.FileHeader, destination string, ownerID int) (models.MediaURLs, error) {
+	allowed, ok := allowedTypes[destination]
+	if !ok {
+		return models.MediaURLs{}, errors.New("invalid upload destination")
+	}
+
+	// Validate the real file type from the file bytes instead of trusting the client header.
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return models.MediaURLs{}, errors.New("failed to read uploaded file")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return models.MediaURLs{}, errors.New("failed to process uploaded file")
+	}
+
+	contentType := http.DetectContentType(sniff[:n])
+	if !allowed[contentType] {
+		if destination == "posts" {
+			return models.MediaURLs{}, errors.New("file must be a JPEG, PNG, WebP, GIF, MP4, WebM, or MOV")
+		}
+		return models.MediaURLs{}, errors.New("file must be a JPEG, PNG, WebP, or GIF image")
+	}
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext == "" || !hasExtension(contentType, ext) {
+		return models.MediaURLs{}, errors.New("file extension does not match the uploaded file type")
+	}
+
+	// Enforce size limits: 10 MB for images/GIFs, 25 MB for videos
+	isVideo := strings.HasPrefix(contentType, "video/")
+	var maxSize int64
+	if isVideo {
+		maxSize = 25 << 20 // 25 MB
+	} else {
+		maxSize = 10 << 20 // 10 MB
+	}
+	if header.Size > maxSize {
+		if isVideo {
+			return models.MediaURLs{}, errors.New("video must be at most 25 MB")
+		}
+		return models.MediaURLs{}, errors.New("image/GIF must be at most 10 MB")
+	}
+
+	m, variants, err := save(file, header.Size, contentType, ownerID, "")
+	if uploaderError(err) {
+		return models.MediaURLs{}, err
+	}
+	if err != nil {
+		fmt.Printf("media: failed to save upload of user %d: %v\n", ownerID, err)
+		return models.MediaURLs{}, errors.New("failed to save file")
+	}
+	return urls(m, variants), nil
+}
+
+// uploaderError reports whether an error of save is about the uploaded file
+// itself, and can be shown to the uploader.
+func uploaderError(err error) bool {
+	for _, target := range []error{imaging.ErrInvalid, imaging.ErrTooLarge, video.ErrInvalid, video.ErrNoVideo, errVideoTooLong} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// blob is content to store under its hash.
+type blob struct {
+	r        io.ReadSeeker
+	size     int64
+	mimeType string
+}
+
+func (b blob) hash() (string, error) {
+	if _, err := b.r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, b.r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// put stores the blob unless the store already has it.
+func (b blob) put(hash string) error {
+	key := blobKey(hash)
+	exists, err := store.Exists(key)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := b.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return store.Put(key, b.r, b.size, b.mimeType)
+}
+
+// processed is an upload ready to store: its content, possibly rewritten, what
+// it shows and its variants.
+type processed struct {
+	data       []byte
+	mimeType   string
+	width      int
+	height     int
+	duration   time.Duration
+	videoCodec string
+	audioCodec string
+	variants   []imaging.Variant
+}
+
+func processImage(data []byte, mimeType string) (processed, error) {
+	res, err := imaging.Process(data, mimeType, sizes)
+	if err != nil {
+		return processed{}, err
+	}
+	return processed{data: res.Data, mimeType: res.MimeType, width: res.Width, height: res.Height, variants: res.Variants}, nil
+}
+
+// save processes content read from r, stores it with its variants and records
+// it. Images and videos that can't be processed are rejected, except imported
+// ones, which are kept as they are.
+func save(r io.ReadSeeker, size int64, mimeType string, ownerID int, legacyPath string) (models.Media, []models.MediaVariant, error) {
+	m := models.Media{MimeType: mimeType, SizeBytes: size}
+	original := blob{r: r, size: size, mimeType: mimeType}
+	var derived []imaging.Variant
+
+	process := processImage
+	if strings.HasPrefix(mimeType, "video/") {
+		process = processVideo
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return models.Media{}, nil, err
+	}
+	p, err := process(data, mimeType)
+	switch {
+	case err == nil:
+		original = blob{r: bytes.NewReader(p.data), size: int64(len(p.data)), mimeType: p.mimeType}
+		m.MimeType, m.SizeBytes = p.mimeType, original.size
+		m.Width, m.Height = &p.width, &p.height
+		if strings.HasPrefix(p.mimeType, "video/") {
+			ms := p.duration.Milliseconds()
+			m.DurationMS, m.VideoCodec, m.AudioCodec = &ms, p.videoCodec, p.audioCodec
+		}
+		derived = p.variants
+	case legacyPath != "":
+		fmt.Printf("media: importing %s unprocessed: %v\n", legacyPath, err)
+	default:
+		return models.Media{}, nil, err
+	}
+
+	if ownerID != 0 {
+		m.OwnerID = &ownerID
+	}
+	hash, err := original.hash()
+	if err != nil {
+		return m, nil, err
+	}
+	m.Hash = hash
+	blobs := map[string]blob{hash: original}
+
+	variants := make([]models.MediaVariant, 0, len(derived))
+	for _, v := range derived {
+		b := blob{r: bytes.NewReader(v.Data), size: int64(len(v.Data)), mimeType: v.MimeType}
+		hash, err := b.hash()
+		if err != nil {
+			return m, nil, err
+		}
+		blobs[hash] = b
+		variants = append(variants, models.MediaVariant{
+			Name: v.Name, Hash: hash, MimeType: v.MimeType, SizeBytes: b.size, Width: v.Width, Height: v.Height,
+		})
+	}
+
+	// Identical content is uploaded once. The blobs stay locked until our
+	// references are taken, so a concurrent release cannot delete them in between
+	hashes := make([]string, 0, len(blobs))
+	for hash := range blobs {
+		hashes = append(hashes, hash)
+	}
+	unlock := lockBlobs(hashes)
+	defer unlock()
+	for hash, b := range blobs {
+		if err := b.put(hash); err != nil {
+			return m, nil, err
+		}
+	}
+	id, err := queries.AddMedia(m, variants, legacyPath)
+	if err != nil {
+		return m, nil, err
+	}
+	m.ID = id
+	m.URL = URL(id, m.MimeType)
+	for i := range variants {
+		variants[i].URL = VariantURL(id, variants[i].Name, variants[i].MimeType)
+	}
+	return m, variants, nil
+}
+
+// urls returns the URL of each size of a media record.
+func urls(m models.Media, variants []models.MediaVariant) models.MediaURLs {
+	u := models.MediaURLs{Original: m.URL, Medium: m.URL, Thumbnail: m.URL}
+	for _, v := range variants {
+		switch v.Name {
+		case "medium":
+			u.Medium = v.URL
+		case "thumbnail":
+			u.Thumbnail = v.URL
+		case "poster":
+			u.Poster = v.URL
+		}
+	}
+	// A thumbnail is never larger than the medium size, and a video's is
+	// never the video
+	if u.Medium != m.URL && u.Thumbnail == m.URL {
+		u.Thumbnail = u.Medium
+	}
+	if u.Poster != "" && u.Thumbnail == m.URL {
+		u.Thumbnail = u.Poster
+	}
+	return u
+}
+
+// Open returns the media record behind a URL and the content of its original.
+// It returns sql.ErrNoRows for unknown media and storage.ErrNotFound for lost
+// content.
+func Open(url string) (models.Media, storage.Object, error) {
+	id, ok := ID(url)
+	if !ok {
+		return models.Media{}, nil, storage.ErrNotFound
+	}
+	m, err := queries.GetMedia(id)
+	if err != nil {
+		return m, nil, err
+	}
+	m.URL = URL(m.ID, m.MimeType)
+	obj, err := store.Open(blobKey(m.Hash))
+	if err != nil {
+		return m, nil, err
+	}
+	return m, obj, nil
+}
+
+// OpenVariant returns a variant of a media record and its content. It returns
+// sql.ErrNoRows if there is no such variant.
+func OpenVariant(mediaID int64, name string) (models.MediaVariant, storage.Object, error) {
+	variants, err := queries.GetMediaVariants(mediaID)
+	if err != nil {
+		return models.MediaVariant{}, nil, err
+	}
+	for _, v := range variants {
+		if v.Name != name {
+			continue
+		}
+		v.URL = VariantURL(mediaID, v.Name, v.MimeType)
+		obj, err := store.Open(blobKey(v.Hash))
+		if err != nil {
+			return v, nil, err
+		}
+		return v, obj, nil
+	}
+	return models.MediaVariant{}, nil, sql.ErrNoRows
+}
+
+// SetOwner sets the owner of media saved without one.
+func SetOwner(url string, ownerID int) {
+	if id, ok := ID(url); ok {
+		if err := queries.SetMediaOwner(id, ownerID); err != nil {
+			fmt.Printf("media: failed to set owner of %s: %v\n", url, err)
+		}
+	}
+}
+
+// Release deletes the media behind a URL that is no longer used, and its
+// content if no other media shares it. Other URLs are ignored.
+// Failures are logged. A record that failed to delete is left for the cleanup
+// job; content that failed to delete stays in the store unreferenced.
+func Release(url string) {
+	id, ok := ID(url)
+	if !ok {
+		return
+	}
+	unused, err := queries.DeleteMedia(id)
+	if err != nil {
+		fmt.Printf("media: failed to release %s: %v\n", url, err)
+		return
+	}
+	for _, hash := range unused {
+		if err := deleteBlob(hash); err != nil {
+			fmt.Printf("media: failed to delete content %s of %s: %v\n", hash, url, err)
+		}
+	}
+}
+
+// deleteBlob deletes the content of an unreferenced blob from the media store,
+// unless an upload took a new reference on it since it was released.
+func deleteBlob(hash string) error {
+	unlock := lockBlobs([]string{hash})
+	defer unlock()
+	used, err := queries.IsBlobReferenced(hash)
+	if err != nil || used {
+		return err
+	}
+	return store.Delete(blobKey(hash))
+}
+
+// blobLocks holds a lock for each blob being stored or deleted. Store I/O runs
+// outside database transactions, so these keep an upload reusing a blob and a
+// release deleting it from interleaving.
+var blobLocks = struct {
+	sync.Mutex
+	held map[string]*blobLock
+}{held: map[string]*blobLock{}}
+
+type blobLock struct {
+	sync.Mutex
+	users int
+}
+
+// lockBlobs locks the blobs of the given hashes, in order so that two callers
+// cannot deadlock, and returns the function that unlocks them.
+func lockBlobs(hashes []string) (unlock func()) {
+	hashes = append([]string(nil), hashes...)
+	sort.Strings(hashes)
+
+	locks := make([]*blobLock, len(hashes))
+	blobLocks.Lock()
+	for i, hash := range hashes {
+		l := blobLocks.held[hash]
+		if l == nil {
+			l = &blobLock{}
+			blobLocks.held[hash] = l
+		}
+		l.users++
+		locks[i] = l
+	}
+	blobLocks.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+	return func() {
+		blobLocks.Lock()
+		defer blobLocks.Unlock()
+		for i, l := range locks {
+			l.Unlock()
+			if l.users--; l.users == 0 {
+				delete(blobLocks.held, hashes[i])
+			}
+		}
+	}
+}
+
+// runCleanup deletes media that nothing has referred to for a while.
+func runCleanup([]byte) error {
+	ids, err := queries.GetUnreferencedMedia(time.Now().Add(-unreferencedAfter))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		Release(URL(id, ""))
+	}
+	if len(ids) > 0 {
+		fmt.Printf("media: deleted %d unused upload(s)\n", len(ids))
+	}
+	return nil
+}
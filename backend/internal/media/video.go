@@ -0,0 +1,72 @@
+package media
+
+import (
+	"backend/internal/imaging"
+	"backend/internal/video"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultMaxVideoDuration = 3 * time.Minute
+
+var errVideoTooLong = errors.New("video is too long")
+
+// posterSize bounds the poster image of a video.
+const posterSize = 1280
+
+// posterThumbnail is the variant made of the poster, named like the thumbnails
+// of images so clients show both alike.
+var posterThumbnail = imaging.Size{Name: "thumbnail", MaxSide: 320}
+
+// posters makes the poster images of videos. There is no video decoder in
+// pure Go, so a placeholder of the video's proportions stands in for its first
+// frame; a PosterExtractor that decodes frames can replace it here.
+var posters video.PosterExtractor = video.Placeholder{MaxSide: posterSize}
+
+// maxVideoDuration is how long an uploaded video may be (VIDEO_MAX_DURATION).
+func maxVideoDuration() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("VIDEO_MAX_DURATION")); err == nil && d > 0 {
+		return d
+	}
+	return defaultMaxVideoDuration
+}
+
+// processVideo checks a video, moves the index of MP4 and QuickTime files to
+// their start so they play while downloading, and makes its poster image. A
+// video whose poster fails is kept without one.
+func processVideo(data []byte, mimeType string) (processed, error) {
+	info, err := video.Probe(data, mimeType)
+	if err != nil {
+		return processed{}, err
+	}
+	if limit := maxVideoDuration(); info.Duration > limit {
+		return processed{}, fmt.Errorf("%w (at most %s)", errVideoTooLong, limit)
+	}
+	if mimeType != "video/webm" {
+		if data, err = video.FastStart(data); err != nil {
+			return processed{}, err
+		}
+	}
+
+	p := processed{
+		data: data, mimeType: mimeType, width: info.Width, height: info.Height,
+		duration: info.Duration, videoCodec: info.Codec, audioCodec: info.AudioCodec,
+	}
+	frame, err := posters.Poster(data, info)
+	if err != nil {
+		fmt.Printf("media: failed to make a poster image: %v\n", err)
+		return p, nil
+	}
+	poster, err := imaging.Encode("poster", frame)
+	if err != nil {
+		return processed{}, err
+	}
+	thumbnails, err := imaging.Variants(frame, []imaging.Size{posterThumbnail})
+	if err != nil {
+		return processed{}, err
+	}
+	p.variants = append([]imaging.Variant{poster}, thumbnails...)
+	return p, nil
+}
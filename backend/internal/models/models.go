@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -107,29 +108,77 @@ type GroupsResponse struct {
 }
 
 type Post struct {
-	ID        int64  `json:"id"`
-	UserID    int    `json:"user_id"`
-	GroupID   *int64 `json:"group_id,omitempty"`
-	Content   string `json:"content"`
-	ImagePath string `json:"image_path,omitempty"`
-	Privacy   string `json:"privacy,omitempty"`
-	CreatedAt string `json:"created_at"`
+	ID          int64            `json:"id"`
+	UserID      int              `json:"user_id"`
+	GroupID     *int64           `json:"group_id,omitempty"`
+	Content     string           `json:"content"`
+	ImagePath   string           `json:"image_path,omitempty"` // URL of the first attachment, for clients that show one
+	Attachments []PostAttachment `json:"attachments"`
+	Privacy     string           `json:"privacy,omitempty"`
+	CreatedAt   string           `json:"created_at"`
+	EditedAt    *string          `json:"edited_at"` // last edit, nil if never edited
+	Edited      bool             `json:"edited"`
+	// AudienceListID is the audience list a "selected" post is shared with,
+	// shown to its author only
+	AudienceListID *int64 `json:"audience_list_id,omitempty"`
+}
+
+// AudienceList is a named list of followers a user shares posts with, such as
+// "close friends". Only its owner sees it.
+type AudienceList struct {
+	ID          int64  `json:"id"`
+	OwnerID     int    `json:"-"`
+	Name        string `json:"name"`
+	MemberCount int    `json:"member_count"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// PostRevision is a text a post had. EditedAt is nil for the text it was
+// posted with.
+type PostRevision struct {
+	ID       int64   `json:"id"`
+	Content  string  `json:"content"`
+	EditedAt *string `json:"edited_at"`
+}
+
+// PostAttachment is an image or video of a post. Attachments are listed in
+// the order they are shown.
+type PostAttachment struct {
+	ID      int64     `json:"id"`
+	URL     string    `json:"url"`
+	AltText string    `json:"alt_text"`
+	Sizes   MediaURLs `json:"sizes"`
+}
+
+// PostWithMeta is a post enriched with its author and engagement counters,
+// as returned by the feed, profile and group post listings.
+type PostWithMeta struct {
+	Post
+	Author        *User `json:"author"`
+	Likes         int   `json:"likes"`
+	IsLiked       bool  `json:"is_liked"`
+	CommentsCount int   `json:"comments_count"`
 }
 
 type Event struct {
-	ID            int64       `json:"id"`
-	GroupID       int64       `json:"group_id"`
-	Title         string      `json:"title"`
-	Description   string      `json:"description,omitempty"`
-	ImagePath     string      `json:"image_path,omitempty"`
-	StartTime     string      `json:"start_time"`
-	EndTime       string      `json:"end_time"`
-	CreatedAt     string      `json:"created_at"`
-	GoingCount    int         `json:"going_count"`
-	NotGoingCount int         `json:"not_going_count"`
-	UserResponse  string      `json:"user_response,omitempty"` // "going", "not-going", or empty
-	CreatorID     int64       `json:"creator_id"`
-	Creator       *UserPublic `json:"creator,omitempty"`
+	ID             int64       `json:"id"`
+	GroupID        int64       `json:"group_id"`
+	Title          string      `json:"title"`
+	Description    string      `json:"description,omitempty"`
+	ImagePath      string      `json:"image_path,omitempty"`
+	RecurrenceRule string      `json:"recurrence_rule,omitempty"` // RRULE value for repeating events, e.g. "FREQ=WEEKLY;BYDAY=MO"
+	StartTime      string      `json:"start_time"`
+	EndTime        string      `json:"end_time"` // empty when the event has no end time
+	Location       string      `json:"location,omitempty"`
+	Capacity       int         `json:"capacity,omitempty"` // 0 = unlimited
+	CreatedAt      string      `json:"created_at"`
+	GoingCount     int         `json:"going_count"`
+	NotGoingCount  int         `json:"not_going_count"`
+	MaybeCount     int         `json:"maybe_count"`
+	WaitlistCount  int         `json:"waitlist_count"`
+	UserResponse   string      `json:"user_response,omitempty"` // "going", "not-going", "maybe", "waitlisted", or empty
+	CreatorID      int64       `json:"creator_id"`
+	Creator        *UserPublic `json:"creator,omitempty"`
 }
 
 type GroupInfo struct {
@@ -194,6 +243,265 @@ type NotificationEventMessage struct {
 	Event NotificationEvent `json:"event"`
 }
 
+// NotificationActor is the user who triggered a notification
+type NotificationActor struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Avatar    *string `json:"avatar"`
+	Username  *string `json:"username"`
+}
+
+// Notification is an inbox entry. Notifications sharing a group key collapse into
+// their most recent one; Count, ActorCount and Actors describe the whole group.
+type Notification struct {
+	ID         int64               `json:"id"`
+	ActorID    *int                `json:"actor_id"`
+	Type       string              `json:"type"`
+	Data       string              `json:"data"`
+	Read       int                 `json:"read"` // 0 = unread, 1 = read
+	CreatedAt  string              `json:"created_at"`
+	Actor      NotificationActor   `json:"actor"`
+	GroupKey   string              `json:"group_key,omitempty"`
+	Count      int                 `json:"count"`
+	ActorCount int                 `json:"actor_count"`
+	Actors     []NotificationActor `json:"actors,omitempty"`  // most recent distinct actors of a group
+	Summary    string              `json:"summary,omitempty"` // e.g. "@alice and 4 others are now following you"
+}
+
+// PushSubscription is a browser's Web Push subscription. The keys are only used
+// to encrypt payloads and are never sent back to clients.
+type PushSubscription struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"-"`
+	Endpoint   string     `json:"endpoint"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Webhook is an outgoing webhook of a user account (GroupID nil) or of a group.
+// Secret is only returned when the webhook is created or its secret rotated.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"user_id"`
+	GroupID   *int64    `json:"group_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent to a webhook, with the outcome of its last attempt.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // "pending", "delivered", "failed"
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	ResponseBody   *string         `json:"response_body"`
+	LastError      *string         `json:"last_error"`
+	DurationMs     *int64          `json:"duration_ms"`
+	CreatedAt      time.Time       `json:"created_at"`
+	FinishedAt     *time.Time      `json:"finished_at"`
+}
+
+// Site roles, independent of group ownership
+const (
+	SiteRoleUser  = "user"
+	SiteRoleAdmin = "admin"
+)
+
+// Suspension describes a suspended account. Until is nil for indefinite suspensions.
+type Suspension struct {
+	SuspendedAt time.Time  `json:"suspended_at"`
+	Until       *time.Time `json:"until"`
+	Reason      string     `json:"reason"`
+}
+
+// Message is the error shown to a suspended user.
+func (s Suspension) Message() string {
+	message := "Your account is suspended"
+	if s.Until != nil {
+		message += " until " + s.Until.Format("2 Jan 2006 15:04 MST")
+	}
+	if s.Reason != "" {
+		message += ": " + s.Reason
+	}
+	return message
+}
+
+// AdminUser is a user account as shown in the site admin tools.
+type AdminUser struct {
+	ID         int         `json:"id"`
+	Username   string      `json:"username"`
+	Email      string      `json:"email"`
+	FirstName  string      `json:"first_name"`
+	LastName   string      `json:"last_name"`
+	Avatar     string      `json:"avatar"`
+	SiteRole   string      `json:"site_role"`
+	IsVerified bool        `json:"is_verified"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Suspension *Suspension `json:"suspension"` // nil unless currently suspended
+	// PendingReports counts the open or triaged reports about the user or their content
+	PendingReports int `json:"pending_reports"`
+}
+
+// UserContentItem is a post, comment or group chat message listed in the site admin tools.
+type UserContentItem struct {
+	Type        string   `json:"type"` // "post", "comment" or "group_message"
+	ID          int64    `json:"id"`
+	Content     string   `json:"content"`
+	Attachments []string `json:"attachments,omitempty"`
+	Privacy     string   `json:"privacy,omitempty"`
+	PostID      *int64   `json:"post_id,omitempty"`
+	GroupID     *int64   `json:"group_id,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// Kinds of content that can be reported
+var ReportTargetTypes = []string{"post", "comment", "private_message", "group_message", "user"}
+
+// Reasons a report can be filed for
+var ReportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "misinformation", "other"}
+
+// Report is a user's report of abusive content or of a profile. ReporterID is
+// nil for content flagged by the content filter.
+// ContentSnapshot keeps the reported content even after it is removed.
+type Report struct {
+	ID              int64      `json:"id"`
+	ReporterID      *int       `json:"reporter_id"`
+	TargetType      string     `json:"target_type"`
+	TargetID        int64      `json:"target_id"`
+	TargetUserID    *int       `json:"target_user_id"`
+	Reason          string     `json:"reason"`
+	Details         string     `json:"details"`
+	ContentSnapshot string     `json:"content_snapshot,omitempty"`
+	Status          string     `json:"status"` // "open", "triaged", "resolved", "dismissed"
+	AssigneeID      *int       `json:"assignee_id"`
+	Resolution      *string    `json:"resolution"`
+	ResolutionNote  *string    `json:"resolution_note,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	// PendingReports counts the open or triaged reports of the same target (admin listing only)
+	PendingReports int `json:"pending_reports,omitempty"`
+}
+
+// ModerationLogEntry is one entry of the append-only moderation audit log.
+type ModerationLogEntry struct {
+	ID         int64           `json:"id"`
+	AdminID    *int            `json:"admin_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	ReportID   *int64          `json:"report_id"`
+	Details    json.RawMessage `json:"details"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Notification channels a user can opt out of per notification type
+const (
+	ChannelInApp = "in_app" // stored in the notification list
+	ChannelPush  = "push"   // pushed live over the WebSocket
+	ChannelEmail = "email"  // included in the email digest
+)
+
+var NotificationChannels = []string{ChannelInApp, ChannelPush, ChannelEmail}
+
+// DataExport is an archive of a user's personal data, built in the background.
+// DownloadURL is set while the archive is ready.
+type DataExport struct {
+	ID          int64      `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"` // "pending", "running", "ready", "failed", "expired"
+	FilePath    string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// Media is an uploaded file. Its content lives in the media store under its
+// hash and is served at URL.
+type Media struct {
+	ID         int64     `json:"id"`
+	OwnerID    *int      `json:"owner_id"`
+	Hash       string    `json:"-"`
+	MimeType   string    `json:"mime_type"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Width      *int      `json:"width"`
+	Height     *int      `json:"height"`
+	DurationMS *int64    `json:"duration_ms,omitempty"` // videos only, like the codecs
+	VideoCodec string    `json:"video_codec,omitempty"`
+	AudioCodec string    `json:"audio_codec,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	URL        string    `json:"url"`
+}
+
+// MediaVariant is a scaled-down copy of an image, e.g. its thumbnail, or the
+// poster image of a video.
+type MediaVariant struct {
+	Name      string `json:"name"`
+	Hash      string `json:"-"`
+	MimeType  string `json:"mime_type"`
+	SizeBytes int64  `json:"size_bytes"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	URL       string `json:"url"`
+}
+
+// MediaURLs are the URLs of an upload in each size. Sizes the upload has no
+// copy in, such as an image already smaller than a thumbnail, point at the
+// original. The thumbnail of a video is a small copy of its poster image.
+type MediaURLs struct {
+	Original  string `json:"original"`
+	Medium    string `json:"medium"`
+	Thumbnail string `json:"thumbnail"`
+	Poster    string `json:"poster,omitempty"` // videos only
+}
+
+// NotificationTypes lists the notification types users can configure
+var NotificationTypes = []string{
+	"follow_request",
+	"follow_update",
+	"group_invitation",
+	"group_join_request",
+	"join_request_approved",
+	"join_request_rejected",
+	"new_message",
+	"event_reminder",
+	"event_updated",
+	"event_waitlist_promoted",
+	"report_update",
+	"data_export",
+	"group_ownership",
+}
+
+// NotificationPreference holds the enabled channels of one notification type
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Push  bool   `json:"push"`
+	Email bool   `json:"email"`
+}
+
+// NotificationMute silences a group or conversation until MutedUntil (nil means indefinitely)
+type NotificationMute struct {
+	TargetType string     `json:"target_type"` // "group" or "conversation"
+	TargetID   int64      `json:"target_id"`
+	MutedUntil *time.Time `json:"muted_until"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
 type OnlineUserData struct {
 	UserID    int    `json:"userId"`
 	Username  string `json:"username,omitempty"`
@@ -217,29 +525,66 @@ type EventRequest struct {
 
 // GroupEvent represents an event created in a group
 type GroupEvent struct {
-	ID          int64     `json:"id"`
-	GroupID     int64     `json:"groupId"`
-	CreatorID   int64     `json:"creatorId"`
-	Title       string    `json:"title"`
-	Description string    `json:"description,omitempty"`
-	EventTime   time.Time `json:"eventTime"` // combined date + time
-	CoverImage  string    `json:"coverImage,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID             int64      `json:"id"`
+	GroupID        int64      `json:"groupId"`
+	CreatorID      int64      `json:"creatorId"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description,omitempty"`
+	EventTime      time.Time  `json:"eventTime"`         // combined date + time
+	EndTime        *time.Time `json:"endTime,omitempty"` // nil when the event has no end time
+	Location       string     `json:"location,omitempty"`
+	Capacity       int        `json:"capacity,omitempty"` // 0 = unlimited
+	CoverImage     string     `json:"coverImage,omitempty"`
+	RecurrenceRule string     `json:"recurrenceRule,omitempty"` // empty for single events
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// CreateGroupEventParams holds the columns of a new group event.
+// EndDate/EndTime, Location and RecurrenceRule may be empty and Capacity 0 (unlimited).
+type CreateGroupEventParams struct {
+	GroupID        int64
+	CreatorID      int64
+	Title          string
+	Description    string
+	EventDate      string
+	EventTime      string
+	EndDate        string
+	EndTime        string
+	Location       string
+	Capacity       int
+	ImagePath      string
+	RecurrenceRule string
+}
+
+// UpdateGroupEventParams holds the editable columns of a group event.
+// Empty optional fields are stored as NULL and Capacity 0 means unlimited.
+type UpdateGroupEventParams struct {
+	Title       string
+	Description string
+	EventDate   string
+	EventTime   string
+	EndDate     string
+	EndTime     string
+	Location    string
+	Capacity    int
+	ImagePath   string
 }
 
 // EventResponse represents a user's response to an event
 type EventResponse struct {
 	EventID   int64     `json:"eventId"`
 	UserID    int64     `json:"userId"`
-	Response  string    `json:"response"` // "going" or "not-going"
+	Response  string    `json:"response"` // "going", "not-going", "maybe" or "waitlisted"
 	CreatedAt time.Time `json:"createdAt"`
 }
 
 // Optional: Counts for frontend UI
 type EventResponseCounts struct {
-	EventID  int64 `json:"eventId"`
-	Going    int   `json:"going"`
-	NotGoing int   `json:"notGoing"`
+	EventID    int64 `json:"eventId"`
+	Going      int   `json:"going"`
+	NotGoing   int   `json:"notGoing"`
+	Maybe      int   `json:"maybe"`
+	Waitlisted int   `json:"waitlisted"`
 }
 
 type GroupChatMessage struct {
@@ -0,0 +1,331 @@
+package moderation
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endpoints of this file are routed through the site admin middleware.
+
+// parsePage reads the limit and cursor query parameters of listings.
+func parsePage(w http.ResponseWriter, r *http.Request) (limit int, cursor int64, ok bool) {
+	limit = 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		c, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || c < 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid cursor"})
+			return 0, 0, false
+		}
+		cursor = c
+	}
+	return limit, cursor, true
+}
+
+// loadReport parses the {id} path value and loads the report.
+func loadReport(w http.ResponseWriter, r *http.Request) (models.Report, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid report ID"})
+		return models.Report{}, false
+	}
+	report, err := queries.GetReport(id)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Report not found"})
+		return report, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch report"})
+		return report, false
+	}
+	return report, true
+}
+
+// ListReports handles GET /api/admin/reports?status=pending&target_type=&reason=&cursor=&limit=
+// status is "pending" (default: open or triaged), "all", or a single status.
+func ListReports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	filter := queries.ReportFilter{
+		Status:     q.Get("status"),
+		TargetType: q.Get("target_type"),
+		Reason:     q.Get("reason"),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = "pending"
+	case "all":
+		filter.Status = ""
+	case "pending", "open", "triaged", "resolved", "dismissed":
+	default:
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid status"})
+		return
+	}
+	if filter.TargetType != "" && !contains(models.ReportTargetTypes, filter.TargetType) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid target type"})
+		return
+	}
+	if filter.Reason != "" && !contains(models.ReportReasons, filter.Reason) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid reason"})
+		return
+	}
+
+	limit, cursor, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	filter.Before = cursor
+
+	reports, err := queries.ListReports(filter, limit)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch reports"})
+		return
+	}
+	var next int64
+	if len(reports) == limit {
+		next = reports[len(reports)-1].ID
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"reports":     reports,
+		"next_cursor": next,
+	})
+}
+
+// GetReport handles GET /api/admin/reports/{id}: the report, the reported content
+// as it is now, the other reports of the same target and the moderation history.
+func GetReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report, ok := loadReport(w, r)
+	if !ok {
+		return
+	}
+
+	target := map[string]interface{}{"exists": false}
+	current, err := queries.GetReportTarget(report.TargetType, report.TargetID)
+	if err == nil {
+		target = map[string]interface{}{
+			"exists":    true,
+			"author_id": current.AuthorID,
+			"content":   current.Content,
+		}
+		if suspension, err := queries.GetActiveSuspension(current.AuthorID); err == nil && suspension != nil {
+			target["author_suspended_until"] = suspension.Until
+			target["author_suspended"] = true
+		}
+	} else if err != sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch reported content"})
+		return
+	}
+
+	related, err := queries.GetReportsByTarget(report.TargetType, report.TargetID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch report"})
+		return
+	}
+	history, err := queries.GetModerationLog(report.TargetType, report.TargetID, 0, 100)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch moderation history"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"report":          report,
+		"target":          target,
+		"related_reports": related,
+		"history":         history,
+	})
+}
+
+// TriageReport handles POST /api/admin/reports/{id}/triage: the admin takes the report.
+func TriageReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID, _ := utils.GetUserIDFromContext(r)
+	report, ok := loadReport(w, r)
+	if !ok {
+		return
+	}
+
+	triaged, err := queries.TriageReport(report.ID, adminID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to triage report"})
+		return
+	}
+	if !triaged {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "Only open reports can be triaged"})
+		return
+	}
+	if err := logAction(adminID, ActionReportTriaged, report.TargetType, report.TargetID, &report.ID, nil); err != nil {
+		fmt.Printf("moderation: failed to log triage of report %d: %v\n", report.ID, err)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Report triaged"})
+}
+
+// ResolveReport handles POST /api/admin/reports/{id}/resolve
+// Body: { "action": "remove_content", "note": "...", "suspend_for": "168h" }
+// The decision closes every pending report of the same target, and their
+// reporters are notified. suspend_for is empty for an indefinite suspension.
+func ResolveReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID, _ := utils.GetUserIDFromContext(r)
+	report, ok := loadReport(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Action     string `json:"action"`
+		Note       string `json:"note"`
+		SuspendFor string `json:"suspend_for"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	body.Note = strings.TrimSpace(body.Note)
+
+	if report.Status != "open" && report.Status != "triaged" {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "Report is already closed"})
+		return
+	}
+	if !contains(Resolutions, body.Action) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("action must be one of: %s", strings.Join(Resolutions, ", ")),
+		})
+		return
+	}
+
+	remove := body.Action == ResolutionRemoveContent || body.Action == ResolutionRemoveAndSuspend
+	suspend := body.Action == ResolutionSuspendUser || body.Action == ResolutionRemoveAndSuspend
+	if remove && report.TargetType == "user" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Profile reports have no content to remove"})
+		return
+	}
+
+	var until *time.Time
+	if suspend {
+		if report.TargetUserID == nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "The reported account no longer exists"})
+			return
+		}
+		if body.SuspendFor != "" {
+			d, err := time.ParseDuration(body.SuspendFor)
+			if err != nil || d <= 0 {
+				utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "suspend_for must be a positive duration such as \"72h\""})
+				return
+			}
+			t := time.Now().Add(d)
+			until = &t
+		}
+	}
+
+	if remove {
+		if _, err := RemoveContent(adminID, report.TargetType, report.TargetID, &report.ID, body.Note); err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to remove content"})
+			return
+		}
+	}
+	if suspend {
+		reason := body.Note
+		if reason == "" {
+			reason = "Reported for " + strings.ReplaceAll(report.Reason, "_", " ")
+		}
+		err := Suspend(adminID, *report.TargetUserID, reason, until, &report.ID)
+		if err == errSuspendAdmin {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Site admins can't be suspended"})
+			return
+		}
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to suspend user"})
+			return
+		}
+	}
+
+	status, action := "resolved", ActionReportResolved
+	if body.Action == ResolutionDismiss {
+		status, action = "dismissed", ActionReportDismissed
+	}
+	closed, err := queries.CloseTargetReports(report.TargetType, report.TargetID, adminID, status, body.Action, body.Note)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to close reports"})
+		return
+	}
+
+	reportIDs := make([]int64, 0, len(closed))
+	for _, c := range closed {
+		reportIDs = append(reportIDs, c.ID)
+	}
+	err = logAction(adminID, action, report.TargetType, report.TargetID, &report.ID, map[string]interface{}{
+		"resolution":     body.Action,
+		"note":           body.Note,
+		"closed_reports": reportIDs,
+	})
+	if err != nil {
+		fmt.Printf("moderation: failed to log resolution of report %d: %v\n", report.ID, err)
+	}
+	notifyReporters(closed, body.Action)
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":        true,
+		"message":        fmt.Sprintf("Closed %d report(s)", len(closed)),
+		"closed_reports": reportIDs,
+	})
+}
+
+// ListModerationLog handles GET /api/admin/moderation-log?target_type=&target_id=&cursor=&limit=
+func ListModerationLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	targetType := r.URL.Query().Get("target_type")
+	var targetID int64
+	if targetType != "" {
+		if !contains(models.ReportTargetTypes, targetType) {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid target type"})
+			return
+		}
+		id, err := strconv.ParseInt(r.URL.Query().Get("target_id"), 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "target_id is required with target_type"})
+			return
+		}
+		targetID = id
+	}
+
+	limit, cursor, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	entries, err := queries.GetModerationLog(targetType, targetID, cursor, limit)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch moderation log"})
+		return
+	}
+	var next int64
+	if len(entries) == limit {
+		next = entries[len(entries)-1].ID
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"entries":     entries,
+		"next_cursor": next,
+	})
+}
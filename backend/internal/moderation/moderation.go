@@ -0,0 +1,168 @@
+// Package moderation handles reports of abusive content and the actions site
+// admins take on them. Every action is recorded in the append-only moderation log.
+package moderation
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/media"
+	"backend/internal/models"
+	activity "backend/internal/notifications"
+	"backend/internal/ws"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the moderation log
+const (
+	ActionReportTriaged   = "report.triaged"
+	ActionReportResolved  = "report.resolved"
+	ActionReportDismissed = "report.dismissed"
+	ActionContentRemoved  = "content.removed"
+	ActionUserSuspended   = "user.suspended"
+	ActionUserUnsuspended = "user.unsuspended"
+	ActionUserLoggedOut   = "user.logged_out"
+	ActionRoleChanged     = "user.role_changed"
+)
+
+// Resolutions an admin picks when closing a report
+const (
+	ResolutionDismiss          = "dismiss"
+	ResolutionNoAction         = "no_action"
+	ResolutionRemoveContent    = "remove_content"
+	ResolutionSuspendUser      = "suspend_user"
+	ResolutionRemoveAndSuspend = "remove_and_suspend"
+)
+
+var Resolutions = []string{ResolutionDismiss, ResolutionNoAction, ResolutionRemoveContent, ResolutionSuspendUser, ResolutionRemoveAndSuspend}
+
+var errSuspendAdmin = errors.New("site admins can't be suspended")
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// logAction appends an entry to the moderation log. adminID 0 records a system action.
+func logAction(adminID int, action, targetType string, targetID int64, reportID *int64, details map[string]interface{}) error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	var admin *int
+	if adminID != 0 {
+		admin = &adminID
+	}
+	return queries.AddModerationLog(admin, action, targetType, targetID, reportID, string(b))
+}
+
+// RemoveContent deletes reported content and logs it. It reports false if the
+// content was already gone, which is logged too.
+func RemoveContent(adminID int, targetType string, targetID int64, reportID *int64, note string) (bool, error) {
+	var paths []string
+	if targetType == "post" {
+		paths, _ = queries.GetPostAttachmentPaths(targetID)
+	}
+
+	removed, err := queries.DeleteReportTarget(targetType, targetID)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		for _, path := range paths {
+			media.Release(path)
+		}
+	}
+
+	return removed, logAction(adminID, ActionContentRemoved, targetType, targetID, reportID, map[string]interface{}{
+		"note":            note,
+		"already_removed": !removed,
+	})
+}
+
+// Suspend suspends an account until the given time (nil for indefinitely), logs
+// it out of every session and socket, and logs the action. Site admins can't be suspended.
+func Suspend(adminID, userID int, reason string, until *time.Time, reportID *int64) error {
+	isAdmin, err := queries.IsSiteAdmin(userID)
+	if err != nil {
+		return err
+	}
+	if isAdmin {
+		return errSuspendAdmin
+	}
+
+	if err := queries.SuspendUser(userID, reason, until); err != nil {
+		return err
+	}
+	suspension := models.Suspension{SuspendedAt: time.Now(), Until: until, Reason: reason}
+	ws.DisconnectUser(userID, "account_suspended", suspension.Message())
+
+	return logAction(adminID, ActionUserSuspended, "user", int64(userID), reportID, map[string]interface{}{
+		"reason": reason,
+		"until":  until,
+	})
+}
+
+// Unsuspend lifts a suspension and logs it. It reports false if the user was not suspended.
+func Unsuspend(adminID, userID int, reason string) (bool, error) {
+	lifted, err := queries.UnsuspendUser(userID)
+	if err != nil || !lifted {
+		return false, err
+	}
+	return true, logAction(adminID, ActionUserUnsuspended, "user", int64(userID), nil, map[string]interface{}{
+		"reason": reason,
+	})
+}
+
+// ForceLogout deletes every session of a user, closes their socket and logs it.
+func ForceLogout(adminID, userID int, reason string) (sessions int64, socketClosed bool, err error) {
+	sessions, err = queries.DeleteUserSessions(userID)
+	if err != nil {
+		return 0, false, err
+	}
+	socketClosed = ws.DisconnectUser(userID, "logged_out", "You have been logged out")
+
+	return sessions, socketClosed, logAction(adminID, ActionUserLoggedOut, "user", int64(userID), nil, map[string]interface{}{
+		"reason":        reason,
+		"sessions":      sessions,
+		"socket_closed": socketClosed,
+	})
+}
+
+// SetRole changes the site role of a user and logs it. adminID is 0 when the
+// change comes from the command line.
+func SetRole(adminID, userID int, role string) error {
+	if err := queries.SetSiteRole(userID, role); err != nil {
+		return err
+	}
+	return logAction(adminID, ActionRoleChanged, "user", int64(userID), nil, map[string]interface{}{
+		"role": role,
+	})
+}
+
+// notifyReporters tells the reporters of closed reports what came of them.
+// Reports raised by the content filter have no one to notify.
+func notifyReporters(reports []models.Report, resolution string) {
+	for _, report := range reports {
+		if report.ReporterID == nil {
+			continue
+		}
+		err := activity.NotifyRecentActivity(*report.ReporterID, nil, "report_update",
+			activity.ReportReviewed(report.TargetType, resolution),
+			map[string]interface{}{
+				"report_id": report.ID,
+				"outcome":   resolution,
+			})
+		if err != nil {
+			fmt.Printf("moderation: failed to notify reporter of report %d: %v\n", report.ID, err)
+		}
+	}
+}
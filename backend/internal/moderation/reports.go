@@ -0,0 +1,165 @@
+package moderation
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/policy"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxReportDetails = 1000
+
+// canReport reports whether reporterID can see the reported content. Profiles
+// can always be reported, even by users their owner blocks.
+func canReport(reporterID int, targetType string, targetID int64, target queries.ReportTarget) (bool, error) {
+	switch targetType {
+	case "post", "comment":
+		postID := targetID
+		if targetType == "comment" {
+			postID = target.PostID
+		}
+		post, err := queries.GetPostByID(postID)
+		if err != nil {
+			return false, err
+		}
+		return policy.CanView(reporterID, post)
+	case "private_message":
+		return queries.IsPrivateChatParticipant(target.ConversationID, reporterID)
+	case "group_message":
+		return queries.IsUserGroupMember(target.GroupID, reporterID)
+	}
+	return true, nil
+}
+
+// CreateReport handles POST /api/reports
+// Body: { "target_type": "post", "target_id": 12, "reason": "spam", "details": "..." }
+func CreateReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var body struct {
+		TargetType string `json:"target_type"`
+		TargetID   int64  `json:"target_id"`
+		Reason     string `json:"reason"`
+		Details    string `json:"details"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	body.Details = strings.TrimSpace(body.Details)
+	switch {
+	case !contains(models.ReportTargetTypes, body.TargetType):
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("target_type must be one of: %s", strings.Join(models.ReportTargetTypes, ", ")),
+		})
+		return
+	case body.TargetID <= 0:
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid target ID"})
+		return
+	case !contains(models.ReportReasons, body.Reason):
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("reason must be one of: %s", strings.Join(models.ReportReasons, ", ")),
+		})
+		return
+	case utf8.RuneCountInString(body.Details) > maxReportDetails:
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: fmt.Sprintf("details must be at most %d characters", maxReportDetails),
+		})
+		return
+	}
+
+	// Content the reporter can't see is reported as missing, so reports can't probe for it
+	target, err := queries.GetReportTarget(body.TargetType, body.TargetID)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Content not found"})
+		return
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create report"})
+		return
+	}
+	visible, err := canReport(userID, body.TargetType, body.TargetID, target)
+	if err != nil && err != sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create report"})
+		return
+	}
+	if !visible {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Content not found"})
+		return
+	}
+	if target.AuthorID == userID {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "You can't report your own content"})
+		return
+	}
+
+	pending, err := queries.HasPendingReport(userID, body.TargetType, body.TargetID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create report"})
+		return
+	}
+	if pending {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "You already reported this and it is being reviewed"})
+		return
+	}
+
+	authorID := target.AuthorID
+	report := models.Report{
+		ReporterID:      &userID,
+		TargetType:      body.TargetType,
+		TargetID:        body.TargetID,
+		TargetUserID:    &authorID,
+		Reason:          body.Reason,
+		Details:         body.Details,
+		ContentSnapshot: target.Content,
+	}
+	id, err := queries.CreateReport(report)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create report"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":   true,
+		"message":   "Thank you, your report will be reviewed",
+		"report_id": id,
+	})
+}
+
+// ListMyReports handles GET /api/reports: the reports filed by the current user and their status.
+func ListMyReports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	reports, err := queries.GetReportsByReporter(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch reports"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"reports": reports,
+		"reasons": models.ReportReasons,
+	})
+}
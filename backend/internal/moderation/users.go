@@ -0,0 +1,263 @@
+package moderation
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/internal/ws"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadUser parses the {id} path value and loads the user for the admin tools.
+func loadUser(w http.ResponseWriter, r *http.Request) (models.AdminUser, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid user ID"})
+		return models.AdminUser{}, false
+	}
+	user, err := queries.GetAdminUser(id)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User not found"})
+		return user, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch user"})
+		return user, false
+	}
+	return user, true
+}
+
+// decodeBody decodes an optional JSON body: an empty body leaves v untouched.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return false
+	}
+	return true
+}
+
+// SearchUsers handles GET /api/admin/users?q=&suspended=true&role=admin&cursor=&limit=
+func SearchUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	filter := queries.AdminUserFilter{
+		Term:      strings.TrimSpace(q.Get("q")),
+		Suspended: q.Get("suspended") == "true",
+		Role:      q.Get("role"),
+	}
+	if filter.Role != "" && filter.Role != models.SiteRoleUser && filter.Role != models.SiteRoleAdmin {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid role"})
+		return
+	}
+	limit, cursor, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	filter.Before = int(cursor)
+
+	users, err := queries.SearchUsersForAdmin(filter, limit)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to search users"})
+		return
+	}
+	var next int
+	if len(users) == limit {
+		next = users[len(users)-1].ID
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"users":       users,
+		"next_cursor": next,
+	})
+}
+
+// GetUser handles GET /api/admin/users/{id}: the account, its activity and its moderation history.
+func GetUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	counts, err := queries.GetUserActivityCounts(user.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch user"})
+		return
+	}
+	history, err := queries.GetModerationLog("user", int64(user.ID), 0, 100)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch moderation history"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"user":    user,
+		"counts":  counts,
+		"online":  ws.IsUserOnline(user.ID),
+		"history": history,
+	})
+}
+
+// GetUserContent handles GET /api/admin/users/{id}/content?type=post|comment|group_message&cursor=&limit=
+// Private messages are not listed; admins only see those through reports.
+func GetUserContent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	contentType := r.URL.Query().Get("type")
+	if contentType == "" {
+		contentType = "post"
+	}
+	if contentType != "post" && contentType != "comment" && contentType != "group_message" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "type must be one of: post, comment, group_message"})
+		return
+	}
+	limit, cursor, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := queries.GetUserContent(user.ID, contentType, cursor, limit)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch content"})
+		return
+	}
+	var next int64
+	if len(items) == limit {
+		next = items[len(items)-1].ID
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"items":       items,
+		"next_cursor": next,
+	})
+}
+
+// SuspendUser handles POST /api/admin/users/{id}/suspend
+// Body: { "reason": "...", "duration": "168h" }. An empty duration suspends indefinitely.
+// Suspending an already suspended user replaces the suspension.
+func SuspendUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID, _ := utils.GetUserIDFromContext(r)
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason   string `json:"reason"`
+		Duration string `json:"duration"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "A reason is required"})
+		return
+	}
+
+	var until *time.Time
+	if body.Duration != "" {
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil || d <= 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "duration must be a positive duration such as \"72h\""})
+			return
+		}
+		t := time.Now().Add(d)
+		until = &t
+	}
+
+	err := Suspend(adminID, user.ID, body.Reason, until, nil)
+	if err == errSuspendAdmin {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Site admins can't be suspended"})
+		return
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to suspend user"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Suspended " + user.Username,
+		"until":   until,
+	})
+}
+
+// UnsuspendUser handles DELETE /api/admin/users/{id}/suspend
+// Body (optional): { "reason": "..." }
+func UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID, _ := utils.GetUserIDFromContext(r)
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	lifted, err := Unsuspend(adminID, user.ID, strings.TrimSpace(body.Reason))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to unsuspend user"})
+		return
+	}
+	if !lifted {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "User is not suspended"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Unsuspended " + user.Username})
+}
+
+// LogoutUser handles POST /api/admin/users/{id}/logout: ends every session and socket of the user.
+// Body (optional): { "reason": "..." }
+func LogoutUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID, _ := utils.GetUserIDFromContext(r)
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	sessions, socketClosed, err := ForceLogout(adminID, user.ID, strings.TrimSpace(body.Reason))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to log user out"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":       true,
+		"message":       "Logged out " + user.Username,
+		"sessions":      sessions,
+		"socket_closed": socketClosed,
+	})
+}
@@ -0,0 +1,85 @@
+// Package netguard keeps requests to addresses chosen by users, such as webhook
+// URLs and push endpoints, from reaching services inside our network.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a connection would reach a private or
+// local address.
+var ErrBlockedAddress = errors.New("address is private or local")
+
+// blockedNets are the non-public ranges the net.IP predicates don't cover. On
+// many cloud hosts they reach internal services too.
+var blockedNets = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},       // "this network"
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},   // carrier-grade NAT
+	{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}, // NAT64
+}
+
+// IsPublicIP reports whether ip is reachable on the internet, not a loopback,
+// private, link-local, multicast, carrier-grade NAT or NAT64 address.
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLocalHost reports whether a URL host names this machine or the local
+// network, before it is resolved.
+func IsLocalHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && !IsPublicIP(ip)
+}
+
+// Client returns an HTTP client that refuses to connect to private, loopback
+// and link-local addresses unless allowPrivate reports true. The check runs on
+// the resolved address of every connection, which also covers DNS rebinding.
+// Redirects are not followed: the redirect response is returned.
+func Client(timeout time.Duration, allowPrivate func() bool) *http.Client {
+	control := func(network, address string, _ syscall.RawConn) error {
+		if allowPrivate() {
+			return nil
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !IsPublicIP(ip) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: (&net.Dialer{
+				Timeout: 5 * time.Second,
+				Control: control,
+			}).DialContext,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/digest"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetDigestSettings handles GET /api/notifications/digest
+func GetDigestSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	frequency, err := queries.GetDigestFrequency(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch digest settings"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"frequency": frequency,
+	})
+}
+
+// UpdateDigestSettings handles PUT /api/notifications/digest
+// Form: frequency ("off" | "hourly" | "daily" | "weekly")
+func UpdateDigestSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+
+	frequency := r.FormValue("frequency")
+	if !digest.IsFrequency(frequency) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "frequency must be 'off', 'hourly', 'daily' or 'weekly'"})
+		return
+	}
+
+	if err := queries.SetDigestFrequency(userID, frequency); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update digest settings"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   "Digest settings updated",
+		"frequency": frequency,
+	})
+}
+
+// UnsubscribeDigest handles GET and POST /api/notifications/digest/unsubscribe?user=&sig=
+// It is public: the signed link in every digest turns digests off without logging in.
+// GET only shows a confirmation page, since mail scanners and link prefetchers
+// open links on their own; the page's button, like one-click unsubscribe from
+// mail clients (RFC 8058), POSTs to the same address to turn digests off.
+func UnsubscribeDigest(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user"))
+	if err != nil || userID <= 0 || !digest.VerifyUnsubscribe(userID, r.URL.Query().Get("sig")) {
+		writeUnsubscribePage(w, r, http.StatusBadRequest, "This unsubscribe link is invalid.", false)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeUnsubscribePage(w, r, http.StatusOK, "Do you want to stop receiving notification digests?", true)
+		return
+	}
+
+	if err := queries.SetDigestFrequency(userID, "off"); err != nil {
+		fmt.Printf("Failed to unsubscribe user %d from digests: %v\n", userID, err)
+		writeUnsubscribePage(w, r, http.StatusInternalServerError, "Something went wrong, please try again later.", false)
+		return
+	}
+
+	writeUnsubscribePage(w, r, http.StatusOK, "You will no longer receive notification digests. You can turn them back on in your settings.", false)
+}
+
+// writeUnsubscribePage answers an unsubscribe request: JSON for mail clients
+// posting the one-click request, a page for browsers. confirm adds the button
+// that posts the request.
+func writeUnsubscribePage(w http.ResponseWriter, r *http.Request, status int, message string, confirm bool) {
+	if r.Method == http.MethodPost && !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "application/json")
+		utils.RespondJSON(w, status, models.GenericResponse{Success: status == http.StatusOK, Message: message})
+		return
+	}
+
+	form := ""
+	if confirm {
+		// Posts back to this address, with its user and signature
+		form = `
+  <form method="post" action="` + html.EscapeString(r.URL.RequestURI()) + `">
+    <button type="submit" style="padding:10px 20px;border:0;border-radius:8px;background:#f4f4f5;color:#09090b;font-size:14px;cursor:pointer;">Unsubscribe</button>
+  </form>`
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Notification digests</title></head>
+<body style="margin:0;padding:40px;background:#09090b;font-family:'Segoe UI',Arial,sans-serif;text-align:center;">
+  <p style="color:#f4f4f5;font-size:16px;">%s</p>%s
+</body>
+</html>`, message, form)
+}
@@ -4,10 +4,51 @@ import (
 	"backend/internal/db/queries"
 	"backend/internal/models"
 	"backend/internal/utils"
+	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// defaultKeepRead is how many read notifications are kept when NOTIFICATION_KEEP_READ is unset
+	defaultKeepRead = 20
+)
+
+// readRetention returns the retention policy for read notifications:
+// NOTIFICATION_KEEP_READ is how many are kept (0 keeps all) and
+// NOTIFICATION_READ_MAX_AGE (e.g. "720h") how long they are kept (unset keeps them forever).
+func readRetention() (keep int, maxAge time.Duration) {
+	keep = defaultKeepRead
+	if raw := strings.TrimSpace(os.Getenv("NOTIFICATION_KEEP_READ")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			keep = n
+		} else {
+			fmt.Printf("notifications: ignoring invalid NOTIFICATION_KEEP_READ %q\n", raw)
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("NOTIFICATION_READ_MAX_AGE")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			maxAge = d
+		} else {
+			fmt.Printf("notifications: ignoring invalid NOTIFICATION_READ_MAX_AGE %q\n", raw)
+		}
+	}
+	return keep, maxAge
+}
+
+// pruneReadNotifications applies the retention policy after notifications were read.
+func pruneReadNotifications(userID int) {
+	keep, maxAge := readRetention()
+	if err := queries.PruneReadNotifications(userID, keep, maxAge); err != nil {
+		fmt.Printf("notifications: failed to prune read notifications of user %d: %v\n", userID, err)
+	}
+}
+
 func ListNotifications(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -28,7 +69,53 @@ func ListNotifications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	notifications, err := queries.GetNotifications(userID)
+	limit := defaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxPageSize {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+				Success: false,
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxPageSize),
+			})
+			return
+		}
+		limit = n
+	}
+
+	var before int64
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+				Success: false,
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		before = n
+	}
+
+	// Fetch one extra entry to know whether another page follows
+	notifications, err := queries.GetNotificationsPage(userID, before, limit+1)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to fetch notifications",
+		})
+		return
+	}
+
+	var nextCursor *string
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		cursor := strconv.FormatInt(notifications[limit-1].ID, 10)
+		nextCursor = &cursor
+	}
+	for i := range notifications {
+		notifications[i].Summary = GroupSummary(notifications[i])
+	}
+
+	unread, err := queries.CountUnreadNotifications(userID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -40,6 +127,36 @@ func ListNotifications(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"success":       true,
 		"notifications": notifications,
+		"next_cursor":   nextCursor,
+		"unread_count":  unread,
+	})
+}
+
+// UnreadNotificationCount handles GET /api/notifications/unread-count
+func UnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	unread, err := queries.CountUnreadNotifications(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to count notifications",
+		})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":      true,
+		"unread_count": unread,
 	})
 }
 
@@ -87,6 +204,7 @@ func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	pruneReadNotifications(userID)
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
 		Success: true,
@@ -121,6 +239,7 @@ func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	pruneReadNotifications(userID)
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
 		Success: true,
@@ -0,0 +1,250 @@
+package notifications
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxMuteDuration bounds timed mutes; longer mutes should use "forever"
+const maxMuteDuration = 365 * 24 * time.Hour
+
+func isKnownNotificationType(t string) bool {
+	for _, known := range models.NotificationTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownNotificationChannel(c string) bool {
+	for _, known := range models.NotificationChannels {
+		if known == c {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNotificationPreferences handles GET /api/notifications/preferences
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	prefs, err := queries.GetNotificationPreferences(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch notification preferences"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"preferences": prefs,
+		"channels":    models.NotificationChannels,
+	})
+}
+
+// UpdateNotificationPreferences handles PUT /api/notifications/preferences
+// Body: { "new_message": { "push": false, "email": false }, ... }
+// Channels that are not mentioned keep their setting.
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var settings map[string]map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil || len(settings) == 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	for nType, channels := range settings {
+		if !isKnownNotificationType(nType) {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: fmt.Sprintf("Unknown notification type '%s'", nType)})
+			return
+		}
+		for channel, enabled := range channels {
+			if !isKnownNotificationChannel(channel) {
+				utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: fmt.Sprintf("Unknown notification channel '%s'", channel)})
+				return
+			}
+			if channel == models.ChannelInApp && !enabled && queries.IsActionableNotificationType(nType) {
+				utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+					Success: false,
+					Message: fmt.Sprintf("In-app notifications for '%s' cannot be turned off because they need a response", nType),
+				})
+				return
+			}
+		}
+	}
+
+	if err := queries.SetNotificationPreferences(userID, settings); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update notification preferences"})
+		return
+	}
+
+	prefs, err := queries.GetNotificationPreferences(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch notification preferences"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"message":     "Notification preferences updated",
+		"preferences": prefs,
+	})
+}
+
+// ListNotificationMutes handles GET /api/notifications/mutes
+func ListNotificationMutes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	mutes, err := queries.GetActiveNotificationMutes(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch mutes"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"mutes":   mutes,
+	})
+}
+
+// MuteTarget handles POST /api/notifications/mutes
+// Form: target_type ("group" | "conversation"), target_id, duration (e.g. "8h"; empty or "forever" mutes indefinitely)
+func MuteTarget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+
+	targetType, targetID, ok := parseMuteTarget(w, userID, r.FormValue("target_type"), r.FormValue("target_id"))
+	if !ok {
+		return
+	}
+
+	var until *time.Time
+	if duration := r.FormValue("duration"); duration != "" && duration != "forever" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 || d > maxMuteDuration {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+				Success: false,
+				Message: "Invalid duration: use a duration such as '8h' up to one year, or 'forever'",
+			})
+			return
+		}
+		t := time.Now().Add(d).UTC().Truncate(time.Second)
+		until = &t
+	}
+
+	if err := queries.MuteNotifications(userID, targetType, targetID, until); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to mute notifications"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Notifications muted",
+		"mute": models.NotificationMute{
+			TargetType: targetType,
+			TargetID:   targetID,
+			MutedUntil: until,
+			CreatedAt:  time.Now().UTC().Truncate(time.Second),
+		},
+	})
+}
+
+// UnmuteTarget handles DELETE /api/notifications/mutes/{type}/{id}
+func UnmuteTarget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	targetType := r.PathValue("type")
+	if targetType != "group" && targetType != "conversation" {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "target_type must be 'group' or 'conversation'"})
+		return
+	}
+	targetID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || targetID <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid target ID"})
+		return
+	}
+
+	removed, err := queries.UnmuteNotifications(userID, targetType, targetID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to unmute notifications"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Not muted"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Notifications unmuted"})
+}
+
+// parseMuteTarget validates a mute target and checks that userID belongs to it.
+// It writes the error response and reports false when the target is invalid.
+func parseMuteTarget(w http.ResponseWriter, userID int, targetType, rawID string) (string, int64, bool) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid target ID"})
+		return "", 0, false
+	}
+
+	var belongs bool
+	switch targetType {
+	case "group":
+		belongs, err = queries.IsUserGroupMember(id, userID)
+	case "conversation":
+		belongs, err = queries.IsPrivateChatParticipant(id, userID)
+	default:
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "target_type must be 'group' or 'conversation'"})
+		return "", 0, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to validate target"})
+		return "", 0, false
+	}
+	if !belongs {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "You can only mute groups and conversations you belong to"})
+		return "", 0, false
+	}
+	return targetType, int64(id), true
+}
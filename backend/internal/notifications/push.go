@@ -0,0 +1,131 @@
+package notifications
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/internal/webpush"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetVAPIDPublicKey handles GET /api/push/vapid-public-key
+// Browsers pass the key to pushManager.subscribe as applicationServerKey.
+func GetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	key, err := webpush.PublicKey()
+	if err != nil {
+		fmt.Printf("Failed to load VAPID key: %v\n", err)
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Push notifications are unavailable"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"publicKey": key,
+	})
+}
+
+// ListPushSubscriptions handles GET /api/push/subscriptions
+func ListPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	subs, err := queries.GetPushSubscriptions(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch push subscriptions"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":       true,
+		"subscriptions": subs,
+	})
+}
+
+// SubscribePush handles POST /api/push/subscriptions
+// Body: the browser's PushSubscription.toJSON(), i.e. { "endpoint": "...", "keys": { "p256dh": "...", "auth": "..." } }
+func SubscribePush(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var body struct {
+		Endpoint string `json:"endpoint"`
+		Keys     struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8<<10)).Decode(&body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	if err := webpush.ValidateSubscription(body.Endpoint, body.Keys.P256dh, body.Keys.Auth); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	id, err := queries.UpsertPushSubscription(userID, body.Endpoint, body.Keys.P256dh, body.Keys.Auth, userAgent)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "This device receives push notifications for another account"})
+		return
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to save push subscription"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Push notifications enabled",
+		"id":      id,
+	})
+}
+
+// UnsubscribePush handles DELETE /api/push/subscriptions/{id}
+func UnsubscribePush(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid subscription ID"})
+		return
+	}
+
+	removed, err := queries.DeletePushSubscription(userID, id)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to remove push subscription"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Subscription not found"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Push notifications disabled for this device"})
+}
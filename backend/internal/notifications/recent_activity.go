@@ -3,6 +3,7 @@ package notifications
 import (
 	"backend/internal/db/queries"
 	"backend/internal/models"
+	"backend/internal/webpush"
 	"backend/internal/ws"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,8 @@ import (
 type RecentActivityText struct {
 	Message  string
 	Subtitle string
+	// GroupKey collapses notifications of the same key into one inbox entry
+	GroupKey string
 }
 
 func activityUsername(username string) string {
@@ -59,6 +62,7 @@ func FollowAcceptedForReceiver(senderUsername string) RecentActivityText {
 	return RecentActivityText{
 		Message:  fmt.Sprintf("%s is now following you", activityUsername(senderUsername)),
 		Subtitle: "Follow Update",
+		GroupKey: "new_followers",
 	}
 }
 
@@ -111,6 +115,130 @@ func GroupInvitationAcceptedForInvitee(groupName string) RecentActivityText {
 	}
 }
 
+func EventWaitlistPromoted(eventTitle, occurrenceDate string) RecentActivityText {
+	message := fmt.Sprintf("A spot opened up: you are now going to '%s'", strings.TrimSpace(eventTitle))
+	if occurrenceDate != "" {
+		message += " on " + occurrenceDate
+	}
+	return RecentActivityText{
+		Message:  message,
+		Subtitle: "Event Waitlist",
+	}
+}
+
+func EventReminder(eventTitle string, startsAt time.Time) RecentActivityText {
+	return RecentActivityText{
+		Message:  fmt.Sprintf("Reminder: '%s' starts %s", strings.TrimSpace(eventTitle), startsAt.Format("Mon 2 Jan at 15:04")),
+		Subtitle: "Event Reminder",
+	}
+}
+
+func EventUpdated(eventID int64, eventTitle string, changes []string) RecentActivityText {
+	return RecentActivityText{
+		Message:  fmt.Sprintf("'%s' was updated: %s", strings.TrimSpace(eventTitle), strings.Join(changes, "; ")),
+		Subtitle: "Event Update",
+		GroupKey: fmt.Sprintf("event_updated:%d", eventID),
+	}
+}
+
+// ReportReviewed tells a reporter what came of their report. targetType is the
+// kind of content reported and outcome the resolution of the report.
+func ReportReviewed(targetType, outcome string) RecentActivityText {
+	what := map[string]string{
+		"post":            "a post",
+		"comment":         "a comment",
+		"private_message": "a message",
+		"group_message":   "a group chat message",
+		"user":            "a profile",
+	}[targetType]
+	if what == "" {
+		what = "some content"
+	}
+
+	result := "we found no violation of the community rules"
+	switch outcome {
+	case "no_action":
+		result = "no further action was needed"
+	case "remove_content":
+		result = "the content was removed"
+	case "suspend_user":
+		result = "the account responsible was suspended"
+	case "remove_and_suspend":
+		result = "the content was removed and the account responsible suspended"
+	}
+	return RecentActivityText{
+		Message:  fmt.Sprintf("We reviewed your report about %s: %s. Thank you for reporting it", what, result),
+		Subtitle: "Report Update",
+	}
+}
+
+// GroupOwnershipTransferred tells a member they own a group whose owner deleted their account.
+func GroupOwnershipTransferred(groupName string) RecentActivityText {
+	return RecentActivityText{
+		Message:  fmt.Sprintf("You are now the owner of %s, as its owner deleted their account", activityGroupName(groupName)),
+		Subtitle: "Group Ownership",
+	}
+}
+
+// DataExportReady tells a user their personal data archive can be downloaded until expiresAt.
+func DataExportReady(expiresAt time.Time) RecentActivityText {
+	return RecentActivityText{
+		Message:  fmt.Sprintf("Your data export is ready. You can download it until %s", expiresAt.Format("Mon 2 Jan at 15:04")),
+		Subtitle: "Data Export",
+	}
+}
+
+// DataExportFailed tells a user their personal data archive could not be built.
+func DataExportFailed() RecentActivityText {
+	return RecentActivityText{
+		Message:  "We couldn't prepare your data export. Please request a new one",
+		Subtitle: "Data Export",
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// GroupSummary describes a collapsed inbox entry, e.g. "@alice and 4 others are
+// now following you". It returns "" for entries holding a single notification.
+func GroupSummary(n models.Notification) string {
+	if n.Count < 2 {
+		return ""
+	}
+
+	var data map[string]interface{}
+	_ = json.Unmarshal([]byte(n.Data), &data)
+
+	actor := "Someone"
+	if len(n.Actors) > 0 && n.Actors[0].Username != nil {
+		actor = activityUsername(*n.Actors[0].Username)
+	}
+	others := ""
+	if n.ActorCount > 1 {
+		others = " and " + plural(n.ActorCount-1, "other", "others")
+	}
+
+	switch n.Type {
+	case "follow_update":
+		if n.ActorCount > 1 {
+			return fmt.Sprintf("%s%s are now following you", actor, others)
+		}
+	case "new_message":
+		return fmt.Sprintf("%s%s sent you %s", actor, others, plural(n.Count, "message", "messages"))
+	case "event_updated":
+		if title, ok := data["event_title"].(string); ok {
+			return fmt.Sprintf("'%s' was updated %s", strings.TrimSpace(title), plural(n.Count, "time", "times"))
+		}
+	}
+
+	message, _ := data["message"].(string)
+	return fmt.Sprintf("%s (+%d more)", message, n.Count-1)
+}
+
 func ActivityPayload(text RecentActivityText, extra map[string]interface{}) map[string]interface{} {
 	payload := map[string]interface{}{
 		"message":  text.Message,
@@ -131,16 +259,60 @@ func StoreRecentActivity(userID int, actorID *int, activityType string, text Rec
 		return 0, err
 	}
 
-	return queries.CreateNotification(userID, actorID, activityType, string(dataBytes))
+	return queries.CreateGroupedNotification(userID, actorID, activityType, string(dataBytes), text.GroupKey)
+}
+
+// muteTarget returns the group or conversation a notification is about, if any.
+func muteTarget(extra map[string]interface{}) (string, int64) {
+	for _, target := range []string{"conversation", "group"} {
+		switch id := extra[target+"_id"].(type) {
+		case int:
+			return target, int64(id)
+		case int64:
+			return target, id
+		}
+	}
+	return "", 0
 }
 
+// NotifyRecentActivity stores a notification and pushes it to the user, on the
+// channels their preferences and mutes allow.
 func NotifyRecentActivity(userID int, actorID *int, activityType string, text RecentActivityText, extra map[string]interface{}) error {
-	// Store in database and get the notification ID
-	notificationID, err := StoreRecentActivity(userID, actorID, activityType, text, extra)
+	targetType, targetID := muteTarget(extra)
+	delivery, err := queries.GetNotificationDelivery(userID, activityType, targetType, targetID)
 	if err != nil {
 		return err
 	}
 
+	var notificationID int64
+	if delivery.InApp {
+		// Store in database and get the notification ID
+		notificationID, err = StoreRecentActivity(userID, actorID, activityType, text, extra)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !delivery.Push {
+		return nil
+	}
+
+	// Users without an open socket get a Web Push on their subscribed devices instead
+	if !ws.IsUserOnline(userID) {
+		data := map[string]interface{}{}
+		if notificationID != 0 {
+			data["notification_id"] = notificationID
+		}
+		webpush.NotifyUser(userID, webpush.Message{
+			Type:  activityType,
+			Title: text.Subtitle,
+			Body:  text.Message,
+			URL:   "/notifications",
+			Data:  data,
+		})
+		return nil
+	}
+
 	actorIDVal := 0
 	if actorID != nil {
 		actorIDVal = *actorID
@@ -153,7 +325,10 @@ func NotifyRecentActivity(userID int, actorID *int, activityType string, text Re
 		Timestamp: time.Now(),
 	})
 
-	// Emit real-time event for instant UI update
+	// Emit real-time event for instant UI update, unless nothing was stored
+	if notificationID == 0 {
+		return nil
+	}
 	status := "pending"
 	if extra != nil {
 		if s, ok := extra["status"].(string); ok {
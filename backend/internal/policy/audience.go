@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+)
+
+// NonFollower returns the first of userIDs who is not an accepted follower of
+// the author, or 0 if they all are. Posts are only shared with accepted
+// followers, whether chosen for the post or through an audience list.
+func NonFollower(authorID int, userIDs []int) (int, error) {
+	others, err := queries.GetNonFollowers(authorID, userIDs)
+	if err != nil || len(others) == 0 {
+		return 0, err
+	}
+	return others[0], nil
+}
+
+// LostViewers returns those of before, who could see the post before a change
+// of its privacy or audience, who can no longer see it.
+func LostViewers(post models.Post, before []int) ([]int, error) {
+	still, err := Viewers(post, before)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[int]bool, len(still))
+	for _, id := range still {
+		kept[id] = true
+	}
+	var lost []int
+	for _, id := range before {
+		if !kept[id] {
+			lost = append(lost, id)
+		}
+	}
+	return lost, nil
+}
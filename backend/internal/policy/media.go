@@ -0,0 +1,21 @@
+package policy
+
+import "backend/internal/db/queries"
+
+// CanViewMedia reports whether viewerID may see an uploaded file and its
+// variants. Media is visible with what uses it:
+//   - own uploads  → always visible to the uploader
+//   - avatars      → everyone not blocked by the user
+//   - post media   → whoever can see the post (see CanView)
+//   - group covers → everyone
+//   - event images → members of the group
+//
+// Site admins see all media, as they see all reported content.
+// Returns sql.ErrNoRows when the media does not exist.
+func CanViewMedia(viewerID int, mediaID int64) (bool, error) {
+	visible, err := queries.IsMediaVisibleTo(mediaID, viewerID)
+	if err != nil || visible {
+		return visible, err
+	}
+	return queries.IsSiteAdmin(viewerID)
+}
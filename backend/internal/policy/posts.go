@@ -0,0 +1,35 @@
+// Package policy holds the access rules for user content. Handlers must go
+// through it instead of re-implementing privacy checks.
+package policy
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+)
+
+// CanView reports whether viewerID may see the post, its likes and its comments.
+//   - group posts    → members of the group only
+//   - own posts      → always visible to the author
+//   - public         → everyone
+//   - followers      → accepted followers of the author
+//   - selected       → the members of its audience list, or without a list
+//     the users chosen in post_selected_followers, who still follow the author
+//
+// Nothing is visible between two users when one of them blocks the other.
+func CanView(viewerID int, post models.Post) (bool, error) {
+	return queries.IsPostVisibleTo(post.ID, viewerID)
+}
+
+// Viewers returns those of userIDs who CanView the post.
+func Viewers(post models.Post, userIDs []int) ([]int, error) {
+	return queries.GetPostViewers(post.ID, userIDs)
+}
+
+// CanInteract reports whether viewerID may like, comment on or reply to the post.
+// Interacting requires an authenticated viewer who can see the post.
+func CanInteract(viewerID int, post models.Post) (bool, error) {
+	if viewerID == 0 {
+		return false, nil
+	}
+	return CanView(viewerID, post)
+}
@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"backend/internal/db"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// Users of the fixture
+const (
+	anonymous = 0
+	author    = 1
+	follower  = 2 // accepted follower of the author
+	stranger  = 3
+	selected  = 4 // accepted follower in the audience of the selected post
+	member    = 5 // member of the group
+	blocked   = 6 // accepted follower in the audience of the selected post, then blocked by the author
+	pending   = 7 // follow request not accepted yet
+	former    = 8 // in the audience of the selected post, no longer following
+)
+
+// Posts of the fixture, by the author
+var (
+	groupID       = int64(1)
+	publicPost    = models.Post{ID: 1, UserID: author, Privacy: "public"}
+	followersPost = models.Post{ID: 2, UserID: author, Privacy: "followers"}
+	selectedPost  = models.Post{ID: 3, UserID: author, Privacy: "selected"}
+	groupPost     = models.Post{ID: 4, UserID: author, GroupID: &groupID, Privacy: "public"}
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "policy")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		if err := db.InitDB(filepath.Join(dir, "test.db")); err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		if err := db.RunMigrations("../db/migrations/sqlite"); err != nil {
+			panic(err)
+		}
+		if err := seed(); err != nil {
+			panic(err)
+		}
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+func seed() error {
+	for id := author; id <= former; id++ {
+		if _, err := queries.DB.Exec(`
+			INSERT INTO users (id, email, username, password_hash, first_name, last_name, date_of_birth)
+			VALUES (?, 'user' || ?1 || '@example.test', 'user' || ?1, 'x', 'User', ?1, '2000-01-01')
+		`, id); err != nil {
+			return err
+		}
+	}
+	statements := []string{
+		`INSERT INTO followers (follower_id, following_id, status) VALUES
+			(2, 1, 'accepted'), (4, 1, 'accepted'), (6, 1, 'accepted'), (7, 1, 'pending')`,
+		`INSERT INTO groups (id, name, owner_id) VALUES (1, 'Group', 1)`,
+		`INSERT INTO group_members (group_id, user_id) VALUES (1, 1), (1, 5)`,
+		`INSERT INTO posts (id, user_id, content, privacy) VALUES
+			(1, 1, 'public', 'public'), (2, 1, 'followers', 'followers'), (3, 1, 'selected', 'selected')`,
+		`INSERT INTO posts (id, user_id, group_id, content, privacy) VALUES (4, 1, 1, 'group', 'public')`,
+		`INSERT INTO post_selected_followers (post_id, user_id) VALUES (3, 4), (3, 6), (3, 8)`,
+	}
+	for _, s := range statements {
+		if _, err := queries.DB.Exec(s); err != nil {
+			return err
+		}
+	}
+	return queries.BlockUser(author, blocked)
+}
+
+func TestCanView(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   models.Post
+		viewer int
+		want   bool
+	}{
+		{"public, anonymous", publicPost, anonymous, true},
+		{"public, stranger", publicPost, stranger, true},
+		{"public, author", publicPost, author, true},
+		{"public, blocked", publicPost, blocked, false},
+
+		{"followers, anonymous", followersPost, anonymous, false},
+		{"followers, stranger", followersPost, stranger, false},
+		{"followers, follower", followersPost, follower, true},
+		{"followers, pending follower", followersPost, pending, false},
+		{"followers, author", followersPost, author, true},
+		{"followers, blocked", followersPost, blocked, false},
+
+		{"selected, anonymous", selectedPost, anonymous, false},
+		{"selected, in audience", selectedPost, selected, true},
+		{"selected, follower not in audience", selectedPost, follower, false},
+		{"selected, stranger", selectedPost, stranger, false},
+		{"selected, author", selectedPost, author, true},
+		{"selected, blocked", selectedPost, blocked, false},
+		{"selected, former follower in audience", selectedPost, former, false},
+
+		{"group, member", groupPost, member, true},
+		{"group, author member", groupPost, author, true},
+		{"group, follower not member", groupPost, follower, false},
+		{"group, stranger", groupPost, stranger, false},
+		{"group, anonymous", groupPost, anonymous, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanView(tt.viewer, tt.post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CanView(%d, post %d) = %v, want %v", tt.viewer, tt.post.ID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanInteract(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   models.Post
+		viewer int
+		want   bool
+	}{
+		{"public, anonymous", publicPost, anonymous, false},
+		{"public, stranger", publicPost, stranger, true},
+		{"public, blocked", publicPost, blocked, false},
+		{"followers, follower", followersPost, follower, true},
+		{"followers, stranger", followersPost, stranger, false},
+		{"selected, in audience", selectedPost, selected, true},
+		{"selected, follower not in audience", selectedPost, follower, false},
+		{"group, member", groupPost, member, true},
+		{"group, stranger", groupPost, stranger, false},
+		{"own post, author", followersPost, author, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanInteract(tt.viewer, tt.post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CanInteract(%d, post %d) = %v, want %v", tt.viewer, tt.post.ID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanViewMissingPost(t *testing.T) {
+	if _, err := CanView(author, models.Post{ID: 999, UserID: author}); err == nil {
+		t.Error("CanView of a missing post: want an error")
+	}
+}
+
+func TestBlockRemovesAudience(t *testing.T) {
+	var n int
+	if err := queries.DB.QueryRow(`SELECT COUNT(*) FROM post_selected_followers WHERE user_id = ?`, blocked).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("blocked user still in the audience of %d posts", n)
+	}
+}
+
+func TestViewers(t *testing.T) {
+	everyone := []int{author, follower, stranger, selected, member, blocked, pending, former}
+	tests := []struct {
+		name string
+		post models.Post
+		want []int
+	}{
+		{"public", publicPost, []int{author, follower, stranger, selected, member, pending, former}},
+		{"followers", followersPost, []int{author, follower, selected}},
+		{"selected", selectedPost, []int{author, selected}},
+		{"group", groupPost, []int{author, member}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Viewers(tt.post, everyone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Ints(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Viewers(post %d) = %v, want %v", tt.post.ID, got, tt.want)
+			}
+		})
+	}
+}
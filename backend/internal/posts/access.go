@@ -0,0 +1,63 @@
+package posts
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"net/http"
+	"strconv"
+)
+
+// postCheck is a policy rule such as policy.CanView or policy.CanInteract.
+type postCheck func(viewerID int, post models.Post) (bool, error)
+
+// loadAccessiblePost parses the {id} path value, loads the post and applies the
+// given policy rule for viewerID. On failure the error response is written and ok is false.
+func loadAccessiblePost(w http.ResponseWriter, r *http.Request, viewerID int, check postCheck) (post models.Post, ok bool) {
+	postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid post ID"})
+		return post, false
+	}
+
+	post, err = queries.GetPostByID(postID)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Post not found"})
+		return post, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch post"})
+		return post, false
+	}
+
+	allowed, err := check(viewerID, post)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to verify access"})
+		return post, false
+	}
+	if !allowed {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Access denied"})
+		return post, false
+	}
+
+	return post, true
+}
+
+// commentBelongsToPost parses the {commentId} path value and verifies the comment
+// is attached to postID. On failure the error response is written and ok is false.
+func commentBelongsToPost(w http.ResponseWriter, r *http.Request, postID int64) (commentID int64, ok bool) {
+	commentID, err := strconv.ParseInt(r.PathValue("commentId"), 10, 64)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid comment ID"})
+		return 0, false
+	}
+
+	commentPostID, err := queries.GetCommentPostID(commentID)
+	if err != nil || commentPostID != postID {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Comment not found"})
+		return 0, false
+	}
+
+	return commentID, true
+}
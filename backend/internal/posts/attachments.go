@@ -0,0 +1,136 @@
+package posts
+
+import (
+	"backend/internal/media"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// attachmentEdit is an entry of the attachments list sent to UpdatePost: an
+// attachment the post already has, by ID, or a new file, by the name of the
+// multipart field it is uploaded in.
+type attachmentEdit struct {
+	ID      int64  `json:"id,omitempty"`
+	File    string `json:"file,omitempty"`
+	AltText string `json:"alt_text"`
+}
+
+type postUpdate struct {
+	Content     string            `json:"content"`
+	Privacy     string            `json:"privacy"`
+	Attachments *[]attachmentEdit `json:"attachments"`
+	// SelectedUsers replaces the audience of a "selected" post when set
+	SelectedUsers *[]int `json:"selected_users"`
+	// AudienceListID shares a "selected" post with one of the author's
+	// audience lists instead
+	AudienceListID *int64 `json:"audience_list_id"`
+}
+
+// parsePostUpdate reads the body of UpdatePost, JSON or multipart. On failure
+// the error response is written and ok is false.
+func parsePostUpdate(w http.ResponseWriter, r *http.Request) (body postUpdate, ok bool) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := utils.ParseJSON(r, &body); err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+			return body, false
+		}
+		return body, true
+	}
+
+	if err := r.ParseMultipartForm(25 << 20); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Request too large (max 25MB)"})
+		return body, false
+	}
+	body.Content = r.FormValue("content")
+	body.Privacy = r.FormValue("privacy")
+	if raw := r.FormValue("attachments"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &body.Attachments); err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid attachments"})
+			return body, false
+		}
+	}
+	if raw := r.FormValue("selected_users"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &body.SelectedUsers); err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid selected_users"})
+			return body, false
+		}
+	}
+	if raw := r.FormValue("audience_list_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid audience_list_id"})
+			return body, false
+		}
+		body.AudienceListID = &id
+	}
+	return body, true
+}
+
+// applyAttachmentEdits checks the attachments list sent for a post and saves
+// its new files. It returns the post's new list and the attachments added, to
+// release if the post fails to save. On failure the error response is written
+// and ok is false.
+func applyAttachmentEdits(w http.ResponseWriter, r *http.Request, post models.Post, edits []attachmentEdit) (attachments, added []models.PostAttachment, ok bool) {
+	fail := func(message string) ([]models.PostAttachment, []models.PostAttachment, bool) {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: message})
+		return nil, nil, false
+	}
+
+	if len(edits) > media.MaxAttachments {
+		return fail(fmt.Sprintf("A post can have at most %d attachments", media.MaxAttachments))
+	}
+	// Personal posts are built around their media; group posts may be text only
+	if len(edits) == 0 && post.GroupID == nil {
+		return fail("Post must include a photo, GIF, or video")
+	}
+
+	current := make(map[int64]models.PostAttachment, len(post.Attachments))
+	for _, a := range post.Attachments {
+		current[a.ID] = a
+	}
+	used := make(map[int64]bool, len(edits))
+	for _, e := range edits {
+		if len(e.AltText) > media.MaxAltText {
+			return fail(fmt.Sprintf("Alt text must be at most %d characters", media.MaxAltText))
+		}
+		switch {
+		case e.ID != 0 && e.File != "":
+			return fail("An attachment must have either an id or a file")
+		case e.ID != 0:
+			if _, ok := current[e.ID]; !ok || used[e.ID] {
+				return fail(fmt.Sprintf("Unknown attachment %d", e.ID))
+			}
+			used[e.ID] = true
+		case e.File != "":
+			if r.MultipartForm == nil || len(r.MultipartForm.File[e.File]) != 1 {
+				return fail(fmt.Sprintf("Missing file %q", e.File))
+			}
+		default:
+			return fail("An attachment must have either an id or a file")
+		}
+	}
+
+	// Files are saved once the whole list is known to be valid
+	attachments = make([]models.PostAttachment, 0, len(edits))
+	for _, e := range edits {
+		if e.ID != 0 {
+			a := current[e.ID]
+			a.AltText = e.AltText
+			attachments = append(attachments, a)
+			continue
+		}
+		a, err := media.SaveAttachment(r.MultipartForm.File[e.File][0], e.AltText, post.UserID)
+		if err != nil {
+			media.ReleaseAttachments(added)
+			return fail(err.Error())
+		}
+		added = append(added, a)
+		attachments = append(attachments, a)
+	}
+	return attachments, added, true
+}
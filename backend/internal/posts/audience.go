@@ -0,0 +1,215 @@
+package posts
+
+import (
+	"backend/internal/audiences"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/policy"
+	"backend/internal/utils"
+	"backend/internal/ws"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type audienceBody struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+// loadOwnPost loads the post of the {id} path value for its author to manage.
+// On failure the error response is written and ok is false.
+func loadOwnPost(w http.ResponseWriter, r *http.Request, userID int) (post models.Post, ok bool) {
+	postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid post ID"})
+		return post, false
+	}
+	post, err = queries.GetPostByID(postID)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Post not found"})
+		return post, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch post"})
+		return post, false
+	}
+	if post.UserID != userID {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "You can only manage the audience of your own posts"})
+		return post, false
+	}
+	return post, true
+}
+
+// checkAudienceList verifies that the author owns the audience list a post is
+// shared with. Lists of other users are reported as not found, so their IDs
+// tell nothing. On failure the error response is written and ok is false.
+func checkAudienceList(w http.ResponseWriter, authorID int, listID int64) bool {
+	owns, err := queries.IsAudienceListOwner(listID, authorID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to verify audience list"})
+		return false
+	}
+	if !owns {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Audience list not found"})
+		return false
+	}
+	return true
+}
+
+// checkNoAudienceList refuses changes to the audience of a post shared with an
+// audience list, which comes from the list. On failure the error response is
+// written and ok is false.
+func checkNoAudienceList(w http.ResponseWriter, post models.Post) bool {
+	if post.AudienceListID != nil {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "Post is shared with an audience list"})
+		return false
+	}
+	return true
+}
+
+// onlineViewers returns the online users, other than the author, who can see
+// a post. Taken before a change of audience or privacy, it tells
+// audiences.NotifyLostAccess who to notify.
+// A personal post that is not public is only seen by accepted followers of
+// its author, its audience included, so only those are checked.
+func onlineViewers(post models.Post) []int {
+	candidates := ws.OnlineUserIDs()
+	if post.GroupID == nil && post.Privacy != "public" {
+		followers, err := queries.GetFollowerIDs(post.UserID)
+		if err != nil {
+			fmt.Printf("posts: failed to load followers of user %d: %v\n", post.UserID, err)
+			return nil
+		}
+		candidates = ws.OnlineAmong(followers)
+	}
+
+	others := make([]int, 0, len(candidates))
+	for _, id := range candidates {
+		if id != post.UserID {
+			others = append(others, id)
+		}
+	}
+	viewers, err := policy.Viewers(post, others)
+	if err != nil {
+		fmt.Printf("posts: failed to check the viewers of post %d: %v\n", post.ID, err)
+		return nil
+	}
+	return viewers
+}
+
+// respondAudience writes the audience of a post: the members of its audience
+// list when it is shared with one.
+func respondAudience(w http.ResponseWriter, post models.Post) {
+	var audience []models.UserSearchResult
+	var err error
+	if post.AudienceListID != nil {
+		audience, err = queries.GetAudienceListMembers(*post.AudienceListID, post.UserID)
+	} else {
+		audience, err = queries.GetPostAudience(post.ID, post.UserID)
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch audience"})
+		return
+	}
+	if audience == nil {
+		audience = []models.UserSearchResult{}
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":          true,
+		"privacy":          post.Privacy,
+		"audience_list_id": post.AudienceListID,
+		"audience":         audience,
+	})
+}
+
+// GetPostAudience handles GET /api/posts/{id}/audience: the followers a
+// "selected" post is shared with, for its author, and the audience list they
+// come from if any.
+func GetPostAudience(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	post, ok := loadOwnPost(w, r, userID)
+	if !ok {
+		return
+	}
+	respondAudience(w, post)
+}
+
+// changeAudience handles the requests that replace or add to the audience
+// of a "selected" post, with {"user_ids": [...]}.
+func changeAudience(w http.ResponseWriter, r *http.Request, change func(postID int64, authorID int, userIDs []int) error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	post, ok := loadOwnPost(w, r, userID)
+	if !ok {
+		return
+	}
+	if post.GroupID != nil || post.Privacy != "selected" {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "Post is not shared with selected followers"})
+		return
+	}
+	if !checkNoAudienceList(w, post) {
+		return
+	}
+
+	var body audienceBody
+	if err := utils.ParseJSON(r, &body); err != nil || body.UserIDs == nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	if !audiences.CheckFollowers(w, userID, body.UserIDs) {
+		return
+	}
+
+	before := onlineViewers(post)
+	if err := change(post.ID, userID, body.UserIDs); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update audience"})
+		return
+	}
+	audiences.NotifyLostAccess([]int64{post.ID}, before)
+	respondAudience(w, post)
+}
+
+// ReplacePostAudience handles PUT /api/posts/{id}/audience: the followers
+// listed become the whole audience of the post.
+func ReplacePostAudience(w http.ResponseWriter, r *http.Request) {
+	changeAudience(w, r, queries.ReplacePostAudience)
+}
+
+// AddToPostAudience handles POST /api/posts/{id}/audience: the followers
+// listed are added to the audience of the post.
+func AddToPostAudience(w http.ResponseWriter, r *http.Request) {
+	changeAudience(w, r, queries.AddToPostAudience)
+}
+
+// RemoveFromPostAudience handles DELETE /api/posts/{id}/audience/{userId}.
+func RemoveFromPostAudience(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.GetUserIDFromContext(r)
+	post, ok := loadOwnPost(w, r, userID)
+	if !ok || !checkNoAudienceList(w, post) {
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid user ID"})
+		return
+	}
+
+	before := onlineViewers(post)
+	removed, err := queries.RemoveFromPostAudience(post.ID, memberID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update audience"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User is not in the audience of this post"})
+		return
+	}
+	audiences.NotifyLostAccess([]int64{post.ID}, before)
+	respondAudience(w, post)
+}
@@ -1,28 +1,26 @@
 package posts
 
 import (
+	"backend/internal/contentfilter"
 	"backend/internal/db/queries"
 	"backend/internal/models"
+	"backend/internal/policy"
 	"backend/internal/utils"
 	"net/http"
-	"strconv"
 )
 
 // GetComments handles GET /api/posts/{id}/comments
 func GetComments(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	postIDStr := r.PathValue("id")
-	postID, err := strconv.ParseInt(postIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid post ID",
-		})
+	viewerID, _ := utils.GetUserIDFromContext(r)
+
+	post, ok := loadAccessiblePost(w, r, viewerID, policy.CanView)
+	if !ok {
 		return
 	}
 
-	rawComments, err := queries.GetComments(postID)
+	rawComments, err := queries.GetComments(post.ID, viewerID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -60,13 +58,8 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postIDStr := r.PathValue("id")
-	postID, err := strconv.ParseInt(postIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid post ID",
-		})
+	post, ok := loadAccessiblePost(w, r, userID, policy.CanInteract)
+	if !ok {
 		return
 	}
 
@@ -89,7 +82,13 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	commentID, err := queries.AddComment(postID, userID, body.Content)
+	decision := contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindComment, Text: body.Content})
+	if decision.Rejected() {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+		return
+	}
+
+	commentID, err := queries.AddComment(post.ID, userID, body.Content)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -97,6 +96,7 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	decision.Report("comment", commentID, userID, body.Content)
 
 	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success":    true,
@@ -116,10 +116,13 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	commentIDStr := r.PathValue("commentId")
-	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid comment ID"})
+	post, ok := loadAccessiblePost(w, r, userID, policy.CanView)
+	if !ok {
+		return
+	}
+
+	commentID, ok := commentBelongsToPost(w, r, post.ID)
+	if !ok {
 		return
 	}
 
@@ -143,10 +146,13 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	commentIDStr := r.PathValue("commentId")
-	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid comment ID"})
+	post, ok := loadAccessiblePost(w, r, userID, policy.CanInteract)
+	if !ok {
+		return
+	}
+
+	commentID, ok := commentBelongsToPost(w, r, post.ID)
+	if !ok {
 		return
 	}
 
@@ -162,10 +168,26 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	current, err := queries.GetCommentContent(commentID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update comment"})
+		return
+	}
+	// Unchanged text was screened when it was stored, and must not count as a repeat
+	var decision contentfilter.Decision
+	if body.Content != current {
+		decision = contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindComment, Text: body.Content})
+		if decision.Rejected() {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+			return
+		}
+	}
+
 	if err := queries.UpdateComment(commentID, userID, body.Content); err != nil {
 		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Not found or you don't own this comment"})
 		return
 	}
+	decision.Report("comment", commentID, userID, body.Content)
 
 	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Comment updated"})
 }
@@ -174,17 +196,19 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 func GetReplies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	commentIDStr := r.PathValue("commentId")
-	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid comment ID",
-		})
+	viewerID, _ := utils.GetUserIDFromContext(r)
+
+	post, ok := loadAccessiblePost(w, r, viewerID, policy.CanView)
+	if !ok {
+		return
+	}
+
+	commentID, ok := commentBelongsToPost(w, r, post.ID)
+	if !ok {
 		return
 	}
 
-	rawReplies, err := queries.GetReplies(commentID)
+	rawReplies, err := queries.GetReplies(commentID, viewerID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -222,23 +246,13 @@ func AddReply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postIDStr := r.PathValue("id")
-	postID, err := strconv.ParseInt(postIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid post ID",
-		})
+	post, ok := loadAccessiblePost(w, r, userID, policy.CanInteract)
+	if !ok {
 		return
 	}
 
-	commentIDStr := r.PathValue("commentId")
-	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid comment ID",
-		})
+	commentID, ok := commentBelongsToPost(w, r, post.ID)
+	if !ok {
 		return
 	}
 
@@ -261,7 +275,13 @@ func AddReply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	replyID, err := queries.AddReply(postID, commentID, userID, body.Content)
+	decision := contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindReply, Text: body.Content})
+	if decision.Rejected() {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+		return
+	}
+
+	replyID, err := queries.AddReply(post.ID, commentID, userID, body.Content)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -269,6 +289,7 @@ func AddReply(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	decision.Report("comment", replyID, userID, body.Content)
 
 	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success":  true,
@@ -1,12 +1,19 @@
 package posts
 
 import (
+	"backend/internal/audiences"
+	"backend/internal/contentfilter"
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
+	"backend/internal/policy"
 	"backend/internal/utils"
+	"backend/internal/webhooks"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // GetUserPostsHandler handles GET /api/users/{username}/posts
@@ -25,24 +32,10 @@ func GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit := 10
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if v, err2 := strconv.Atoi(l); err2 == nil && v > 0 && v <= 20 {
-			limit = v
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if v, err2 := strconv.Atoi(o); err2 == nil && v >= 0 {
-			offset = v
-		}
-	}
+	limit, offset := parsePagination(r, 10)
 
-	isOwner := viewerID == target.ID
-	followStatus, _ := queries.GetFollowStatus(viewerID, target.ID)
-	isFollower := followStatus == "accepted"
-
-	allPosts, err := queries.GetPostsByUserID(target.ID)
+	// Privacy is enforced in SQL by the shared visibility policy
+	posts, err := queries.GetVisiblePersonalPosts(viewerID, target.ID, limit+1, offset)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false, Message: "Failed to fetch posts",
@@ -50,103 +43,23 @@ func GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	author, err := queries.GetUserByID(target.ID)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
-			Success: false, Message: "Failed to fetch author",
-		})
-		return
-	}
-
-	type PostWithMeta struct {
-		models.Post
-		Author        *models.User `json:"author"`
-		Likes         int          `json:"likes"`
-		IsLiked       bool         `json:"is_liked"`
-		CommentsCount int          `json:"comments_count"`
-	}
-
-	visible := make([]PostWithMeta, 0)
-	for _, post := range allPosts {
-		if !isOwner {
-			switch post.Privacy {
-			case "public":
-				// visible to all
-			case "followers":
-				if !isFollower {
-					continue
-				}
-			case "selected":
-				ok, _ := queries.IsInSelectedFollowers(post.ID, viewerID)
-				if !ok {
-					continue
-				}
-			default:
-				continue
-			}
-		}
-
-		likesCount, _ := queries.GetPostLikesCount(post.ID)
-		isLiked := false
-		if viewerID != 0 {
-			isLiked, _ = queries.IsPostLikedByUser(post.ID, viewerID)
-		}
-		commentsCount, _ := queries.GetCommentCount(post.ID)
-
-		visible = append(visible, PostWithMeta{
-			Post:          post,
-			Author:        &author,
-			Likes:         likesCount,
-			IsLiked:       isLiked,
-			CommentsCount: commentsCount,
-		})
-	}
-
-	total := len(visible)
-	if offset >= total {
-		utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-			"success":  true,
-			"posts":    []interface{}{},
-			"has_more": false,
-		})
-		return
-	}
-	end := offset + limit
-	if end > total {
-		end = total
-	}
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"success":  true,
-		"posts":    visible[offset:end],
-		"has_more": end < total,
-	})
+	respondPostsPage(w, posts, limit)
 }
 
 // GetFeedPosts handles GET /api/posts
-// Privacy is enforced in Go after fetching all posts:
+// Privacy is enforced in SQL by the shared visibility policy (see policy.CanView):
 //   - public    → everyone sees it
 //   - followers → only accepted followers of the author see it
-//   - selected  → only users in post_selected_followers see it
+//   - selected  → only the members of its audience list, or without one the
+//     users in post_selected_followers, who still follow the author see it
 func GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	viewerID, _ := utils.GetUserIDFromContext(r)
 
-	limit := 5
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 20 {
-			limit = v
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
-			offset = v
-		}
-	}
+	limit, offset := parsePagination(r, 5)
 
-	// 1. Fetch all personal posts (no privacy filter in SQL)
-	allPosts, err := queries.GetAllPersonalPosts()
+	posts, err := queries.GetVisiblePersonalPosts(viewerID, 0, limit+1, offset)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -155,89 +68,41 @@ func GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Build a set of author IDs the viewer follows (for "followers" privacy)
-	followingIDs, _ := queries.GetFollowingIDs(viewerID)
-	followingSet := make(map[int]bool, len(followingIDs))
-	for _, id := range followingIDs {
-		followingSet[id] = true
-	}
-
-	type PostWithMeta struct {
-		models.Post
-		Author        *models.User `json:"author"`
-		Likes         int          `json:"likes"`
-		IsLiked       bool         `json:"is_liked"`
-		CommentsCount int          `json:"comments_count"`
-	}
-
-	result := make([]PostWithMeta, 0)
-
-	for _, post := range allPosts {
-		// 3. Apply privacy rules in Go
-		// Authors always see their own posts regardless of privacy
-		if post.UserID != viewerID {
-			switch post.Privacy {
-			case "public":
-				// visible to everyone — always include
-
-			case "followers":
-				// visible only to accepted followers of the author
-				if !followingSet[post.UserID] {
-					continue
-				}
-
-			case "selected":
-				// visible only to users in the post's selected-followers list
-				ok, _ := queries.IsInSelectedFollowers(post.ID, viewerID)
-				if !ok {
-					continue
-				}
-
-			default:
-				continue
-			}
-		}
+	respondPostsPage(w, posts, limit)
+}
 
-		// 4. Attach author, likes, comment count
-		author, err := queries.GetUserByID(post.UserID)
-		if err != nil {
-			continue
+// parsePagination reads limit (1-20) and offset query parameters.
+func parsePagination(r *http.Request, defaultLimit int) (limit, offset int) {
+	limit = defaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 20 {
+			limit = v
 		}
-
-		likesCount, _ := queries.GetPostLikesCount(post.ID)
-		isLiked := false
-		if viewerID != 0 {
-			isLiked, _ = queries.IsPostLikedByUser(post.ID, viewerID)
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
 		}
-		commentsCount, _ := queries.GetCommentCount(post.ID)
-
-		result = append(result, PostWithMeta{
-			Post:          post,
-			Author:        &author,
-			Likes:         likesCount,
-			IsLiked:       isLiked,
-			CommentsCount: commentsCount,
-		})
 	}
+	return limit, offset
+}
 
-	// Paginate the privacy-filtered result
-	total := len(result)
-	if offset >= total {
-		utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-			"success":  true,
-			"posts":    []interface{}{},
-			"has_more": false,
-		})
-		return
+// respondPostsPage writes a page of posts fetched with limit+1 rows,
+// using the extra row to report has_more.
+func respondPostsPage(w http.ResponseWriter, posts []models.PostWithMeta, limit int) {
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
 	}
-	end := offset + limit
-	if end > total {
-		end = total
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i].Post
 	}
+	media.ResolveSizes(refs...)
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"success":  true,
-		"posts":    result[offset:end],
-		"has_more": end < total,
+		"posts":    posts,
+		"has_more": hasMore,
 	})
 }
 
@@ -247,44 +112,13 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 
 	viewerID, _ := utils.GetUserIDFromContext(r)
 
-	postIDStr := r.PathValue("id")
-	postID, err := strconv.ParseInt(postIDStr, 10, 64)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid post ID"})
-		return
-	}
-
-	post, err := queries.GetPostByID(postID)
-	if err != nil {
-		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Post not found"})
+	post, ok := loadAccessiblePost(w, r, viewerID, policy.CanView)
+	if !ok {
 		return
 	}
-
-	// Privacy check
+	// Audience lists are private to their owner
 	if post.UserID != viewerID {
-		switch post.Privacy {
-		case "public":
-			// ok
-		case "followers":
-			followingIDs, _ := queries.GetFollowingIDs(viewerID)
-			followingSet := make(map[int]bool, len(followingIDs))
-			for _, id := range followingIDs {
-				followingSet[id] = true
-			}
-			if !followingSet[post.UserID] {
-				utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Access denied"})
-				return
-			}
-		case "selected":
-			ok, _ := queries.IsInSelectedFollowers(postID, viewerID)
-			if !ok {
-				utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Access denied"})
-				return
-			}
-		default:
-			utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Access denied"})
-			return
-		}
+		post.AudienceListID = nil
 	}
 
 	author, err := queries.GetUserByID(post.UserID)
@@ -293,24 +127,17 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	likesCount, _ := queries.GetPostLikesCount(postID)
+	likesCount, _ := queries.GetPostLikesCount(post.ID)
 	isLiked := false
 	if viewerID != 0 {
-		isLiked, _ = queries.IsPostLikedByUser(postID, viewerID)
-	}
-	commentsCount, _ := queries.GetCommentCount(postID)
-
-	type PostWithMeta struct {
-		models.Post
-		Author        *models.User `json:"author"`
-		Likes         int          `json:"likes"`
-		IsLiked       bool         `json:"is_liked"`
-		CommentsCount int          `json:"comments_count"`
+		isLiked, _ = queries.IsPostLikedByUser(post.ID, viewerID)
 	}
+	commentsCount, _ := queries.GetCommentCount(post.ID)
+	media.ResolveSizes(&post)
 
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"post": PostWithMeta{
+		"post": models.PostWithMeta{
 			Post:          post,
 			Author:        &author,
 			Likes:         likesCount,
@@ -321,7 +148,11 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreatePost handles POST /api/posts
-// Accepts multipart form: content, privacy (public|followers|selected), image (optional)
+// Accepts multipart form: content, privacy (public|followers|selected), and 1 to
+// media.MaxAttachments files as attachments, each with an alt_text value in the
+// same order. A single image file is accepted instead for older clients.
+// "selected" posts are shared with selected_users, or with the audience list
+// audience_list_id, which makes the post "selected" on its own.
 func CreatePost(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -351,28 +182,62 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checked before the upload is saved, so rejected posts leave no file behind
+	decision := contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindPost, Text: content})
+	if decision.Rejected() {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+		return
+	}
+
 	privacy := r.FormValue("privacy")
 	validPrivacy := map[string]bool{"public": true, "followers": true, "selected": true}
 	if !validPrivacy[privacy] {
 		privacy = "public"
 	}
 
-	var imagePath *string
-	file, handler, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-		path, err := utils.SaveUploadedFile(file, handler, "posts")
+	// A post shared with an audience list is "selected", its audience being the
+	// members of the list when it is viewed
+	var listID *int64
+	if raw := r.FormValue("audience_list_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
 		if err != nil {
-			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
-				Success: false,
-				Message: err.Error(),
-			})
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid audience_list_id"})
+			return
+		}
+		if r.FormValue("selected_users") != "" {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Give either selected_users or audience_list_id"})
+			return
+		}
+		if !checkAudienceList(w, userID, id) {
+			return
+		}
+		privacy, listID = "selected", &id
+	}
+
+	// The followers a "selected" post is shared with, as a JSON array of user IDs
+	var audience []int
+	if privacy == "selected" && listID == nil {
+		if raw := r.FormValue("selected_users"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &audience); err != nil {
+				utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid selected_users"})
+				return
+			}
+		}
+		if !audiences.CheckFollowers(w, userID, audience) {
 			return
 		}
-		imagePath = &path
 	}
 
-	if imagePath == nil {
+	attachments, err := media.SaveAttachments(r.MultipartForm, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if len(attachments) == 0 {
 		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 			Success: false,
 			Message: "Post must include a photo, GIF, or video",
@@ -380,8 +245,9 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postID, err := queries.CreatePost(userID, content, imagePath, privacy)
+	postID, err := queries.CreatePost(userID, content, attachments, privacy, listID, audience)
 	if err != nil {
+		media.ReleaseAttachments(attachments)
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
 			Message: "Failed to create post",
@@ -389,25 +255,25 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save selected followers for "selected" privacy posts
-	if privacy == "selected" {
-		if raw := r.FormValue("selected_users"); raw != "" {
-			var userIDs []int
-			if err := json.Unmarshal([]byte(raw), &userIDs); err == nil {
-				_ = queries.SetSelectedFollowers(postID, userIDs)
-			}
-		}
-	}
+	decision.Report("post", postID, userID, content)
+
+	webhooks.EmitAccountEvent(userID, webhooks.EventPostCreated, userID, map[string]interface{}{
+		"post": models.Post{ID: postID, UserID: userID, Content: content, ImagePath: attachments[0].URL, Attachments: attachments, Privacy: privacy, AudienceListID: listID, CreatedAt: time.Now().UTC().Format("2006-01-02 15:04:05")},
+	})
 
 	utils.RespondJSON(w, http.StatusCreated, map[string]any{
-		"success": true,
-		"message": "Post created successfully",
-		"post_id": postID,
+		"success":     true,
+		"message":     "Post created successfully",
+		"post_id":     postID,
+		"attachments": attachments,
 	})
 }
 
 // UpdatePost handles PUT /api/posts/{id}
-// Accepts JSON: { content, privacy }
+// Accepts JSON: { content, privacy, attachments, selected_users,
+// audience_list_id }, or the same fields as a multipart form with attachments
+// JSON-encoded, to upload new files. When attachments is given it becomes the
+// post's list, in order (see attachmentEdit); attachments left out are removed.
 func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -431,8 +297,8 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Ensure the caller owns the post
-	ownerID, err := queries.GetPostOwnerID(postID)
-	if err != nil || ownerID != userID {
+	post, err := queries.GetPostByID(postID)
+	if err != nil || post.UserID != userID {
 		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
 			Success: false,
 			Message: "You can only edit your own posts",
@@ -440,15 +306,23 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var body struct {
-		Content string `json:"content"`
-		Privacy string `json:"privacy"`
+	if window := editWindow(); window > 0 {
+		createdAt, err := queries.GetPostCreatedAt(postID)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update post"})
+			return
+		}
+		if time.Since(createdAt) > window {
+			utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+				Success: false,
+				Message: fmt.Sprintf("Posts can only be edited within %s of posting", window),
+			})
+			return
+		}
 	}
-	if err := utils.ParseJSON(r, &body); err != nil {
-		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+
+	body, ok := parsePostUpdate(w, r)
+	if !ok {
 		return
 	}
 
@@ -465,16 +339,73 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		body.Privacy = "public"
 	}
 
-	if err := queries.UpdatePost(postID, body.Content, body.Privacy); err != nil {
+	// Unchanged text was screened when it was stored; checking it again would
+	// count edits of the privacy or attachments as repeats
+	var decision contentfilter.Decision
+	if body.Content != post.Content {
+		decision = contentfilter.Check(contentfilter.Input{UserID: userID, Kind: contentfilter.KindPost, Text: body.Content})
+		if decision.Rejected() {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: decision.Message()})
+			return
+		}
+	}
+
+	// A "selected" post stays shared with its audience list unless it is given
+	// another list or selected_users
+	var listID *int64
+	if body.AudienceListID != nil {
+		if body.SelectedUsers != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Give either selected_users or audience_list_id"})
+			return
+		}
+		if !checkAudienceList(w, userID, *body.AudienceListID) {
+			return
+		}
+		body.Privacy, listID = "selected", body.AudienceListID
+	} else if body.Privacy == "selected" && body.SelectedUsers == nil {
+		listID = post.AudienceListID
+	}
+
+	var audience []int
+	if body.Privacy == "selected" && body.SelectedUsers != nil {
+		audience = *body.SelectedUsers
+		if !audiences.CheckFollowers(w, userID, audience) {
+			return
+		}
+	}
+
+	var attachments, added []models.PostAttachment
+	if body.Attachments != nil {
+		attachments, added, ok = applyAttachmentEdits(w, r, post, *body.Attachments)
+		if !ok {
+			return
+		}
+	}
+
+	before := onlineViewers(post)
+	removed, edited, err := queries.UpdatePost(postID, body.Content, body.Privacy, listID, attachments, audience)
+	if err != nil {
+		media.ReleaseAttachments(added)
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
 			Message: "Failed to update post",
 		})
 		return
 	}
+	for _, url := range removed {
+		media.Release(url)
+	}
+	audiences.NotifyLostAccess([]int64{post.ID}, before)
+	decision.Report("post", postID, userID, body.Content)
 
-	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
-		Success: true,
-		Message: "Post updated successfully",
+	if attachments == nil {
+		attachments = post.Attachments
+	}
+	media.ResolveSizes(&models.Post{Attachments: attachments})
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"message":     "Post updated successfully",
+		"attachments": attachments,
+		"edited":      edited || post.Edited,
 	})
 }
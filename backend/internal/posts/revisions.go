@@ -0,0 +1,50 @@
+package posts
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/policy"
+	"backend/internal/utils"
+	"net/http"
+	"os"
+	"time"
+)
+
+// editWindow is how long after posting a post may be edited
+// (POST_EDIT_WINDOW). 0, the default, allows edits at any time.
+func editWindow() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("POST_EDIT_WINDOW")); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// GetPostRevisions handles GET /api/posts/{id}/revisions: the texts the post
+// had, oldest first, for whoever can see it. The first has no edited_at; a
+// post never edited has only its current text.
+func GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	viewerID, _ := utils.GetUserIDFromContext(r)
+
+	post, ok := loadAccessiblePost(w, r, viewerID, policy.CanView)
+	if !ok {
+		return
+	}
+
+	revisions, err := queries.GetPostRevisions(post.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch revisions"})
+		return
+	}
+	if len(revisions) == 0 {
+		revisions = []models.PostRevision{{Content: post.Content}}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"created_at": post.CreatedAt,
+		"edited_at":  post.EditedAt,
+		"revisions":  revisions,
+	})
+}
@@ -2,13 +2,17 @@ package profile
 
 import (
 	"backend/internal/db/queries"
+	"backend/internal/deletion"
 	"backend/internal/models"
 	"backend/internal/utils"
+	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
+	"time"
 )
 
+// DeleteProfile schedules the deletion of the current account after the grace
+// period, logs it out everywhere and clears the session cookie. Logging in
+// again before the deletion runs cancels it.
 func DeleteProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if r.Method != http.MethodDelete {
@@ -26,9 +30,7 @@ func DeleteProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user data for cleanup
-	user, err := queries.GetUserByID(userID)
-	if err != nil {
+	if _, err := queries.GetUserByID(userID); err != nil {
 		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{
 			Success: false,
 			Message: "User not found",
@@ -36,14 +38,7 @@ func DeleteProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete avatar file if exists
-	if user.Avatar != "" {
-		avatarPath := filepath.Join(".", user.Avatar)
-		os.Remove(avatarPath) // Ignore errors
-	}
-
-	// Delete user from database (cascading deletes should handle related data)
-	_, err = queries.DB.Exec("DELETE FROM users WHERE id = ?", userID)
+	deleteAt, err := deletion.Schedule(userID)
 	if err != nil {
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
@@ -54,17 +49,19 @@ func DeleteProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Clear session cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
+		Name:     "session_id",
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
+		Expires:  time.Now().Add(-1 * time.Hour),
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteLaxMode,
 	})
 
-	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{
-		Success: true,
-		Message: "Account deleted successfully",
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   fmt.Sprintf("Your account will be deleted on %s. Log in before then to cancel", deleteAt.Format("Mon 2 Jan 2006 at 15:04 MST")),
+		"delete_at": deleteAt,
 	})
 }
@@ -3,12 +3,11 @@ package profile
 import (
 	"backend/internal/auth"
 	"backend/internal/db/queries"
+	"backend/internal/media"
 	"backend/internal/models"
 	"backend/internal/utils"
 	"backend/internal/ws"
 	"net/http"
-	"os"
-	"path/filepath"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -136,14 +135,7 @@ func EditProfile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Delete old avatar if exists
-		if currentUser.Avatar != "" {
-			oldPath := filepath.Join(".", currentUser.Avatar)
-			os.Remove(oldPath) // Ignore errors
-		}
-
-		// Save uploaded file using utils function
-		savedPath, err := utils.SaveUploadedFile(file, header, "avatars")
+		urls, err := media.SaveUploadedFile(file, header, "avatars", userID)
 		if err != nil {
 			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{
 				Success: false,
@@ -152,7 +144,7 @@ func EditProfile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		avatarPath = savedPath
+		avatarPath = urls.Original
 	}
 
 	// Update user
@@ -170,6 +162,9 @@ func EditProfile(w http.ResponseWriter, r *http.Request) {
 
 	if err := queries.UpdateUser(userID, updateReq); err != nil {
 		println("UpdateUser failed:", err.Error())
+		if avatarPath != currentUser.Avatar {
+			media.Release(avatarPath)
+		}
 		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
 			Success: false,
 			Message: "Failed to update profile: " + err.Error(),
@@ -177,6 +172,11 @@ func EditProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The old avatar goes once the new one is in place
+	if avatarPath != currentUser.Avatar {
+		media.Release(currentUser.Avatar)
+	}
+
 	println("Profile updated successfully for user:", userID)
 
 	// Update password if provided
@@ -0,0 +1,233 @@
+// Package reminders notifies "going" respondents ahead of group events, using
+// the scheduler to run one job per event occurrence and reminder offset.
+package reminders
+
+import (
+	"backend/internal/calendar"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	activity "backend/internal/notifications"
+	"backend/internal/scheduler"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	jobKind = "event_reminder"
+	// maxOffsets bounds the number of reminders per occurrence
+	maxOffsets = 5
+	// lookahead bounds how many cancelled occurrences are skipped when looking for the next one
+	lookahead = 50
+)
+
+var defaultOffsets = []time.Duration{24 * time.Hour, time.Hour}
+
+type payload struct {
+	EventID        int64  `json:"event_id"`
+	OccurrenceDate string `json:"occurrence_date,omitempty"` // empty for single events
+	Offset         string `json:"offset"`
+}
+
+// Register installs the reminder job handler. Call it before scheduler.Run.
+func Register() {
+	scheduler.Register(jobKind, run)
+}
+
+// Offsets returns the reminder offsets configured in EVENT_REMINDER_OFFSETS
+// (comma-separated durations such as "24h,1h"), or the defaults.
+func Offsets() []time.Duration {
+	raw := strings.TrimSpace(os.Getenv("EVENT_REMINDER_OFFSETS"))
+	if raw == "" {
+		return defaultOffsets
+	}
+
+	var offsets []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			fmt.Printf("reminders: ignoring invalid offset %q\n", part)
+			continue
+		}
+		if len(offsets) == maxOffsets {
+			break
+		}
+		offsets = append(offsets, d)
+	}
+	if len(offsets) == 0 {
+		return defaultOffsets
+	}
+	return offsets
+}
+
+// emailEnabled reports whether reminders are also sent by email (EVENT_REMINDER_EMAIL=true).
+func emailEnabled() bool {
+	return os.Getenv("EVENT_REMINDER_EMAIL") == "true"
+}
+
+func keyPrefix(eventID int64) string {
+	return fmt.Sprintf("%s:%d:", jobKind, eventID)
+}
+
+// ScheduleEvent schedules the upcoming reminders of an event. Recurring events
+// get reminders for their next occurrence; each reminder schedules the following one.
+func ScheduleEvent(event models.GroupEvent) error {
+	now := time.Now()
+	for _, offset := range Offsets() {
+		if err := scheduleNext(event, offset, now.Add(offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelEvent cancels every pending reminder of an event.
+func CancelEvent(eventID int64) error {
+	return scheduler.Cancel(keyPrefix(eventID))
+}
+
+// scheduleNext schedules the reminder at offset before the first occurrence starting after from.
+func scheduleNext(event models.GroupEvent, offset time.Duration, from time.Time) error {
+	start, date, ok, err := nextOccurrence(event, from)
+	if err != nil || !ok {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s:%s", keyPrefix(event.ID), date, offset)
+	return scheduler.Schedule(jobKind, key, start.Add(-offset), payload{
+		EventID:        event.ID,
+		OccurrenceDate: date,
+		Offset:         offset.String(),
+	})
+}
+
+// nextOccurrence returns the start and occurrence date of the first non-cancelled
+// occurrence starting after from.
+func nextOccurrence(event models.GroupEvent, from time.Time) (start time.Time, date string, ok bool, err error) {
+	if event.RecurrenceRule == "" {
+		return event.EventTime, "", event.EventTime.After(from), nil
+	}
+
+	rule, err := calendar.ParseRule(event.RecurrenceRule)
+	if err != nil {
+		return start, "", false, err
+	}
+	exceptions, err := queries.GetEventExceptions(event.ID)
+	if err != nil {
+		return start, "", false, err
+	}
+	cancelled := make(map[string]bool, len(exceptions))
+	for _, ex := range exceptions {
+		cancelled[ex] = true
+	}
+
+	for _, t := range rule.Between(event.EventTime, from.Add(time.Second), from.AddDate(10, 0, 0), lookahead) {
+		if d := t.Format("2006-01-02"); !cancelled[d] {
+			return t, d, true, nil
+		}
+	}
+	return start, "", false, nil
+}
+
+func run(data []byte) error {
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	offset, err := time.ParseDuration(p.Offset)
+	if err != nil {
+		return err
+	}
+
+	event, err := queries.GetGroupEventByID(p.EventID)
+	if err == sql.ErrNoRows {
+		// Deleted after the reminder was scheduled
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	start := event.EventTime
+	if p.OccurrenceDate != "" {
+		day, err := time.Parse("2006-01-02", p.OccurrenceDate)
+		if err != nil {
+			return err
+		}
+		hour, min, sec := event.EventTime.Clock()
+		start = time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, 0, event.EventTime.Location())
+
+		// Keep the chain going before notifying, so a failure below does not end it.
+		// Occurrences whose reminder time already passed are skipped.
+		next := start
+		if earliest := time.Now().Add(offset); earliest.After(next) {
+			next = earliest
+		}
+		if err := scheduleNext(event, offset, next); err != nil {
+			fmt.Printf("reminders: failed to schedule next reminder for event %d: %v\n", event.ID, err)
+		}
+
+		exceptions, err := queries.GetEventExceptions(event.ID)
+		if err != nil {
+			return err
+		}
+		for _, ex := range exceptions {
+			if ex == p.OccurrenceDate {
+				return nil
+			}
+		}
+	}
+
+	userIDs, err := queries.GetEventRespondentIDs(event.ID, p.OccurrenceDate, "going")
+	if err != nil {
+		return err
+	}
+
+	text := activity.EventReminder(event.Title, start)
+	for _, userID := range userIDs {
+		_ = activity.NotifyRecentActivity(userID, nil, jobKind, text, map[string]interface{}{
+			"event_id":        event.ID,
+			"group_id":        event.GroupID,
+			"occurrence_date": p.OccurrenceDate,
+			"starts_at":       start.Format(queries.EventTimeLayout),
+		})
+
+		if emailEnabled() {
+			if err := sendEmail(userID, event, text); err != nil {
+				fmt.Printf("reminders: failed to email user %d: %v\n", userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func sendEmail(userID int, event models.GroupEvent, text activity.RecentActivityText) error {
+	user, err := queries.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	var details strings.Builder
+	if event.Location != "" {
+		details.WriteString("<p>Location: " + html.EscapeString(event.Location) + "</p>")
+	}
+	if event.Description != "" {
+		details.WriteString("<p>" + html.EscapeString(event.Description) + "</p>")
+	}
+	body := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family:'Segoe UI',Arial,sans-serif;">
+  <p>Hi %s,</p>
+  <p><strong>%s</strong></p>
+  %s
+</body>
+</html>`, html.EscapeString(user.FirstName), html.EscapeString(text.Message), details.String())
+
+	return utils.SendEmail(user.Email, text.Subtitle+": "+event.Title, body)
+}
@@ -0,0 +1,181 @@
+// Package scheduler runs persistent background jobs inside the server process.
+//
+// Jobs live in the scheduled_jobs table, so they survive restarts. Every job is
+// claimed atomically before its handler runs, which makes execution at-most-once:
+// a job interrupted by a crash is marked failed on the next start instead of being
+// run again.
+package scheduler
+
+import (
+	"backend/internal/db/queries"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Handler runs a job with the JSON payload it was scheduled with.
+type Handler func(payload []byte) error
+
+const (
+	pollInterval = 15 * time.Second
+	batchSize    = 20
+	// Finished jobs are kept this long for inspection before being purged
+	retention       = 7 * 24 * time.Hour
+	cleanupInterval = time.Hour
+)
+
+var (
+	mu       sync.RWMutex
+	handlers = make(map[string]Handler)
+	// periodic holds the interval of job kinds that repeat forever
+	periodic = make(map[string]time.Duration)
+
+	// wake interrupts the poll wait when a job is scheduled to run soon
+	wake = make(chan struct{}, 1)
+)
+
+// Register installs the handler for a job kind. It must be called before Run.
+func Register(kind string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[kind] = h
+}
+
+// RegisterPeriodic installs the handler for a job kind that runs at every multiple
+// of interval (e.g. at the top of every hour). Each kind has a single pending job,
+// keyed by the kind itself. It must be called before Run.
+func RegisterPeriodic(kind string, interval time.Duration, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[kind] = h
+	periodic[kind] = interval
+}
+
+// schedulePeriodic schedules the next run of a periodic job kind.
+func schedulePeriodic(kind string, interval time.Duration) error {
+	next := time.Now().Truncate(interval).Add(interval)
+	return Schedule(kind, kind, next, nil)
+}
+
+// Schedule stores a job of the given kind to run at runAt. key identifies the job
+// for Cancel; scheduling an existing key replaces its run time and payload.
+func Schedule(kind, key string, runAt time.Time, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if err := queries.UpsertScheduledJob(kind, key, string(data), runAt); err != nil {
+		return err
+	}
+	if time.Until(runAt) < pollInterval {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Cancel cancels every pending job whose key starts with keyPrefix.
+func Cancel(keyPrefix string) error {
+	_, err := queries.CancelScheduledJobs(keyPrefix)
+	return err
+}
+
+// Run executes due jobs until ctx is cancelled.
+func Run(ctx context.Context) {
+	if n, err := queries.FailInterruptedJobs(); err != nil {
+		fmt.Printf("scheduler: failed to recover interrupted jobs: %v\n", err)
+	} else if n > 0 {
+		fmt.Printf("scheduler: %d job(s) interrupted by the last shutdown were not retried\n", n)
+	}
+
+	// Interrupted periodic jobs are failed above, so every chain is restarted here
+	mu.RLock()
+	for kind, interval := range periodic {
+		if err := schedulePeriodic(kind, interval); err != nil {
+			fmt.Printf("scheduler: failed to schedule periodic job %s: %v\n", kind, err)
+		}
+	}
+	mu.RUnlock()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+
+	for {
+		runDue()
+
+		if time.Since(lastCleanup) > cleanupInterval {
+			if err := queries.DeleteFinishedJobs(time.Now().Add(-retention)); err != nil {
+				fmt.Printf("scheduler: cleanup failed: %v\n", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// runDue claims and runs jobs until none are due.
+func runDue() {
+	for {
+		jobs, err := queries.GetDueScheduledJobs(time.Now(), batchSize)
+		if err != nil {
+			fmt.Printf("scheduler: failed to load due jobs: %v\n", err)
+			return
+		}
+		for _, job := range jobs {
+			claimed, err := queries.ClaimScheduledJob(job.ID)
+			if err != nil {
+				fmt.Printf("scheduler: failed to claim job %d: %v\n", job.ID, err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			if err := queries.FinishScheduledJob(job.ID, execute(job)); err != nil {
+				fmt.Printf("scheduler: failed to record result of job %d: %v\n", job.ID, err)
+			}
+
+			mu.RLock()
+			interval, repeats := periodic[job.Kind]
+			mu.RUnlock()
+			if repeats {
+				if err := schedulePeriodic(job.Kind, interval); err != nil {
+					fmt.Printf("scheduler: failed to schedule periodic job %s: %v\n", job.Kind, err)
+				}
+			}
+		}
+		if len(jobs) < batchSize {
+			return
+		}
+	}
+}
+
+// execute runs the job's handler, turning a panic into an error.
+func execute(job queries.ScheduledJob) (err error) {
+	mu.RLock()
+	h, ok := handlers[job.Kind]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for %q", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if err = h([]byte(job.Payload)); err != nil {
+		fmt.Printf("scheduler: job %d (%s) failed: %v\n", job.ID, job.Key, err)
+	}
+	return err
+}
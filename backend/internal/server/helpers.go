@@ -11,3 +11,7 @@ func Auth(h http.HandlerFunc) http.Handler {
 func authHandle(mux *http.ServeMux, path string, h http.HandlerFunc) {
 	mux.Handle(path, Auth(h))
 }
+
+func adminHandle(mux *http.ServeMux, path string, h http.HandlerFunc) {
+	mux.Handle(path, AuthMiddleware(SiteAdminMiddleware(h)))
+}
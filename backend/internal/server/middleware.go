@@ -40,6 +40,23 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Suspended users are turned away even if a session survived the suspension
+		suspension, err := queries.GetActiveSuspension(session.UserID)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+				Success: false,
+				Message: "Failed to verify session",
+			})
+			return
+		}
+		if suspension != nil {
+			utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+				Success: false,
+				Message: suspension.Message(),
+			})
+			return
+		}
+
 		// Fingerprint check removed - was causing false positives
 		// Add userID to context
 		ctx := context.WithValue(r.Context(), "userID", session.UserID)
@@ -49,3 +66,35 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// SiteAdminMiddleware only lets site admins through. It must run after AuthMiddleware.
+func SiteAdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := utils.GetUserIDFromContext(r)
+		if !ok {
+			utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{
+				Success: false,
+				Message: "Unauthorized",
+			})
+			return
+		}
+
+		isAdmin, err := queries.IsSiteAdmin(userID)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+				Success: false,
+				Message: "Failed to verify permissions",
+			})
+			return
+		}
+		if !isAdmin {
+			utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+				Success: false,
+				Message: "Site admin access required",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,15 +1,20 @@
 package server
 
 import (
+	"backend/internal/audiences"
 	"backend/internal/auth"
 	"backend/internal/chat"
+	"backend/internal/dataexport"
 	"backend/internal/groups"
+	"backend/internal/media"
+	"backend/internal/moderation"
 	"backend/internal/notifications"
 	"backend/internal/otp"
 	"backend/internal/posts"
 	"backend/internal/follow"
 	"backend/internal/profile"
 	"backend/internal/users"
+	"backend/internal/webhooks"
 	"backend/internal/ws"
 	"net/http"
 )
@@ -29,16 +34,27 @@ func SetupRoutes(mux *http.ServeMux) {
 	authHandle(mux, "PUT /api/profile", profile.ProfileHandler)
 	authHandle(mux, "DELETE /api/profile", profile.ProfileHandler)
 
+	// ===== ACCOUNT DATA =====
+	authHandle(mux, "POST /api/account/exports", dataexport.RequestExport)
+	authHandle(mux, "GET /api/account/exports", dataexport.ListExports)
+	authHandle(mux, "GET /api/account/exports/{id}/download", dataexport.DownloadExport)
+
 	// ===== USERS =====
 	// Exact routes must come before the wildcard {username} route
 	authHandle(mux, "GET /api/users/search", users.SearchUsersHandler)
 	authHandle(mux, "GET /api/users/contacts", users.GetContactsHandler)
 	authHandle(mux, "GET /api/users/following", users.GetFollowingHandler)
+	authHandle(mux, "GET /api/users/blocked", users.GetBlockedUsersHandler)
+	authHandle(mux, "GET /api/users/muted", users.GetMutedUsersHandler)
 	authHandle(mux, "GET /api/users/{username}", users.GetPublicProfileHandler)
 	authHandle(mux, "GET /api/users/{username}/followers", follow.GetFollowersHandler)
 	authHandle(mux, "GET /api/users/{username}/following", follow.GetFollowingListHandler)
 	authHandle(mux, "GET /api/users/{username}/posts", posts.GetUserPostsHandler)
 	authHandle(mux, "GET /api/users/{username}/stats", users.GetUserStatsHandler)
+	authHandle(mux, "POST /api/users/{username}/block", users.BlockUserHandler)
+	authHandle(mux, "DELETE /api/users/{username}/block", users.UnblockUserHandler)
+	authHandle(mux, "POST /api/users/{username}/mute", users.MuteUserHandler)
+	authHandle(mux, "DELETE /api/users/{username}/mute", users.UnmuteUserHandler)
 
 	// ===== FOLLOW =====
 	authHandle(mux, "POST /api/follow/{username}", follow.FollowHandler)
@@ -79,7 +95,19 @@ func SetupRoutes(mux *http.ServeMux) {
 	authHandle(mux, "POST /api/groups/events", groups.CreateAnEvent)
 	authHandle(mux, "POST /api/groups/events/respond", groups.RespondToEvent)
 	authHandle(mux, "GET /api/groups/events/responses", groups.GetEventResponsesHandler)
+	authHandle(mux, "PUT /api/groups/events/{id}", groups.EditAnEvent)
 	authHandle(mux, "DELETE /api/groups/events/{id}", groups.DeleteAnEvent)
+	authHandle(mux, "GET /api/groups/events/{id}/occurrences", groups.GetEventOccurrences)
+	authHandle(mux, "POST /api/groups/events/{id}/exceptions", groups.AddEventException)
+	authHandle(mux, "DELETE /api/groups/events/{id}/exceptions/{date}", groups.RemoveEventException)
+	authHandle(mux, "GET /api/groups/events/{id}/ics", groups.ExportEventICS)
+	authHandle(mux, "GET /api/groups/{id}/events.ics", groups.ExportGroupEventsICS)
+
+	// ===== CALENDAR =====
+	authHandle(mux, "GET /api/calendar/token", groups.GetCalendarToken)
+	authHandle(mux, "POST /api/calendar/token/rotate", groups.RotateCalendarToken)
+	// Public: authenticated by the private token in the URL
+	mux.HandleFunc("GET /api/calendar/feed/{token}", groups.CalendarFeed)
 
 	// ===== OTP VERIFICATION =====
 	authHandle(mux, "POST /api/otp/send", otp.SendOTPHandler)
@@ -90,6 +118,11 @@ func SetupRoutes(mux *http.ServeMux) {
 	authHandle(mux, "POST /api/posts", posts.CreatePost)
 	authHandle(mux, "GET /api/posts/{id}", posts.GetPost)
 	authHandle(mux, "PUT /api/posts/{id}", posts.UpdatePost)
+	authHandle(mux, "GET /api/posts/{id}/revisions", posts.GetPostRevisions)
+	authHandle(mux, "GET /api/posts/{id}/audience", posts.GetPostAudience)
+	authHandle(mux, "PUT /api/posts/{id}/audience", posts.ReplacePostAudience)
+	authHandle(mux, "POST /api/posts/{id}/audience", posts.AddToPostAudience)
+	authHandle(mux, "DELETE /api/posts/{id}/audience/{userId}", posts.RemoveFromPostAudience)
 	authHandle(mux, "GET /api/posts/{id}/comments", posts.GetComments)
 	authHandle(mux, "POST /api/posts/{id}/comments", posts.AddComment)
 	authHandle(mux, "DELETE /api/posts/{id}/comments/{commentId}", posts.DeleteComment)
@@ -99,10 +132,58 @@ func SetupRoutes(mux *http.ServeMux) {
 	authHandle(mux, "POST /posts/{id}/like", groups.PostLike)
 	authHandle(mux, "DELETE /posts/{id}", groups.DeletePost)
 
+	// ===== AUDIENCE LISTS =====
+	authHandle(mux, "GET /api/audience-lists", audiences.GetListsHandler)
+	authHandle(mux, "POST /api/audience-lists", audiences.CreateListHandler)
+	authHandle(mux, "GET /api/audience-lists/{id}", audiences.GetListHandler)
+	authHandle(mux, "PUT /api/audience-lists/{id}", audiences.RenameListHandler)
+	authHandle(mux, "DELETE /api/audience-lists/{id}", audiences.DeleteListHandler)
+	authHandle(mux, "POST /api/audience-lists/{id}/members", audiences.AddMembersHandler)
+	authHandle(mux, "DELETE /api/audience-lists/{id}/members/{userId}", audiences.RemoveMemberHandler)
+
 	// ===== NOTIFICATIONS =====
 	authHandle(mux, "GET /api/notifications", notifications.ListNotifications)
+	authHandle(mux, "GET /api/notifications/unread-count", notifications.UnreadNotificationCount)
 	authHandle(mux, "POST /api/notifications/read", notifications.MarkNotificationRead)
 	authHandle(mux, "POST /api/notifications/read-all", notifications.MarkAllNotificationsRead)
+	authHandle(mux, "GET /api/notifications/preferences", notifications.GetNotificationPreferences)
+	authHandle(mux, "PUT /api/notifications/preferences", notifications.UpdateNotificationPreferences)
+	authHandle(mux, "GET /api/notifications/mutes", notifications.ListNotificationMutes)
+	authHandle(mux, "POST /api/notifications/mutes", notifications.MuteTarget)
+	authHandle(mux, "DELETE /api/notifications/mutes/{type}/{id}", notifications.UnmuteTarget)
+	authHandle(mux, "GET /api/notifications/digest", notifications.GetDigestSettings)
+	authHandle(mux, "PUT /api/notifications/digest", notifications.UpdateDigestSettings)
+	mux.HandleFunc("GET /api/notifications/digest/unsubscribe", notifications.UnsubscribeDigest)
+	mux.HandleFunc("POST /api/notifications/digest/unsubscribe", notifications.UnsubscribeDigest)
+	authHandle(mux, "GET /api/push/vapid-public-key", notifications.GetVAPIDPublicKey)
+	authHandle(mux, "GET /api/push/subscriptions", notifications.ListPushSubscriptions)
+	authHandle(mux, "POST /api/push/subscriptions", notifications.SubscribePush)
+	authHandle(mux, "DELETE /api/push/subscriptions/{id}", notifications.UnsubscribePush)
+
+	// ===== WEBHOOKS =====
+	authHandle(mux, "GET /api/webhooks", webhooks.ListWebhooks)
+	authHandle(mux, "POST /api/webhooks", webhooks.CreateWebhook)
+	authHandle(mux, "GET /api/webhooks/{id}", webhooks.GetWebhook)
+	authHandle(mux, "PUT /api/webhooks/{id}", webhooks.UpdateWebhook)
+	authHandle(mux, "DELETE /api/webhooks/{id}", webhooks.DeleteWebhook)
+	authHandle(mux, "POST /api/webhooks/{id}/rotate-secret", webhooks.RotateWebhookSecret)
+	authHandle(mux, "POST /api/webhooks/{id}/test", webhooks.SendTestEvent)
+	authHandle(mux, "GET /api/webhooks/{id}/deliveries", webhooks.ListWebhookDeliveries)
+
+	// ===== MODERATION =====
+	authHandle(mux, "GET /api/reports", moderation.ListMyReports)
+	authHandle(mux, "POST /api/reports", moderation.CreateReport)
+	adminHandle(mux, "GET /api/admin/reports", moderation.ListReports)
+	adminHandle(mux, "GET /api/admin/reports/{id}", moderation.GetReport)
+	adminHandle(mux, "POST /api/admin/reports/{id}/triage", moderation.TriageReport)
+	adminHandle(mux, "POST /api/admin/reports/{id}/resolve", moderation.ResolveReport)
+	adminHandle(mux, "GET /api/admin/moderation-log", moderation.ListModerationLog)
+	adminHandle(mux, "GET /api/admin/users", moderation.SearchUsers)
+	adminHandle(mux, "GET /api/admin/users/{id}", moderation.GetUser)
+	adminHandle(mux, "GET /api/admin/users/{id}/content", moderation.GetUserContent)
+	adminHandle(mux, "POST /api/admin/users/{id}/suspend", moderation.SuspendUser)
+	adminHandle(mux, "DELETE /api/admin/users/{id}/suspend", moderation.UnsuspendUser)
+	adminHandle(mux, "POST /api/admin/users/{id}/logout", moderation.LogoutUser)
 
 	// ===== PRIVATE CHAT =====
 	authHandle(mux, "GET /api/chats/private", chat.GetPrivateConversations)
@@ -110,5 +191,8 @@ func SetupRoutes(mux *http.ServeMux) {
 	authHandle(mux, "GET /api/chats/private/{conversationID}/messages", chat.GetPrivateChatMessages)
 
 	// ===== FILES =====
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
+	// Media checks the session itself so <img> tags and signed URLs work
+	mux.HandleFunc("GET /media/{file}", media.Serve)
+	mux.HandleFunc("GET /media/{id}/{file}", media.ServeVariant)
+	authHandle(mux, "POST /api/media/signed-urls", media.SignURLs)
 }
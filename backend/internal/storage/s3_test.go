@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-test-1"
+	testBucket    = "media"
+)
+
+var authorizationFormat = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=` + testAccessKey +
+	`/(\d{8})/` + testRegion + `/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// fakeS3 is a local stand-in for an S3 bucket. It checks the signature of every
+// request and answers like S3 for the calls the store makes.
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+	ranges  []string // Range headers of the GETs received
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	store, err := NewS3(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          testBucket,
+		Region:          testRegion,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, store
+}
+
+func (f *fakeS3) object(key string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[key]
+}
+
+func (f *fakeS3) rangesRequested() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := checkSignature(r); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, exists := f.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+	case http.MethodHead, http.MethodGet:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		f.ranges = append(f.ranges, r.Header.Get("Range"))
+		var start int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil || start >= len(data) {
+			w.Write(data)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start:])
+	case http.MethodDelete:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature verifies the SigV4 Authorization header of a request as
+// received, with the signed headers it names.
+func checkSignature(r *http.Request) error {
+	m := authorizationFormat.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return fmt.Errorf("malformed Authorization %q", r.Header.Get("Authorization"))
+	}
+	date, signedHeaders, signature := m[1], m[2], m[3]
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date+"T") {
+		return fmt.Errorf("X-Amz-Date %q does not match the credential date %s", amzDate, date)
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != unsignedPayload {
+		return fmt.Errorf("X-Amz-Content-Sha256 = %q", r.Header.Get("X-Amz-Content-Sha256"))
+	}
+
+	names := strings.Split(signedHeaders, ";")
+	if !sort.StringsAreSorted(names) {
+		return fmt.Errorf("SignedHeaders %q not sorted", signedHeaders)
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+signedHeaders+";", ";"+required+";") {
+			return fmt.Errorf("SignedHeaders %q lack %s", signedHeaders, required)
+		}
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method, r.URL.EscapedPath(), r.URL.RawQuery, canonicalHeaders.String(), signedHeaders, unsignedPayload,
+	}, "\n")
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), date)
+	for _, part := range []string{testRegion, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); signature != want {
+		return fmt.Errorf("signature %s, want %s", signature, want)
+	}
+	return nil
+}
+
+func TestS3PutOpenDelete(t *testing.T) {
+	fake, store := newFakeS3(t)
+	content := []byte("0123456789abcdefghij")
+	const key = "ab/cd/abcdef.jpg"
+
+	if exists, err := store.Exists(key); err != nil || exists {
+		t.Fatalf("Exists before Put = %v, %v; want false", exists, err)
+	}
+	if err := store.Put(key, bytes.NewReader(content), int64(len(content)), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.object(key); !bytes.Equal(got, content) {
+		t.Fatalf("stored %q, want %q", got, content)
+	}
+	if exists, err := store.Exists(key); err != nil || !exists {
+		t.Fatalf("Exists after Put = %v, %v; want true", exists, err)
+	}
+
+	obj, err := store.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if obj.Size() != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", obj.Size(), len(content))
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !obj.ModTime().Equal(want) {
+		t.Errorf("ModTime = %v, want %v", obj.ModTime(), want)
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(obj, head); err != nil || string(head) != "0123" {
+		t.Fatalf("first read = %q, %v", head, err)
+	}
+	if pos, err := obj.Seek(-5, io.SeekEnd); err != nil || pos != 15 {
+		t.Fatalf("Seek(-5, end) = %d, %v; want 15", pos, err)
+	}
+	tail, err := io.ReadAll(obj)
+	if err != nil || string(tail) != "fghij" {
+		t.Fatalf("read after Seek = %q, %v; want fghij", tail, err)
+	}
+	if pos, err := obj.Seek(10, io.SeekStart); err != nil || pos != 10 {
+		t.Fatalf("Seek(10, start) = %d, %v", pos, err)
+	}
+	if _, err := io.ReadFull(obj, head); err != nil || string(head) != "abcd" {
+		t.Fatalf("read after second Seek = %q, %v; want abcd", head, err)
+	}
+	if got, want := fake.rangesRequested(), []string{"bytes=0-", "bytes=15-", "bytes=10-"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ranged GETs %q, want %q", got, want)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := store.Exists(key); err != nil || exists {
+		t.Fatalf("Exists after Delete = %v, %v; want false", exists, err)
+	}
+	// S3 answers 404 for a missing object, which is not an error
+	if err := store.Delete(key); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+	if _, err := store.Open(key); err != ErrNotFound {
+		t.Errorf("Open of a missing object = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package storage keeps the files behind uploaded media. A MediaStore holds
+// objects by key, either on the local disk or in an S3-compatible bucket; the
+// media package decides the keys and tracks who uses them.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// MediaStore stores media files. Implementations must be safe for concurrent
+// use, and Put must be safe to repeat for the same key and content.
+type MediaStore interface {
+	// Put stores size bytes read from r under key.
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object stored under key, or ErrNotFound.
+	Open(key string) (Object, error)
+	// Exists reports whether an object is stored under key.
+	Exists(key string) (bool, error)
+	// Delete removes the object under key. Deleting a missing object is not an error.
+	Delete(key string) error
+}
+
+// Object is an opened stored file. It can be passed to http.ServeContent.
+type Object interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// FromEnv returns the store configured by MEDIA_STORE: "local" (the default)
+// keeps files under MEDIA_DIR, "s3" in the bucket set by the S3_* variables.
+func FromEnv() (MediaStore, error) {
+	switch backend := os.Getenv("MEDIA_STORE"); backend {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "media"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("storage: unknown MEDIA_STORE %q", backend)
+	}
+}
@@ -0,0 +1,145 @@
+package users
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"net/http"
+)
+
+// GetBlockedUsersHandler handles GET /api/users/blocked
+func GetBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	listRelatedUsers(w, r, queries.GetBlockedUsers, "Failed to fetch blocked users")
+}
+
+// GetMutedUsersHandler handles GET /api/users/muted
+func GetMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	listRelatedUsers(w, r, queries.GetMutedUsers, "Failed to fetch muted users")
+}
+
+func listRelatedUsers(w http.ResponseWriter, r *http.Request, list func(int) ([]models.UserSearchResult, error), failure string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	users, err := list(userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: failure})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.SearchUsersResponse{
+		Success: true,
+		Users:   users,
+	})
+}
+
+// BlockUserHandler handles POST /api/users/{username}/block.
+// Blocking removes the follows between both users and replaces any mute.
+func BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := loadOtherUser(w, r, "Cannot block yourself")
+	if !ok {
+		return
+	}
+
+	if err := queries.BlockUser(userID, target.ID); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to block user"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Blocked " + target.Username})
+}
+
+// UnblockUserHandler handles DELETE /api/users/{username}/block
+func UnblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := loadOtherUser(w, r, "Cannot unblock yourself")
+	if !ok {
+		return
+	}
+
+	removed, err := queries.UnblockUser(userID, target.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to unblock user"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User is not blocked"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Unblocked " + target.Username})
+}
+
+// MuteUserHandler handles POST /api/users/{username}/mute.
+// Muting only hides the user's posts from the feed.
+func MuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := loadOtherUser(w, r, "Cannot mute yourself")
+	if !ok {
+		return
+	}
+
+	blocked, err := queries.HasBlocked(userID, target.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to mute user"})
+		return
+	}
+	if blocked {
+		utils.RespondJSON(w, http.StatusConflict, models.GenericResponse{Success: false, Message: "User is already blocked"})
+		return
+	}
+
+	if err := queries.MuteUser(userID, target.ID); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to mute user"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Muted " + target.Username})
+}
+
+// UnmuteUserHandler handles DELETE /api/users/{username}/mute
+func UnmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := loadOtherUser(w, r, "Cannot unmute yourself")
+	if !ok {
+		return
+	}
+
+	removed, err := queries.UnmuteUser(userID, target.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to unmute user"})
+		return
+	}
+	if !removed {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User is not muted"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Unmuted " + target.Username})
+}
+
+// loadOtherUser returns the current user ID and the {username} user, who must be
+// someone else. It writes the error response and returns ok=false on failure.
+func loadOtherUser(w http.ResponseWriter, r *http.Request, selfMessage string) (userID int, target models.User, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok = utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return 0, target, false
+	}
+
+	target, err := queries.GetUserByIdentifier(r.PathValue("username"))
+	if err != nil {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "User not found"})
+		return 0, target, false
+	}
+	if target.ID == userID {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: selfMessage})
+		return 0, target, false
+	}
+
+	return userID, target, true
+}
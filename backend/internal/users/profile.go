@@ -36,6 +36,16 @@ func GetPublicProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users blocked by the target can't see their profile at all
+	if blockedBy, err := queries.HasBlocked(target.ID, viewerID); err != nil || blockedBy {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{
+			Success: false, Message: "User not found",
+		})
+		return
+	}
+	isBlocked, _ := queries.HasBlocked(viewerID, target.ID)
+	isMuted, _ := queries.HasMuted(viewerID, target.ID)
+
 	followStatus, err := queries.GetFollowStatus(viewerID, target.ID)
 	if err != nil {
 		followStatus = "none"
@@ -47,16 +57,19 @@ func GetPublicProfileHandler(w http.ResponseWriter, r *http.Request) {
 	isOwner := viewerID == target.ID
 	isFollower := followStatus == "accepted"
 
-	// Private account: only the owner or accepted followers can see full profile
-	if !target.IsPublic && !isOwner && !isFollower {
+	// Private account: only the owner or accepted followers can see full profile.
+	// A blocked user only gets the locked profile.
+	if (!target.IsPublic && !isOwner && !isFollower) || isBlocked {
 		utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 			"success":        true,
 			"userId":         target.ID,
 			"username":       target.Username,
 			"nickname":       target.Nickname,
 			"avatar":         target.Avatar,
-			"isPublic":       false,
+			"isPublic":       target.IsPublic,
 			"isLocked":       true,
+			"isBlocked":      isBlocked,
+			"isMuted":        isMuted,
 			"createdAt":      target.CreatedAt,
 			"followStatus":   followStatus,
 			"followersCount": followersCount,
@@ -76,6 +89,8 @@ func GetPublicProfileHandler(w http.ResponseWriter, r *http.Request) {
 		"aboutMe":        target.AboutMe,
 		"isPublic":       target.IsPublic,
 		"isLocked":       false,
+		"isBlocked":      false,
+		"isMuted":        isMuted,
 		"createdAt":      target.CreatedAt,
 		"followStatus":   followStatus,
 		"followersCount": followersCount,
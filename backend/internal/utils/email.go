@@ -1,26 +1,20 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
 	"net/smtp"
+	"net/textproto"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // SendOTPEmail sends a verification OTP to the given email address.
 func SendOTPEmail(toEmail, toName, code string) error {
-	host := os.Getenv("SMTP_HOST")
-	portStr := os.Getenv("SMTP_PORT")
-	sender := os.Getenv("SENDER_EMAIL")
-	password := os.Getenv("SENDER_PASSWORD")
-
-	port, err := strconv.Atoi(portStr)
-	if err != nil || port == 0 {
-		port = 587
-	}
-
-	auth := smtp.PlainAuth("", sender, password, host)
-
 	subject := "Your Verification Code"
 	body := fmt.Sprintf(`<!DOCTYPE html>
 <html>
@@ -58,10 +52,71 @@ func SendOTPEmail(toEmail, toName, code string) error {
 </body>
 </html>`, toName, code)
 
-	msg := fmt.Sprintf(
-		"From: Reboot Social <%s>\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
-		sender, toEmail, subject, body,
-	)
+	return SendEmail(toEmail, subject, body)
+}
+
+// SendEmail sends an HTML email through the SMTP server configured in the environment.
+func SendEmail(toEmail, subject, htmlBody string) error {
+	return sendMail(toEmail, fmt.Sprintf(
+		"Subject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
+		subject, htmlBody,
+	))
+}
+
+// SendAlternativeEmail sends an email with plain text and HTML versions of the body.
+// headers are added to the message, e.g. List-Unsubscribe.
+func SendAlternativeEmail(toEmail, subject, textBody, htmlBody string, headers map[string]string) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return err
+		}
+		if err := qw.Close(); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	for name, value := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return sendMail(toEmail, msg.String())
+}
+
+// sendMail adds the From and To headers to message and sends it.
+func sendMail(toEmail, message string) error {
+	host := os.Getenv("SMTP_HOST")
+	portStr := os.Getenv("SMTP_PORT")
+	sender := os.Getenv("SENDER_EMAIL")
+	password := os.Getenv("SENDER_PASSWORD")
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port == 0 {
+		port = 587
+	}
+
+	auth := smtp.PlainAuth("", sender, password, host)
+
+	msg := fmt.Sprintf("From: Reboot Social <%s>\r\nTo: %s\r\n%s", sender, toEmail, message)
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 	return smtp.SendMail(addr, auth, sender, []string{toEmail}, []byte(msg))
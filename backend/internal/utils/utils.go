@@ -1,16 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
 
 	"github.com/google/uuid"
 )
@@ -29,6 +24,15 @@ func GenerateSessionID() string {
 	return uuid.New().String()
 }
 
+// GenerateToken returns a random 256-bit hex token for unguessable URLs.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func GetUserIDFromContext(r *http.Request) (int, bool) {
 	userID, ok := r.Context().Value("userID").(int)
 	return userID, ok
@@ -83,126 +87,6 @@ func ValidateUserCanPostOrCreateEvent(r *http.Request, groupID int64, isMemberCh
 	return nil
 }
 
-// SaveUploadedFile saves an uploaded file to the specified directory
-// Returns the path in the format /uploads/{directory}/{filename}
-func SaveUploadedFile(file multipart.File, header *multipart.FileHeader, directory string) (string, error) {
-	allowedTypesByDirectory := map[string]map[string]bool{
-		"avatars": {
-			"image/jpeg": true,
-			"image/png":  true,
-			"image/webp": true,
-			"image/gif":  true,
-		},
-		"groups": {
-			"image/jpeg": true,
-			"image/png":  true,
-			"image/webp": true,
-			"image/gif":  true,
-		},
-		"posts": {
-			"image/jpeg":      true,
-			"image/png":       true,
-			"image/webp":      true,
-			"image/gif":       true,
-			"video/mp4":       true,
-			"video/webm":      true,
-			"video/quicktime": true,
-		},
-	}
-
-	allowedTypes, ok := allowedTypesByDirectory[directory]
-	if !ok {
-		return "", errors.New("invalid upload destination")
-	}
-
-	allowedExtensions := map[string]map[string]bool{
-		"image/jpeg": {
-			".jpg":  true,
-			".jpeg": true,
-		},
-		"image/png": {
-			".png": true,
-		},
-		"image/webp": {
-			".webp": true,
-		},
-		"image/gif": {
-			".gif": true,
-		},
-		"video/mp4": {
-			".mp4": true,
-		},
-		"video/webm": {
-			".webm": true,
-		},
-		"video/quicktime": {
-			".mov": true,
-			".qt":  true,
-		},
-	}
-
-	// Validate the real file type from the file bytes instead of trusting the client header.
-	sniff := make([]byte, 512)
-	n, err := io.ReadFull(file, sniff)
-	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-		return "", errors.New("failed to read uploaded file")
-	}
-
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return "", errors.New("failed to process uploaded file")
-	}
-
-	contentType := http.DetectContentType(sniff[:n])
-	if !allowedTypes[contentType] {
-		if directory == "posts" {
-			return "", errors.New("file must be a JPEG, PNG, WebP, GIF, MP4, WebM, or MOV")
-		}
-		return "", errors.New("file must be a JPEG, PNG, WebP, or GIF image")
-	}
-
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext == "" || !allowedExtensions[contentType][ext] {
-		return "", errors.New("file extension does not match the uploaded file type")
-	}
-
-	// Enforce size limits: 10 MB for images/GIFs, 25 MB for videos
-	isVideo := strings.HasPrefix(contentType, "video/")
-	var maxSize int64
-	if isVideo {
-		maxSize = 25 << 20 // 25 MB
-	} else {
-		maxSize = 10 << 20 // 10 MB
-	}
-	if header.Size > maxSize {
-		if isVideo {
-			return "", errors.New("video must be at most 25 MB")
-		}
-		return "", errors.New("image/GIF must be at most 10 MB")
-	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("%d_%s%s", time.Now().Unix(), GenerateSessionID(), ext)
-
-	// Ensure directory exists
-	uploadPath := filepath.Join("uploads", directory)
-	if err := os.MkdirAll(uploadPath, 0755); err != nil {
-		return "", errors.New("failed to create upload directory")
-	}
-
-	// Save file to disk
-	dst, err := os.Create(filepath.Join(uploadPath, filename))
-	if err != nil {
-		return "", errors.New("failed to create file")
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		return "", errors.New("failed to save file")
-	}
-
-	return "/uploads/" + directory + "/" + filename, nil
-}
-
 func GetPathParts(path string) []string {
 	// Remove leading/trailing slashes
 	if len(path) > 0 && path[0] == '/' {
@@ -0,0 +1,306 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// box is an MP4 box: data[start:end] holds it, its payload starts at body.
+type box struct {
+	kind  string
+	start int
+	body  int
+	end   int
+}
+
+// readBoxes splits data[start:end] into boxes.
+func readBoxes(data []byte, start, end int) ([]box, error) {
+	var boxes []box
+	for i := start; i < end; {
+		if i+8 > end {
+			return nil, ErrInvalid
+		}
+		b := box{kind: string(data[i+4 : i+8]), start: i, body: i + 8}
+		size := uint64(binary.BigEndian.Uint32(data[i:]))
+		switch size {
+		case 0: // up to the end
+			size = uint64(end - i)
+		case 1: // 64-bit size
+			if i+16 > end {
+				return nil, ErrInvalid
+			}
+			size = binary.BigEndian.Uint64(data[i+8:])
+			b.body = i + 16
+		}
+		if size < uint64(b.body-i) || size > uint64(end-i) {
+			return nil, ErrInvalid
+		}
+		b.end = i + int(size)
+		boxes = append(boxes, b)
+		i = b.end
+	}
+	return boxes, nil
+}
+
+// children returns the boxes inside a container box.
+func children(data []byte, b box) ([]box, error) {
+	return readBoxes(data, b.body, b.end)
+}
+
+func find(boxes []box, kind string) (box, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// findPath follows a path of box types down from b, e.g. "mdia", "minf".
+func findPath(data []byte, b box, path ...string) (box, bool) {
+	for _, kind := range path {
+		kids, err := children(data, b)
+		if err != nil {
+			return box{}, false
+		}
+		if b, _ = find(kids, kind); b.kind != kind {
+			return box{}, false
+		}
+	}
+	return b, true
+}
+
+// topLevel reads the top-level boxes of an MP4 file and checks that it has
+// an index (moov) and media data (mdat).
+func topLevel(data []byte) ([]box, box, error) {
+	boxes, err := readBoxes(data, 0, len(data))
+	if err != nil {
+		return nil, box{}, err
+	}
+	moov, ok := find(boxes, "moov")
+	if !ok {
+		return nil, box{}, ErrInvalid
+	}
+	if _, ok := find(boxes, "mdat"); !ok {
+		return nil, box{}, ErrInvalid
+	}
+	return boxes, moov, nil
+}
+
+func probeMP4(data []byte) (Info, error) {
+	_, moov, err := topLevel(data)
+	if err != nil {
+		return Info{}, err
+	}
+	kids, err := children(data, moov)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	mvhd, ok := find(kids, "mvhd")
+	if !ok {
+		return Info{}, ErrInvalid
+	}
+	if info.Duration, err = headerDuration(data[mvhd.body:mvhd.end], 12); err != nil {
+		return Info{}, err
+	}
+
+	for _, trak := range kids {
+		if trak.kind != "trak" {
+			continue
+		}
+		hdlr, ok := findPath(data, trak, "mdia", "hdlr")
+		if !ok || hdlr.body+12 > hdlr.end {
+			continue
+		}
+		stsd, ok := findPath(data, trak, "mdia", "minf", "stbl", "stsd")
+		if !ok || stsd.body+16 > stsd.end {
+			continue
+		}
+		// stsd: version/flags, entry count, then sample entries (size, format, ...)
+		entry := stsd.body + 8
+		format := string(data[entry+4 : entry+8])
+
+		switch string(data[hdlr.body+8 : hdlr.body+12]) {
+		case "vide":
+			if info.Codec != "" {
+				continue // the first video track is the one played
+			}
+			info.Codec = format
+			if tkhd, ok := findPath(data, trak, "tkhd"); ok {
+				info.Width, info.Height = trackSize(data[tkhd.body:tkhd.end])
+			}
+			// Some writers leave the track size at 0; the sample entry has the coded size
+			if (info.Width == 0 || info.Height == 0) && entry+36 <= stsd.end {
+				info.Width = int(binary.BigEndian.Uint16(data[entry+32:]))
+				info.Height = int(binary.BigEndian.Uint16(data[entry+34:]))
+			}
+		case "soun":
+			if info.AudioCodec == "" {
+				info.AudioCodec = format
+			}
+		}
+	}
+	if info.Codec == "" {
+		return Info{}, ErrNoVideo
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return Info{}, ErrInvalid
+	}
+	return info, nil
+}
+
+// headerDuration reads the timescale and duration of an mvhd or mdhd box.
+// v0Offset is where the timescale is in a version 0 box.
+func headerDuration(b []byte, v0Offset int) (time.Duration, error) {
+	if len(b) < 4 {
+		return 0, ErrInvalid
+	}
+	var timescale uint32
+	var duration uint64
+	if b[0] == 1 { // 64-bit times
+		if len(b) < 32 {
+			return 0, ErrInvalid
+		}
+		timescale = binary.BigEndian.Uint32(b[20:])
+		duration = binary.BigEndian.Uint64(b[24:])
+	} else {
+		if len(b) < v0Offset+8 {
+			return 0, ErrInvalid
+		}
+		timescale = binary.BigEndian.Uint32(b[v0Offset:])
+		duration = uint64(binary.BigEndian.Uint32(b[v0Offset+4:]))
+	}
+	if timescale == 0 {
+		return 0, ErrInvalid
+	}
+	seconds := float64(duration) / float64(timescale)
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return 0, ErrInvalid
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// trackSize reads the display size of a tkhd box, swapped for videos turned
+// a quarter by their matrix.
+func trackSize(b []byte) (int, int) {
+	matrix := 4 + 20 + 8 + 8 // version/flags, times and ID, reserved, layer to volume
+	if len(b) > 0 && b[0] == 1 {
+		matrix += 12
+	}
+	if len(b) < matrix+36+8 {
+		return 0, 0
+	}
+	w := int(binary.BigEndian.Uint32(b[matrix+36:]) >> 16)
+	h := int(binary.BigEndian.Uint32(b[matrix+40:]) >> 16)
+	// The matrix is {a, b, u, c, d, v, x, y, w}; a quarter turn zeroes a and d
+	a := binary.BigEndian.Uint32(b[matrix:])
+	d := binary.BigEndian.Uint32(b[matrix+16:])
+	if a == 0 && d == 0 {
+		w, h = h, w
+	}
+	return w, h
+}
+
+// FastStart returns an MP4 or QuickTime file with its index (moov) ahead of
+// its media data (mdat), so browsers can start playing it before it is fully
+// downloaded. The chunk offsets of the index are moved with the data. Files
+// already laid out so are returned as they are.
+func FastStart(data []byte) ([]byte, error) {
+	boxes, moov, err := topLevel(data)
+	if err != nil {
+		return nil, err
+	}
+	mdatIndex := -1
+	for i, b := range boxes {
+		if b.kind == "mdat" && mdatIndex < 0 {
+			mdatIndex = i
+		}
+	}
+	if moov.start < boxes[mdatIndex].start {
+		return data, nil
+	}
+
+	// Everything between the first mdat and the moov moves forward by the
+	// size of the moov, and the offsets pointing there with it
+	shift := moov.end - moov.start
+	from, to := boxes[mdatIndex].start, moov.start
+	index := append([]byte(nil), data[moov.start:moov.end]...)
+	if binary.BigEndian.Uint32(index) == 0 { // "up to the end" no longer holds
+		binary.BigEndian.PutUint32(index, uint32(len(index)))
+	}
+	if err := shiftChunkOffsets(index, from, to, shift); err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:from])
+	out.Write(index)
+	out.Write(data[from:moov.start])
+	out.Write(data[moov.end:])
+	return out.Bytes(), nil
+}
+
+// shiftChunkOffsets adds shift to the chunk offsets in [from, to) of every
+// track of a moov box.
+func shiftChunkOffsets(moov []byte, from, to, shift int) error {
+	root, err := readBoxes(moov, 0, len(moov))
+	if err != nil || len(root) != 1 {
+		return ErrInvalid
+	}
+	traks, err := children(moov, root[0])
+	if err != nil {
+		return err
+	}
+	for _, trak := range traks {
+		if trak.kind != "trak" {
+			continue
+		}
+		stbl, ok := findPath(moov, trak, "mdia", "minf", "stbl")
+		if !ok {
+			return ErrInvalid
+		}
+		tables, err := children(moov, stbl)
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			if t.kind != "stco" && t.kind != "co64" {
+				continue
+			}
+			if t.body+8 > t.end {
+				return ErrInvalid
+			}
+			count := int(binary.BigEndian.Uint32(moov[t.body+4:]))
+			width := 4
+			if t.kind == "co64" {
+				width = 8
+			}
+			if count > (t.end-t.body-8)/width {
+				return ErrInvalid
+			}
+			for i := 0; i < count; i++ {
+				p := t.body + 8 + i*width
+				if width == 4 {
+					offset := uint64(binary.BigEndian.Uint32(moov[p:]))
+					if offset >= uint64(from) && offset < uint64(to) {
+						offset += uint64(shift)
+						if offset > math.MaxUint32 {
+							return ErrInvalid
+						}
+						binary.BigEndian.PutUint32(moov[p:], uint32(offset))
+					}
+				} else {
+					offset := binary.BigEndian.Uint64(moov[p:])
+					if offset >= uint64(from) && offset < uint64(to) {
+						binary.BigEndian.PutUint64(moov[p:], offset+uint64(shift))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package video
+
+import (
+	"image"
+	"image/color"
+)
+
+// PosterExtractor makes the poster image of a video, shown before it plays.
+type PosterExtractor interface {
+	Poster(data []byte, info Info) (image.Image, error)
+}
+
+// Placeholder is the PosterExtractor used while no video decoder is
+// available: it draws a dark frame of the video's proportions, at most
+// maxSide pixels on its longer side, with a play symbol in the middle.
+type Placeholder struct {
+	MaxSide int
+}
+
+func (p Placeholder) Poster(_ []byte, info Info) (image.Image, error) {
+	w, h := info.Width, info.Height
+	if w <= 0 || h <= 0 {
+		return nil, ErrInvalid
+	}
+	if w > p.MaxSide || h > p.MaxSide {
+		if w >= h {
+			w, h = p.MaxSide, max(1, h*p.MaxSide/w)
+		} else {
+			w, h = max(1, w*p.MaxSide/h), p.MaxSide
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	background := color.RGBA{R: 0x1f, G: 0x23, B: 0x2a, A: 0xff}
+	symbol := color.RGBA{R: 0xe6, G: 0xe8, B: 0xeb, A: 0xff}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, background.A
+	}
+
+	// A triangle pointing right, a fifth of the shorter side high
+	side := max(2, min(w, h)/5)
+	cx, cy := w/2, h/2
+	left := cx - side*2/5
+	for y := cy - side/2; y <= cy+side/2; y++ {
+		// The triangle narrows from its left edge to its tip at the middle height
+		dy := y - cy
+		if dy < 0 {
+			dy = -dy
+		}
+		right := left + (side-2*dy)*7/8
+		for x := left; x <= right; x++ {
+			img.SetRGBA(x, y, symbol)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,41 @@
+// Package video reads the containers of uploaded videos, in pure Go: it checks
+// MP4, QuickTime and WebM files, reads their duration, resolution and codecs,
+// and moves the index of MP4 files ahead of the media data so they can play
+// while downloading. It decodes no frames; see PosterExtractor.
+package video
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned for files that are not a readable video.
+	ErrInvalid = errors.New("file is not a valid video")
+	// ErrNoVideo is returned for files without a video track, such as audio files.
+	ErrNoVideo = errors.New("file has no video track")
+)
+
+// Info describes a video file.
+type Info struct {
+	Duration time.Duration
+	// Width and Height are the display size, with rotation applied
+	Width  int
+	Height int
+	// Codec is the video codec as the container names it, e.g. "avc1" in
+	// MP4 or "V_VP9" in WebM. AudioCodec is "" for silent videos
+	Codec      string
+	AudioCodec string
+}
+
+// Probe reads the container of an MP4 or QuickTime ("video/mp4",
+// "video/quicktime") or WebM ("video/webm") file.
+func Probe(data []byte, mimeType string) (Info, error) {
+	switch mimeType {
+	case "video/mp4", "video/quicktime":
+		return probeMP4(data)
+	case "video/webm":
+		return probeWebM(data)
+	}
+	return Info{}, ErrInvalid
+}
@@ -0,0 +1,336 @@
+package video
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// EBML element IDs used by WebM, with their length marker bits.
+const (
+	idEBML          = 0x1A45DFA3
+	idDocType       = 0x4282
+	idSegment       = 0x18538067
+	idInfo          = 0x1549A966
+	idTimecodeScale = 0x2AD7B1
+	idDuration      = 0x4489
+	idTracks        = 0x1654AE6B
+	idTrackEntry    = 0xAE
+	idTrackType     = 0x83
+	idCodecID       = 0x86
+	idVideo         = 0xE0
+	idPixelWidth    = 0xB0
+	idPixelHeight   = 0xBA
+	idDisplayWidth  = 0x54B0
+	idDisplayHeight = 0x54BA
+	idCluster       = 0x1F43B675
+	idTimecode      = 0xE7
+	idSimpleBlock   = 0xA3
+	idBlockGroup    = 0xA0
+	idBlock         = 0xA1
+)
+
+// topLevelIDs are the children of a Segment. A Cluster of unknown size ends
+// where one of them starts.
+var topLevelIDs = map[uint32]bool{
+	0x114D9B74: true, // SeekHead
+	idInfo:     true,
+	idTracks:   true,
+	idCluster:  true,
+	0x1C53BB6B: true, // Cues
+	0x1941A469: true, // Attachments
+	0x1043A770: true, // Chapters
+	0x1254C367: true, // Tags
+}
+
+// element is an EBML element: data[start:end] holds it, its payload starts at
+// body. Live recorders such as browsers write Segments and Clusters of
+// unknown size; see readElements.
+type element struct {
+	id      uint32
+	start   int
+	body    int
+	end     int
+	unknown bool
+}
+
+// readVint reads a variable-size integer at data[i:], returning its value
+// with (raw) or without the length marker, and its length.
+func readVint(data []byte, i int, raw bool) (uint64, int, bool) {
+	if i >= len(data) || data[i] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for data[i]&(0x80>>(n-1)) == 0 {
+		n++
+	}
+	if i+n > len(data) {
+		return 0, 0, false
+	}
+	v := uint64(data[i])
+	if !raw {
+		v &= 0xFF >> n
+	}
+	for _, b := range data[i+1 : i+n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n, true
+}
+
+// readElement reads the header of the element at data[i:], within a parent
+// ending at end.
+func readElement(data []byte, i, end int) (element, bool) {
+	id, n, ok := readVint(data[:end], i, true)
+	if !ok || n > 4 {
+		return element{}, false
+	}
+	size, m, ok := readVint(data[:end], i+n, false)
+	if !ok {
+		return element{}, false
+	}
+	e := element{id: uint32(id), start: i, body: i + n + m}
+	if size == 1<<(7*m)-1 { // all ones: unknown size
+		e.end, e.unknown = end, true
+		return e, true
+	}
+	if size > uint64(end-e.body) {
+		return element{}, false
+	}
+	e.end = e.body + int(size)
+	return e, true
+}
+
+// readElements splits data[start:end] into elements. A Segment of unknown
+// size ends with its parent, a Cluster where the next top-level element starts.
+func readElements(data []byte, start, end int) ([]element, error) {
+	var elements []element
+	for i := start; i < end; {
+		e, ok := readElement(data, i, end)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		if e.unknown && e.id == idCluster {
+			e.end = unknownEnd(data, e.body, end)
+		}
+		elements = append(elements, e)
+		i = e.end
+	}
+	return elements, nil
+}
+
+// unknownEnd finds where a Cluster of unknown size whose payload starts at i
+// ends: at the next top-level element, or at end.
+func unknownEnd(data []byte, i, end int) int {
+	for i < end {
+		e, ok := readElement(data, i, end)
+		if !ok || topLevelIDs[e.id] {
+			return i
+		}
+		if e.unknown {
+			return end
+		}
+		i = e.end
+	}
+	return end
+}
+
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func readFloat(b []byte) (float64, bool) {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), true
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), true
+	}
+	return 0, false
+}
+
+func probeWebM(data []byte) (Info, error) {
+	top, err := readElements(data, 0, len(data))
+	if err != nil || len(top) < 2 || top[0].id != idEBML || top[1].id != idSegment {
+		return Info{}, ErrInvalid
+	}
+	header, err := readElements(data, top[0].body, top[0].end)
+	if err != nil {
+		return Info{}, err
+	}
+	docType := ""
+	for _, e := range header {
+		if e.id == idDocType {
+			docType = string(data[e.body:e.end])
+		}
+	}
+	if docType != "webm" {
+		return Info{}, ErrInvalid
+	}
+
+	segment, err := readElements(data, top[1].body, top[1].end)
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	scale := uint64(1_000_000) // nanoseconds per timecode
+	duration := -1.0
+	var tracksSeen bool
+	var lastTimecode int64 // of the last block, for files written without a duration
+
+	for _, e := range segment {
+		switch e.id {
+		case idInfo:
+			kids, err := readElements(data, e.body, e.end)
+			if err != nil {
+				return Info{}, err
+			}
+			for _, k := range kids {
+				switch k.id {
+				case idTimecodeScale:
+					if s := readUint(data[k.body:k.end]); s > 0 {
+						scale = s
+					}
+				case idDuration:
+					if d, ok := readFloat(data[k.body:k.end]); ok && d >= 0 {
+						duration = d
+					}
+				}
+			}
+		case idTracks:
+			if err := readTracks(data, e, &info); err != nil {
+				return Info{}, err
+			}
+			tracksSeen = true
+		case idCluster:
+			// Players need the tracks before the first frame
+			if !tracksSeen {
+				return Info{}, ErrInvalid
+			}
+			if duration < 0 {
+				t, err := clusterEnd(data, e)
+				if err != nil {
+					return Info{}, err
+				}
+				lastTimecode = max(lastTimecode, t)
+			}
+		}
+	}
+	if !tracksSeen || info.Codec == "" {
+		return Info{}, ErrNoVideo
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return Info{}, ErrInvalid
+	}
+	if duration < 0 {
+		duration = float64(lastTimecode)
+	}
+	ns := duration * float64(scale)
+	if ns > math.MaxInt64 {
+		return Info{}, ErrInvalid
+	}
+	info.Duration = time.Duration(ns)
+	return info, nil
+}
+
+// readTracks sets the codecs and size of info from a Tracks element. The
+// first video and audio tracks are the ones played.
+func readTracks(data []byte, tracks element, info *Info) error {
+	entries, err := readElements(data, tracks.body, tracks.end)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.id != idTrackEntry {
+			continue
+		}
+		kids, err := readElements(data, entry.body, entry.end)
+		if err != nil {
+			return err
+		}
+		var kind uint64
+		var codec string
+		var video element
+		for _, k := range kids {
+			switch k.id {
+			case idTrackType:
+				kind = readUint(data[k.body:k.end])
+			case idCodecID:
+				codec = string(data[k.body:k.end])
+			case idVideo:
+				video = k
+			}
+		}
+		switch {
+		case kind == 1 && info.Codec == "" && video.id == idVideo:
+			info.Codec = codec
+			fields, err := readElements(data, video.body, video.end)
+			if err != nil {
+				return err
+			}
+			var displayW, displayH int
+			for _, f := range fields {
+				v := int(readUint(data[f.body:f.end]))
+				switch f.id {
+				case idPixelWidth:
+					info.Width = v
+				case idPixelHeight:
+					info.Height = v
+				case idDisplayWidth:
+					displayW = v
+				case idDisplayHeight:
+					displayH = v
+				}
+			}
+			if displayW > 0 && displayH > 0 {
+				info.Width, info.Height = displayW, displayH
+			}
+		case kind == 2 && info.AudioCodec == "":
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
+
+// clusterEnd returns the timecode of the last block of a cluster.
+func clusterEnd(data []byte, cluster element) (int64, error) {
+	kids, err := readElements(data, cluster.body, cluster.end)
+	if err != nil {
+		return 0, err
+	}
+	var base, last int64
+	for _, k := range kids {
+		var block element
+		switch k.id {
+		case idTimecode:
+			base = int64(readUint(data[k.body:k.end]))
+			continue
+		case idSimpleBlock:
+			block = k
+		case idBlockGroup:
+			group, err := readElements(data, k.body, k.end)
+			if err != nil {
+				return 0, err
+			}
+			for _, g := range group {
+				if g.id == idBlock {
+					block = g
+				}
+			}
+		}
+		if block.id == 0 {
+			continue
+		}
+		// A block starts with its track number and a timecode relative to the cluster
+		_, n, ok := readVint(data[:block.end], block.body, false)
+		if !ok || block.body+n+2 > block.end {
+			return 0, ErrInvalid
+		}
+		relative := int16(binary.BigEndian.Uint16(data[block.body+n:]))
+		last = max(last, base+int64(relative))
+	}
+	return last, nil
+}
@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"backend/internal/netguard"
+	"errors"
+	"net/url"
+	"os"
+	"time"
+)
+
+var errBlockedAddress = errors.New("webhook URL resolves to a private or local address")
+
+// client refuses to connect to private, loopback and link-local addresses, so
+// webhooks cannot reach services inside our network. Redirects are reported as
+// failures instead of being followed.
+var client = netguard.Client(10*time.Second, allowPrivate)
+
+// allowPrivate reports whether webhooks may target private addresses
+// (WEBHOOK_ALLOW_PRIVATE=true), for receivers running on the same machine during development.
+func allowPrivate() bool {
+	return os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+}
+
+// ValidateURL checks a webhook URL given by a user.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || len(raw) > 2048 {
+		return errors.New("invalid webhook URL")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return errors.New("webhook URL must use http or https")
+	}
+	if u.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	if !allowPrivate() && netguard.IsLocalHost(u.Hostname()) {
+		return errBlockedAddress
+	}
+	return nil
+}
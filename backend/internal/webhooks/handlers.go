@@ -0,0 +1,432 @@
+package webhooks
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxWebhooks bounds the webhooks of one account or group
+const maxWebhooks = 10
+
+// canManage reports whether userID may manage the webhooks of a group (its
+// owner) or of an account (the account itself).
+func canManage(userID int, groupID *int64, ownerID int) (bool, error) {
+	if groupID == nil {
+		return ownerID == userID, nil
+	}
+	group, err := queries.GetGroupByID(*groupID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return group.OwnerID == userID, nil
+}
+
+// loadWebhook returns the webhook of the {id} path value if the current user
+// manages it, or writes an error response and returns false.
+func loadWebhook(w http.ResponseWriter, r *http.Request, userID int) (models.Webhook, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid webhook ID"})
+		return models.Webhook{}, false
+	}
+
+	hook, err := queries.GetWebhook(id)
+	if err == sql.ErrNoRows {
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Webhook not found"})
+		return hook, false
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch webhook"})
+		return hook, false
+	}
+
+	allowed, err := canManage(userID, hook.GroupID, hook.UserID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch webhook"})
+		return hook, false
+	}
+	if !allowed {
+		// Same answer as a missing webhook, so IDs cannot be probed
+		utils.RespondJSON(w, http.StatusNotFound, models.GenericResponse{Success: false, Message: "Webhook not found"})
+		return hook, false
+	}
+	return hook, true
+}
+
+// validateEvents checks and deduplicates the events a webhook subscribes to.
+func validateEvents(events []string, group bool) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("select at least one event")
+	}
+	seen := make(map[string]bool)
+	var valid []string
+	for _, e := range events {
+		if !IsEvent(e, group) {
+			return nil, fmt.Errorf("unknown event '%s'", e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			valid = append(valid, e)
+		}
+	}
+	return valid, nil
+}
+
+func newSecret() (string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// ListWebhooks handles GET /api/webhooks?group_id=
+// Without group_id it lists the current user's account webhooks.
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var groupID *int64
+	events := AccountEvents
+	if raw := r.URL.Query().Get("group_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid group ID"})
+			return
+		}
+		groupID = &id
+		events = GroupEvents
+	}
+
+	allowed, err := canManage(userID, groupID, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch webhooks"})
+		return
+	}
+	if !allowed {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Only the group owner can manage its webhooks"})
+		return
+	}
+
+	hooks, err := queries.ListWebhooks(userID, groupID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch webhooks"})
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":          true,
+		"webhooks":         hooks,
+		"available_events": events,
+	})
+}
+
+// CreateWebhook handles POST /api/webhooks
+// Body: { "url": "https://...", "events": ["post.created"], "group_id": 1 }
+// group_id is omitted for account webhooks. The signing secret is only returned here.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var body struct {
+		URL     string   `json:"url"`
+		Events  []string `json:"events"`
+		GroupID *int64   `json:"group_id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	if body.GroupID != nil && *body.GroupID <= 0 {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid group ID"})
+		return
+	}
+	allowed, err := canManage(userID, body.GroupID, userID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create webhook"})
+		return
+	}
+	if !allowed {
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{Success: false, Message: "Only the group owner can manage its webhooks"})
+		return
+	}
+
+	if err := ValidateURL(body.URL); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+		return
+	}
+	events, err := validateEvents(body.Events, body.GroupID != nil)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	existing, err := queries.ListWebhooks(userID, body.GroupID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create webhook"})
+		return
+	}
+	if len(existing) >= maxWebhooks {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: fmt.Sprintf("You can register at most %d webhooks", maxWebhooks)})
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create webhook"})
+		return
+	}
+	id, err := queries.CreateWebhook(userID, body.GroupID, body.URL, secret, events)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create webhook"})
+		return
+	}
+	hook, err := queries.GetWebhook(id)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to create webhook"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Webhook created. Store its secret now, it will not be shown again",
+		"webhook": hook,
+	})
+}
+
+// GetWebhook handles GET /api/webhooks/{id}
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+	hook.Secret = ""
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"webhook": hook,
+	})
+}
+
+// UpdateWebhook handles PUT /api/webhooks/{id}
+// Body: { "url": "...", "events": [...], "active": false }; omitted fields are kept.
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		URL    *string  `json:"url"`
+		Events []string `json:"events"`
+		Active *bool    `json:"active"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&body); err != nil {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	if body.URL != nil {
+		if err := ValidateURL(*body.URL); err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+			return
+		}
+		hook.URL = *body.URL
+	}
+	if body.Events != nil {
+		events, err := validateEvents(body.Events, hook.GroupID != nil)
+		if err != nil {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: err.Error()})
+			return
+		}
+		hook.Events = events
+	}
+	if body.Active != nil {
+		hook.Active = *body.Active
+	}
+
+	if err := queries.UpdateWebhook(hook.ID, hook.URL, hook.Events, hook.Active); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update webhook"})
+		return
+	}
+	updated, err := queries.GetWebhook(hook.ID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to update webhook"})
+		return
+	}
+	updated.Secret = ""
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"webhook": updated,
+	})
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := queries.DeleteWebhook(hook.ID); err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to delete webhook"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.GenericResponse{Success: true, Message: "Webhook deleted"})
+}
+
+// RotateWebhookSecret handles POST /api/webhooks/{id}/rotate-secret
+// Deliveries signed from now on use the returned secret.
+func RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	secret, err := newSecret()
+	if err == nil {
+		err = queries.SetWebhookSecret(hook.ID, secret)
+	}
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to rotate webhook secret"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"secret":  secret,
+	})
+}
+
+// SendTestEvent handles POST /api/webhooks/{id}/test
+// It queues a "ping" delivery, whose outcome appears in the delivery log.
+func SendTestEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+	if !hook.Active {
+		utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Webhook is disabled"})
+		return
+	}
+
+	deliveryID, err := SendTest(hook, userID)
+	if err != nil {
+		fmt.Printf("webhooks: failed to queue test event for webhook %d: %v\n", hook.ID, err)
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to send test event"})
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success":     true,
+		"message":     "Test event queued",
+		"delivery_id": deliveryID,
+	})
+}
+
+// ListWebhookDeliveries handles GET /api/webhooks/{id}/deliveries?limit=&cursor=
+// Returns the delivery log, newest first; next_cursor is 0 on the last page.
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := utils.GetUserIDFromContext(r)
+	if !ok {
+		utils.RespondJSON(w, http.StatusUnauthorized, models.GenericResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	hook, ok := loadWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	var cursor int64
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		c, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || c < 0 {
+			utils.RespondJSON(w, http.StatusBadRequest, models.GenericResponse{Success: false, Message: "Invalid cursor"})
+			return
+		}
+		cursor = c
+	}
+
+	deliveries, err := queries.GetWebhookDeliveries(hook.ID, cursor, limit)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{Success: false, Message: "Failed to fetch deliveries"})
+		return
+	}
+	var next int64
+	if len(deliveries) == limit {
+		next = deliveries[len(deliveries)-1].ID
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"deliveries":  deliveries,
+		"next_cursor": next,
+	})
+}
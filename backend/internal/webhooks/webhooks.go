@@ -0,0 +1,317 @@
+// Package webhooks sends account and group events to URLs registered by users.
+//
+// Every event becomes a row in webhook_deliveries and a scheduler job, so pending
+// deliveries survive restarts. Failed attempts are retried with exponential backoff,
+// except when the URL resolves to a private address, and the outcome of the last
+// attempt is kept as the delivery log of the webhook.
+package webhooks
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/netguard"
+	"backend/internal/scheduler"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Events of group webhooks
+const (
+	EventPostCreated        = "post.created"
+	EventMemberJoined       = "member.joined"
+	EventEventCreated       = "event.created"
+	EventJoinRequestCreated = "join_request.created"
+)
+
+// Events of account webhooks (besides EventPostCreated)
+const (
+	EventFollowerCreated      = "follower.created"
+	EventFollowRequestCreated = "follow_request.created"
+)
+
+// EventPing is sent by the "send test event" endpoint, whatever the webhook subscribes to.
+const EventPing = "ping"
+
+var (
+	GroupEvents   = []string{EventPostCreated, EventMemberJoined, EventEventCreated, EventJoinRequestCreated}
+	AccountEvents = []string{EventPostCreated, EventFollowerCreated, EventFollowRequestCreated}
+)
+
+const (
+	deliveryJob    = "webhook_delivery"
+	maintenanceJob = "webhook_maintenance"
+
+	// maxAttempts bounds the attempts of a delivery; with retryBase doubling
+	// up to retryCap, the last one happens about 2 hours after the event
+	maxAttempts = 8
+	retryBase   = time.Minute
+	retryCap    = 2 * time.Hour
+
+	// stalledAfter is how long past its attempt time a pending delivery is
+	// considered lost (e.g. interrupted by a restart) and queued again
+	stalledAfter     = 10 * time.Minute
+	defaultRetention = 30 * 24 * time.Hour
+	maxResponseBody  = 1024
+)
+
+// Envelope is the JSON body POSTed to webhooks.
+type Envelope struct {
+	ID        string      `json:"id"` // identical for every webhook receiving the same event
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	GroupID   *int64      `json:"group_id,omitempty"`
+	Actor     *Actor      `json:"actor,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// Actor is the user who caused an event.
+type Actor struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type jobPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// Register installs the delivery and maintenance jobs. Call it before scheduler.Run.
+func Register() {
+	scheduler.Register(deliveryJob, runDelivery)
+	scheduler.RegisterPeriodic(maintenanceJob, time.Hour, runMaintenance)
+}
+
+// IsEvent reports whether event can be subscribed to by group (or account) webhooks.
+func IsEvent(event string, group bool) bool {
+	events := AccountEvents
+	if group {
+		events = GroupEvents
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// EmitGroupEvent queues an event for the webhooks of a group.
+func EmitGroupEvent(groupID int64, event string, actorID int, data interface{}) {
+	emit(0, &groupID, event, actorID, data)
+}
+
+// EmitAccountEvent queues an event for the webhooks of userID's account.
+func EmitAccountEvent(userID int, event string, actorID int, data interface{}) {
+	emit(userID, nil, event, actorID, data)
+}
+
+func emit(userID int, groupID *int64, event string, actorID int, data interface{}) {
+	hooks, err := queries.GetSubscribedWebhooks(userID, groupID, event)
+	if err != nil {
+		fmt.Printf("webhooks: failed to load webhooks for %s: %v\n", event, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := envelope(event, groupID, actorID, data)
+	if err != nil {
+		fmt.Printf("webhooks: failed to encode %s: %v\n", event, err)
+		return
+	}
+	for _, hook := range hooks {
+		if _, err := enqueue(hook.ID, event, payload); err != nil {
+			fmt.Printf("webhooks: failed to queue %s for webhook %d: %v\n", event, hook.ID, err)
+		}
+	}
+}
+
+// SendTest queues a ping event for a webhook and returns the delivery ID.
+func SendTest(hook models.Webhook, actorID int) (int64, error) {
+	payload, err := envelope(EventPing, hook.GroupID, actorID, map[string]interface{}{
+		"webhook_id": hook.ID,
+		"message":    "This is a test event",
+	})
+	if err != nil {
+		return 0, err
+	}
+	return enqueue(hook.ID, EventPing, payload)
+}
+
+func envelope(event string, groupID *int64, actorID int, data interface{}) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	env := Envelope{
+		ID:        "evt_" + hex.EncodeToString(id),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		GroupID:   groupID,
+		Data:      data,
+	}
+	if actorID != 0 {
+		if user, err := queries.GetUserByID(actorID); err == nil {
+			env.Actor = &Actor{ID: user.ID, Username: user.Username, FirstName: user.FirstName, LastName: user.LastName}
+		}
+	}
+
+	b, err := json.Marshal(env)
+	return string(b), err
+}
+
+func enqueue(webhookID int64, event, payload string) (int64, error) {
+	now := time.Now()
+	deliveryID, err := queries.CreateWebhookDelivery(webhookID, event, payload, now)
+	if err != nil {
+		return 0, err
+	}
+	return deliveryID, scheduleAttempt(deliveryID, 1, now)
+}
+
+// scheduleAttempt schedules the given attempt (1-based) of a delivery. Each
+// attempt has its own job key, since a running job cannot be rescheduled.
+func scheduleAttempt(deliveryID int64, attempt int, runAt time.Time) error {
+	key := fmt.Sprintf("%s:%d:%d", deliveryJob, deliveryID, attempt)
+	return scheduler.Schedule(deliveryJob, key, runAt, jobPayload{DeliveryID: deliveryID})
+}
+
+// backoff returns the wait after the given failed attempt: 1m, 2m, 4m, ... up to retryCap.
+func backoff(attempt int) time.Duration {
+	d := retryBase << (attempt - 1)
+	if d > retryCap || d <= 0 {
+		return retryCap
+	}
+	return d
+}
+
+// Sign returns the X-Webhook-Signature value of a body sent at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func runDelivery(payload []byte) error {
+	var p jobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	delivery, err := queries.GetWebhookDelivery(p.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.Status != "pending" {
+		return nil
+	}
+	hook, err := queries.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if !hook.Active {
+		return queries.FailWebhookDelivery(delivery.ID, "webhook is disabled")
+	}
+
+	attempt, permanent := send(hook, delivery)
+	if attempt.Error == "" {
+		return queries.RecordWebhookAttempt(delivery.ID, attempt, "delivered", nil)
+	}
+
+	attempts := delivery.Attempts + 1
+	if permanent || attempts >= maxAttempts {
+		return queries.RecordWebhookAttempt(delivery.ID, attempt, "failed", nil)
+	}
+	next := time.Now().Add(backoff(attempts))
+	if err := queries.RecordWebhookAttempt(delivery.ID, attempt, "pending", &next); err != nil {
+		return err
+	}
+	return scheduleAttempt(delivery.ID, attempts+1, next)
+}
+
+// send POSTs a delivery once. Any answer other than 2xx counts as a failure.
+// permanent reports a failure that retrying cannot fix, such as a URL that
+// resolves to a private address.
+func send(hook models.Webhook, delivery models.WebhookDelivery) (attempt queries.WebhookAttempt, permanent bool) {
+	start := time.Now()
+	defer func() { attempt.Duration = time.Since(start) }()
+
+	timestamp := start.Unix()
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, true
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SocialNetwork-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, netguard.ErrBlockedAddress) {
+			attempt.Error = errBlockedAddress.Error()
+			return attempt, true
+		}
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.ResponseStatus = resp.StatusCode
+	attempt.ResponseBody = string(body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("receiver answered %s", resp.Status)
+	}
+	return attempt, false
+}
+
+// retention returns how long finished deliveries are kept (WEBHOOK_LOG_RETENTION, e.g. "720h").
+func retention() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_LOG_RETENTION")); err == nil && d > 0 {
+		return d
+	}
+	return defaultRetention
+}
+
+// runMaintenance queues lost deliveries again and prunes the delivery log.
+func runMaintenance([]byte) error {
+	pending, err := queries.GetPendingWebhookDeliveries()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, d := range pending {
+		if d.NextAttemptAt != nil && now.Sub(*d.NextAttemptAt) < stalledAfter {
+			continue
+		}
+		if err := scheduleAttempt(d.ID, d.Attempts+1, now); err != nil {
+			fmt.Printf("webhooks: failed to requeue delivery %d: %v\n", d.ID, err)
+		}
+	}
+
+	if n, err := queries.DeleteFinishedWebhookDeliveries(now.Add(-retention())); err != nil {
+		return err
+	} else if n > 0 {
+		fmt.Printf("webhooks: pruned %d old deliveries\n", n)
+	}
+	return nil
+}
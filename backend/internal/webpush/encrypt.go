@@ -0,0 +1,104 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// recordSize is the aes128gcm record size announced in the header. Payloads are
+// sent as a single record, so it only has to exceed the encrypted payload.
+const recordSize = 4096
+
+// maxPayload keeps a single record, including the delimiter and the 16-byte tag, within recordSize
+// and below the 4096 bytes push services guarantee to accept.
+const maxPayload = 3993
+
+// ErrPayloadTooLarge is returned for payloads that do not fit in one push message.
+var ErrPayloadTooLarge = errors.New("webpush: payload too large")
+
+// encrypt encrypts plaintext for a subscription as described in RFC 8291, using
+// the aes128gcm content coding of RFC 8188. clientKey is the subscription's p256dh
+// key and authSecret its auth secret.
+func encrypt(plaintext, clientKey, authSecret []byte) ([]byte, error) {
+	if len(plaintext) > maxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+
+	curve := ecdh.P256()
+	uaPublic, err := ecdhPublicKey(clientKey)
+	if err != nil {
+		return nil, err
+	}
+	// A fresh key pair per message, whose public key travels in the header
+	asPrivate, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append([]byte("WebPush: info\x00"), clientKey...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := deriveKey(authSecret, sharedSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := deriveKey(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := deriveKey(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt || record size || key id length || key id (the server public key)
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 marks the last (and only) record, without padding
+	record := append(append([]byte{}, plaintext...), 0x02)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+func deriveKey(salt, secret, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func ecdhPublicKey(key []byte) (*ecdh.PublicKey, error) {
+	return ecdh.P256().NewPublicKey(key)
+}
@@ -0,0 +1,139 @@
+package webpush
+
+import (
+	"backend/internal/db/queries"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	privateKeySecret = "vapid_private_key"
+	// jwtLifetime must stay below the 24 hours push services accept
+	jwtLifetime = 12 * time.Hour
+)
+
+var b64 = base64.RawURLEncoding
+
+var (
+	keyOnce sync.Once
+	key     *ecdsa.PrivateKey
+	keyErr  error
+)
+
+// vapidKey returns the server's VAPID signing key. It comes from VAPID_PRIVATE_KEY
+// (base64url-encoded P-256 scalar) or is generated once and stored in app_secrets.
+func vapidKey() (*ecdsa.PrivateKey, error) {
+	keyOnce.Do(func() {
+		encoded := strings.TrimSpace(os.Getenv("VAPID_PRIVATE_KEY"))
+		if encoded == "" {
+			encoded, keyErr = queries.GetOrCreateAppSecret(privateKeySecret, generatePrivateKey)
+			if keyErr != nil {
+				return
+			}
+		}
+		key, keyErr = parsePrivateKey(encoded)
+	})
+	return key, keyErr
+}
+
+func generatePrivateKey() (string, error) {
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(k.Bytes()), nil
+}
+
+func parsePrivateKey(encoded string) (*ecdsa.PrivateKey, error) {
+	d, err := b64.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	k, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	pub := k.PublicKey().Bytes() // 0x04 || X || Y
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, nil
+}
+
+// PublicKey returns the base64url-encoded VAPID public key browsers subscribe with
+// (the applicationServerKey of pushManager.subscribe).
+func PublicKey() (string, error) {
+	k, err := vapidKey()
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(publicKeyBytes(k)), nil
+}
+
+func publicKeyBytes(k *ecdsa.PrivateKey) []byte {
+	pub := make([]byte, 65)
+	pub[0] = 4
+	k.X.FillBytes(pub[1:33])
+	k.Y.FillBytes(pub[33:])
+	return pub
+}
+
+// subject identifies the sender to push services (VAPID_SUBJECT, or a mailto: of SENDER_EMAIL).
+func subject() string {
+	if s := strings.TrimSpace(os.Getenv("VAPID_SUBJECT")); s != "" {
+		return s
+	}
+	if email := strings.TrimSpace(os.Getenv("SENDER_EMAIL")); email != "" {
+		return "mailto:" + email
+	}
+	return "mailto:admin@localhost"
+}
+
+// authorization returns the VAPID Authorization header for a push endpoint (RFC 8292).
+func authorization(endpoint string) (string, error) {
+	k, err := vapidKey()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(jwtLifetime).Unix(),
+		"sub": subject(),
+	})
+	unsigned := b64.EncodeToString(header) + "." + b64.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS ES256 signatures are the fixed-size concatenation r || s
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	token := unsigned + "." + b64.EncodeToString(sig)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, b64.EncodeToString(publicKeyBytes(k))), nil
+}
@@ -0,0 +1,161 @@
+// Package webpush delivers notifications to users' browsers through the Web Push
+// protocol while they have no open WebSocket. Payloads are encrypted for each
+// subscription (RFC 8291) and requests are signed with the server's VAPID key (RFC 8292).
+package webpush
+
+import (
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"backend/internal/netguard"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// ttl is how long push services keep an undelivered message
+	ttl = 24 * time.Hour
+	// maxSubscriptionsPerUser bounds the devices notified for one user
+	maxSubscriptionsPerUser = 20
+)
+
+// client refuses to connect to private, loopback and link-local addresses, so
+// endpoints given by users cannot reach services inside our network.
+var client = netguard.Client(10*time.Second, allowInsecure)
+
+// Message is the JSON payload received by the service worker's push event.
+type Message struct {
+	Type  string                 `json:"type"`
+	Title string                 `json:"title"`
+	Body  string                 `json:"body"`
+	URL   string                 `json:"url,omitempty"` // page to open when the notification is clicked
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// allowInsecure reports whether plain http and private endpoints are accepted
+// (WEBPUSH_ALLOW_INSECURE=true), for local stand-in push services during development.
+func allowInsecure() bool {
+	return os.Getenv("WEBPUSH_ALLOW_INSECURE") == "true"
+}
+
+// ValidateSubscription checks a subscription sent by a browser
+// (PushSubscription.toJSON()).
+func ValidateSubscription(endpoint, p256dh, auth string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || len(endpoint) > 2048 {
+		return errors.New("invalid push endpoint")
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && allowInsecure()) {
+		return errors.New("push endpoint must use https")
+	}
+	if !allowInsecure() && netguard.IsLocalHost(u.Hostname()) {
+		return errors.New("push endpoint must not be a private or local address")
+	}
+
+	key, err := b64.DecodeString(p256dh)
+	if err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	if _, err := ecdhPublicKey(key); err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	secret, err := b64.DecodeString(auth)
+	if err != nil || len(secret) != 16 {
+		return errors.New("invalid auth secret")
+	}
+	return nil
+}
+
+// NotifyUser pushes a message to every subscribed device of a user. It runs in
+// the background; subscriptions the push service reports as gone are deleted.
+func NotifyUser(userID int, msg Message) {
+	go func() {
+		subs, err := queries.GetPushSubscriptions(userID)
+		if err != nil {
+			fmt.Printf("webpush: failed to load subscriptions of user %d: %v\n", userID, err)
+			return
+		}
+		if len(subs) == 0 {
+			return
+		}
+		if len(subs) > maxSubscriptionsPerUser {
+			subs = subs[:maxSubscriptionsPerUser]
+		}
+
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			fmt.Printf("webpush: failed to encode message: %v\n", err)
+			return
+		}
+		for _, sub := range subs {
+			if err := Send(sub, payload, urgency(msg.Type)); err != nil {
+				fmt.Printf("webpush: delivery to subscription %d of user %d failed: %v\n", sub.ID, userID, err)
+			}
+		}
+	}()
+}
+
+func urgency(messageType string) string {
+	if messageType == "new_message" {
+		return "high"
+	}
+	return "normal"
+}
+
+// Send encrypts payload for one subscription and posts it to its push service.
+// A subscription answered with 404 or 410 has expired and is deleted.
+func Send(sub models.PushSubscription, payload []byte, urgency string) error {
+	clientKey, err := b64.DecodeString(sub.P256dh)
+	if err != nil {
+		return err
+	}
+	authSecret, err := b64.DecodeString(sub.Auth)
+	if err != nil {
+		return err
+	}
+	body, err := encrypt(payload, clientKey, authSecret)
+	if err != nil {
+		return err
+	}
+	authHeader, err := authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", urgency)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		if err := queries.DeletePushSubscriptionByEndpoint(sub.Endpoint); err != nil {
+			return err
+		}
+		fmt.Printf("webpush: removed expired subscription %d\n", sub.ID)
+		return nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return queries.TouchPushSubscription(sub.ID)
+	default:
+		return fmt.Errorf("push service answered %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+}
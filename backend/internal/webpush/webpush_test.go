@@ -0,0 +1,322 @@
+package webpush
+
+import (
+	"backend/internal/db"
+	"backend/internal/db/queries"
+	"backend/internal/models"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testUser = 1
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "webpush")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		os.Setenv("WEBPUSH_ALLOW_INSECURE", "true")
+		os.Setenv("VAPID_SUBJECT", "mailto:push@example.test")
+		if err := db.InitDB(filepath.Join(dir, "test.db")); err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		if err := db.RunMigrations("../db/migrations/sqlite"); err != nil {
+			panic(err)
+		}
+		for id := 1; id <= 2; id++ {
+			if _, err := queries.DB.Exec(`
+				INSERT INTO users (id, email, username, password_hash, first_name, last_name, date_of_birth)
+				VALUES (?, 'user' || ?1 || '@example.test', 'user' || ?1, 'x', 'User', ?1, '2000-01-01')
+			`, id); err != nil {
+				panic(err)
+			}
+		}
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// browser holds the keys of a subscribing user agent.
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) browser {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return browser{key: key, auth: auth}
+}
+
+// subscribe stores a subscription of the browser to endpoint for the test user.
+func (b browser) subscribe(t *testing.T, endpoint string) models.PushSubscription {
+	t.Helper()
+	p256dh := b64.EncodeToString(b.key.PublicKey().Bytes())
+	auth := b64.EncodeToString(b.auth)
+	if err := ValidateSubscription(endpoint, p256dh, auth); err != nil {
+		t.Fatal(err)
+	}
+	id, err := queries.UpsertPushSubscription(testUser, endpoint, p256dh, auth, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.PushSubscription{ID: id, UserID: testUser, Endpoint: endpoint, P256dh: p256dh, Auth: auth}
+}
+
+// decrypt reverses encrypt as the browser does (RFC 8291).
+func (b browser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("short body")
+	}
+	salt := body[:16]
+	idLen := int(body[20])
+	if binary.BigEndian.Uint32(body[16:20]) != recordSize || len(body) < 21+idLen {
+		return nil, errors.New("bad header")
+	}
+	asPublic, err := ecdhPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := b.key.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), b.key.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublic.Bytes()...)
+	ikm, err := deriveKey(b.auth, shared, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, _ := deriveKey(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := deriveKey(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	record, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		return nil, errors.New("missing last record delimiter")
+	}
+	return record[:len(record)-1], nil
+}
+
+// pushService is a local stand-in push service that records the last request.
+type pushService struct {
+	*httptest.Server
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newPushService(t *testing.T, status int) *pushService {
+	s := &pushService{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.header = r.Header.Clone()
+		s.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// checkVAPID verifies the Authorization header of a request to endpoint (RFC 8292).
+func checkVAPID(t *testing.T, header, endpoint string) {
+	t.Helper()
+	rest, ok := strings.CutPrefix(header, "vapid t=")
+	token, k, ok2 := strings.Cut(rest, ", k=")
+	if !ok || !ok2 {
+		t.Fatalf("Authorization = %q, want vapid t=..., k=...", header)
+	}
+	public, err := PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != public {
+		t.Errorf("k = %q, want the server public key %q", k, public)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts, want 3", len(parts))
+	}
+	var head map[string]string
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := decodePart(parts[0], &head); err != nil || head["alg"] != "ES256" || head["typ"] != "JWT" {
+		t.Errorf("JWT header = %v (%v), want ES256 JWT", head, err)
+	}
+	if err := decodePart(parts[1], &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != endpoint {
+		t.Errorf("aud = %q, want %q", claims.Aud, endpoint)
+	}
+	if exp := time.Unix(claims.Exp, 0); exp.Before(time.Now()) || exp.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("exp = %v, want within the next 24 hours", exp)
+	}
+	if claims.Sub != "mailto:push@example.test" {
+		t.Errorf("sub = %q", claims.Sub)
+	}
+
+	keyBytes, _ := b64.DecodeString(k)
+	x, y := elliptic.Unmarshal(elliptic.P256(), keyBytes)
+	if x == nil {
+		t.Fatal("k is not a P-256 point")
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		t.Fatalf("signature of %d bytes (%v), want 64", len(sig), err)
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	if !ecdsa.Verify(pub, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		t.Error("JWT signature does not verify")
+	}
+}
+
+func decodePart(part string, v any) error {
+	raw, err := b64.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func TestSend(t *testing.T) {
+	service := newPushService(t, http.StatusCreated)
+	b := newBrowser(t)
+	sub := b.subscribe(t, service.URL+"/push/abc")
+
+	payload := []byte(`{"type":"new_message","title":"Hello"}`)
+	if err := Send(sub, payload, "high"); err != nil {
+		t.Fatal(err)
+	}
+
+	checkVAPID(t, service.header.Get("Authorization"), service.URL)
+	for name, want := range map[string]string{
+		"Content-Encoding": "aes128gcm",
+		"Content-Type":     "application/octet-stream",
+		"TTL":              "86400",
+		"Urgency":          "high",
+	} {
+		if got := service.header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	got, err := b.decrypt(service.body)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("decrypted %q, want %q", got, payload)
+	}
+}
+
+func TestSendPrunesExpired(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			service := newPushService(t, status)
+			sub := newBrowser(t).subscribe(t, service.URL+"/push/gone")
+
+			if err := Send(sub, []byte("{}"), "normal"); err != nil {
+				t.Fatal(err)
+			}
+			subs, err := queries.GetPushSubscriptions(testUser)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range subs {
+				if s.ID == sub.ID {
+					t.Errorf("subscription answered %d was kept", status)
+				}
+			}
+		})
+	}
+}
+
+func TestSendKeepsOnError(t *testing.T) {
+	service := newPushService(t, http.StatusTooManyRequests)
+	sub := newBrowser(t).subscribe(t, service.URL+"/push/busy")
+
+	if err := Send(sub, []byte("{}"), "normal"); err == nil {
+		t.Error("Send answered 429: want an error")
+	}
+	subs, err := queries.GetPushSubscriptions(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range subs {
+		if s.ID == sub.ID {
+			return
+		}
+	}
+	t.Error("subscription answered 429 was deleted")
+}
+
+func TestSendBlocksPrivateAddresses(t *testing.T) {
+	service := newPushService(t, http.StatusCreated)
+	sub := newBrowser(t).subscribe(t, service.URL+"/push/private")
+
+	t.Setenv("WEBPUSH_ALLOW_INSECURE", "false")
+	if err := Send(sub, []byte("{}"), "normal"); err == nil {
+		t.Error("Send to a loopback endpoint: want an error")
+	}
+	if service.body != nil {
+		t.Error("the loopback push service was reached")
+	}
+	if err := ValidateSubscription("https://127.0.0.1/push", sub.P256dh, sub.Auth); err == nil {
+		t.Error("ValidateSubscription of a loopback endpoint: want an error")
+	}
+}
+
+func TestUpsertKeepsOtherUsersEndpoint(t *testing.T) {
+	sub := newBrowser(t).subscribe(t, "https://push.example.test/taken")
+
+	other := newBrowser(t)
+	_, err := queries.UpsertPushSubscription(2, sub.Endpoint, b64.EncodeToString(other.key.PublicKey().Bytes()), b64.EncodeToString(other.auth), "test")
+	if err != sql.ErrNoRows {
+		t.Errorf("UpsertPushSubscription of another user's endpoint = %v, want sql.ErrNoRows", err)
+	}
+	if subs, _ := queries.GetPushSubscriptions(2); len(subs) != 0 {
+		t.Errorf("the endpoint moved to the other user")
+	}
+}
@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"backend/internal/contentfilter"
 	"backend/internal/db/queries"
 	"backend/internal/models"
 	"fmt"
@@ -47,6 +48,12 @@ func HandleGroupMessage(sConn *SafeConn, session *models.Session, msg map[string
 		return
 	}
 
+	decision := contentfilter.Check(contentfilter.Input{UserID: session.UserID, Kind: contentfilter.KindGroupMessage, Text: content})
+	if decision.Rejected() {
+		sendWSError(sConn, "content_rejected", decision.Message())
+		return
+	}
+
 	// Save to database
 	msgID, err := queries.CreateGroupChatMessage(groupID, session.UserID, content)
 	if err != nil {
@@ -54,6 +61,7 @@ func HandleGroupMessage(sConn *SafeConn, session *models.Session, msg map[string
 		sendWSError(sConn, "storage_error", "Failed to store message")
 		return
 	}
+	decision.Report("group_message", int64(msgID), session.UserID, content)
 
 	// Get user details for broadcast
 	user, err := queries.GetUserByID(session.UserID)
@@ -10,7 +10,15 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// BroadcastToGroup sends a notification to all members of a group
+// groupAlertTypes are the group messages that alert members of something new.
+// Members who muted the group don't get them; the others only keep open pages
+// in sync and go to every member.
+var groupAlertTypes = map[string]bool{
+	"new_group_event": true,
+}
+
+// BroadcastToGroup sends a message to all members of a group, leaving out those
+// who muted it for the types in groupAlertTypes
 func BroadcastToGroup(groupID int64, messageType string, data interface{}) {
 	// Run in a goroutine to not block the caller
 	go func() {
@@ -26,9 +34,22 @@ func BroadcastToGroup(groupID int64, messageType string, data interface{}) {
 			Timestamp: time.Now(),
 		}
 
+		// Members who muted the group are skipped for alerts
+		muted := map[int]bool{}
+		if groupAlertTypes[messageType] {
+			muted, err = queries.GetMutingUserIDs("group", groupID)
+			if err != nil {
+				fmt.Printf("Error fetching group mutes for notification: %v\n", err)
+				return
+			}
+		}
+
 		fmt.Printf("Broadcasting %s to group %d (%d members)\n", messageType, groupID, len(members))
 
 		for _, member := range members {
+			if muted[member.UserID] {
+				continue
+			}
 			SendNotificationToUser(member.UserID, notification)
 		}
 	}()
@@ -96,6 +117,17 @@ func SendNotificationToUser(userID int, notification models.NotificationMessage)
 	}
 }
 
+// NotifyPostRemoved tells users who can no longer see a post to drop it
+func NotifyPostRemoved(postID int64, userIDs []int) {
+	for _, userID := range userIDs {
+		SendNotificationToUser(userID, models.NotificationMessage{
+			Type:      "post_removed",
+			Data:      map[string]interface{}{"postId": postID},
+			Timestamp: time.Now(),
+		})
+	}
+}
+
 // BroadcastFollowRequest notifies a user of a follow request
 func BroadcastFollowRequest(recipientID int, senderID int, senderName string, senderAvatar *string) {
 	go func() {
@@ -130,9 +162,21 @@ func BroadcastFollowRequest(recipientID int, senderID int, senderName string, se
 // BroadcastMessageNotification notifies user of a new message (also stores in DB)
 func BroadcastMessageNotification(recipientID int, senderID int, senderName string, content string, conversationID int) {
 	go func() {
+		delivery, err := queries.GetNotificationDelivery(recipientID, "new_message", "conversation", int64(conversationID))
+		if err != nil {
+			fmt.Printf("Failed to load notification preferences of user %d: %v\n", recipientID, err)
+			return
+		}
+
 		// Store in database
 		data := fmt.Sprintf(`{"sender_id":%d,"sender_name":"%s","content":"%s","conversation_id":%d}`, senderID, senderName, content, conversationID)
-		_, _ = queries.CreateNotification(recipientID, &senderID, "new_message", data)
+		if delivery.InApp {
+			groupKey := fmt.Sprintf("new_message:%d", conversationID)
+			_, _ = queries.CreateGroupedNotification(recipientID, &senderID, "new_message", data, groupKey)
+		}
+		if !delivery.Push {
+			return
+		}
 
 		notification := map[string]interface{}{
 			"type":            "new_message",
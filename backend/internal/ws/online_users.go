@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 )
@@ -33,6 +34,62 @@ var (
 	mu          sync.Mutex
 )
 
+// IsUserOnline reports whether the user currently holds a WebSocket connection
+func IsUserOnline(userID int) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := OnlineUsers[userID]
+	return ok
+}
+
+// OnlineAmong returns those of userIDs who currently hold a WebSocket connection
+func OnlineAmong(userIDs []int) []int {
+	mu.Lock()
+	defer mu.Unlock()
+	var online []int
+	for _, id := range userIDs {
+		if _, ok := OnlineUsers[id]; ok {
+			online = append(online, id)
+		}
+	}
+	return online
+}
+
+// OnlineUserIDs returns the users currently holding a WebSocket connection
+func OnlineUserIDs() []int {
+	mu.Lock()
+	defer mu.Unlock()
+	ids := make([]int, 0, len(OnlineUsers))
+	for id := range OnlineUsers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DisconnectUser closes the user's WebSocket, if any, after sending an error
+// event that tells the client why. It reports whether a socket was open.
+func DisconnectUser(userID int, code, message string) bool {
+	mu.Lock()
+	sConn, ok := OnlineUsers[userID]
+	mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	sendWSError(sConn, code, message)
+	// Control frames carry at most 123 bytes of reason
+	reason := message
+	if len(reason) > 123 {
+		for len(reason) > 120 {
+			_, size := utf8.DecodeLastRuneInString(reason)
+			reason = reason[:len(reason)-size]
+		}
+		reason += "..."
+	}
+	closeWithPolicyViolation(sConn, reason)
+	return true
+}
+
 // BroadcastToAll sends a message to all connected clients
 func BroadcastToAll(message interface{}) {
 	data, err := json.Marshal(message)
@@ -1,8 +1,11 @@
 package ws
 
 import (
+	"backend/internal/contentfilter"
 	"backend/internal/db/queries"
 	"backend/internal/models"
+	"backend/internal/webpush"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -49,6 +52,33 @@ func HandlePrivateMessage(sConn *SafeConn, session *models.Session, msg map[stri
 		return
 	}
 
+	// Existing conversations are frozen while either user blocks the other.
+	// Without another participant (the account is gone) there is no one to check.
+	other, err := queries.GetOtherPrivateChatUser(conversationID, session.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Failed to load the other user of conversation %d for user %d: %v\n", conversationID, session.UserID, err)
+		sendWSError(sConn, "internal_error", "Failed to validate conversation membership")
+		return
+	}
+	if err == nil {
+		blocked, err := queries.IsBlockedBetween(session.UserID, other.ID)
+		if err != nil {
+			fmt.Printf("Failed block check for conversation %d user %d: %v\n", conversationID, session.UserID, err)
+			sendWSError(sConn, "internal_error", "Failed to validate conversation membership")
+			return
+		}
+		if blocked {
+			sendWSError(sConn, "forbidden", "You can't message this user")
+			return
+		}
+	}
+
+	decision := contentfilter.Check(contentfilter.Input{UserID: session.UserID, Kind: contentfilter.KindPrivateMessage, Text: content})
+	if decision.Rejected() {
+		sendWSError(sConn, "content_rejected", decision.Message())
+		return
+	}
+
 	// Save to database
 	msgID, err := queries.CreatePrivateChatMessage(conversationID, session.UserID, content)
 	if err != nil {
@@ -56,6 +86,12 @@ func HandlePrivateMessage(sConn *SafeConn, session *models.Session, msg map[stri
 		sendWSError(sConn, "storage_error", "Failed to store message")
 		return
 	}
+	decision.Report("private_message", int64(msgID), session.UserID, content)
+
+	// Replying means the sender has read the conversation
+	if err := queries.MarkConversationRead(conversationID, session.UserID); err != nil {
+		fmt.Printf("Failed to mark conversation %d read for user %d: %v\n", conversationID, session.UserID, err)
+	}
 
 	// Get user details for broadcast
 	user, err := queries.GetUserByID(session.UserID)
@@ -90,6 +126,25 @@ func HandlePrivateMessage(sConn *SafeConn, session *models.Session, msg map[stri
 		senderName := user.FirstName + " " + user.LastName
 		for _, participantID := range participants {
 			if participantID != session.UserID {
+				delivery, err := queries.GetNotificationDelivery(participantID, "new_message", "conversation", int64(conversationID))
+				if err != nil {
+					fmt.Printf("Failed to load notification preferences of user %d: %v\n", participantID, err)
+					continue
+				}
+				// Participants without an open socket are reached on their push subscriptions
+				if delivery.Push && !IsUserOnline(participantID) {
+					webpush.NotifyUser(participantID, webpush.Message{
+						Type:  "new_message",
+						Title: senderName,
+						Body:  pushExcerpt(content),
+						URL:   fmt.Sprintf("/chat?conversation=%d", conversationID),
+						Data:  map[string]interface{}{"conversation_id": conversationID, "sender_id": session.UserID},
+					})
+				}
+				if !delivery.InApp {
+					continue
+				}
+
 				// Create notification for other participant with properly encoded JSON
 				dataMap := map[string]interface{}{
 					"sender_id":       session.UserID,
@@ -99,7 +154,8 @@ func HandlePrivateMessage(sConn *SafeConn, session *models.Session, msg map[stri
 					"conversation_id": conversationID,
 				}
 				dataBytes, _ := json.Marshal(dataMap)
-				_, err := queries.CreateNotification(participantID, &session.UserID, "new_message", string(dataBytes))
+				groupKey := fmt.Sprintf("new_message:%d", conversationID)
+				_, err = queries.CreateGroupedNotification(participantID, &session.UserID, "new_message", string(dataBytes), groupKey)
 				if err != nil {
 					fmt.Printf("Failed to create new_message notification: %v\n", err)
 				}
@@ -107,3 +163,13 @@ func HandlePrivateMessage(sConn *SafeConn, session *models.Session, msg map[stri
 		}
 	}
 }
+
+// pushExcerpt shortens a message for the push payload, which is limited to about 4KB
+func pushExcerpt(content string) string {
+	const maxRunes = 200
+	runes := []rune(content)
+	if len(runes) <= maxRunes {
+		return content
+	}
+	return string(runes[:maxRunes]) + "…"
+}
@@ -60,6 +60,23 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	suspension, err := queries.GetActiveSuspension(session.UserID)
+	if err != nil {
+		utils.RespondJSON(w, http.StatusInternalServerError, models.GenericResponse{
+			Success: false,
+			Message: "Failed to verify session",
+		})
+		return
+	}
+	if suspension != nil {
+		fmt.Printf("WebSocket error: User %d is suspended\n", session.UserID)
+		utils.RespondJSON(w, http.StatusForbidden, models.GenericResponse{
+			Success: false,
+			Message: suspension.Message(),
+		})
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
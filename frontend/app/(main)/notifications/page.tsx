@@ -68,13 +68,20 @@ type PendingFollowRequest = {
   };
 };
 
-async function fetchNotifications(): Promise<NotificationItem[]> {
+type NotificationPage = {
+  notifications: NotificationItem[];
+  nextCursor: string | null;
+};
+
+async function fetchNotifications(
+  cursor: string | null = null,
+): Promise<NotificationPage> {
+  const url = cursor
+    ? `${API_URL}/api/notifications?cursor=${encodeURIComponent(cursor)}`
+    : `${API_URL}/api/notifications`;
   try {
-    console.log(
-      "[Notifications API] Fetching notifications from:",
-      `${API_URL}/api/notifications`,
-    );
-    const response = await fetch(`${API_URL}/api/notifications`, {
+    console.log("[Notifications API] Fetching notifications from:", url);
+    const response = await fetch(url, {
       credentials: "include",
     });
     console.log("[Notifications API] Response status:", response.status);
@@ -83,7 +90,7 @@ async function fetchNotifications(): Promise<NotificationItem[]> {
         "[Notifications API] Response not OK:",
         response.statusText,
       );
-      return [];
+      return { notifications: [], nextCursor: null };
     }
     const data = await response.json();
     console.log("[Notifications API] Raw response:", data);
@@ -92,10 +99,10 @@ async function fetchNotifications(): Promise<NotificationItem[]> {
       "[Notifications API] Notifications count:",
       notifications.length,
     );
-    return notifications;
+    return { notifications, nextCursor: data.next_cursor || null };
   } catch (error) {
     console.error("[Notifications API] Error:", error);
-    return [];
+    return { notifications: [], nextCursor: null };
   }
 }
 
@@ -234,6 +241,8 @@ export default function NotificationsPage() {
   const [processingId, setProcessingId] = useState<number | null>(null);
   const [markingAllRead, setMarkingAllRead] = useState(false);
   const [viewAllNotifications, setViewAllNotifications] = useState(false);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
+  const [loadingMore, setLoadingMore] = useState(false);
 
   const unreadCount = useMemo(
     () =>
@@ -261,7 +270,7 @@ export default function NotificationsPage() {
       ]);
 
     console.log("[Notifications] Loaded data:", {
-      notifications: notificationData.length,
+      notifications: notificationData.notifications.length,
       invitations: invitationData?.invitations?.length || 0,
       followRequests: followRequestsData?.requests?.length || 0,
     });
@@ -271,10 +280,11 @@ export default function NotificationsPage() {
       followRequestsData?.requests,
     );
 
-    setNotifications(notificationData);
+    setNotifications(notificationData.notifications);
+    setNextCursor(notificationData.nextCursor);
     console.log(
       "[Notifications] Notifications state set to:",
-      notificationData.length,
+      notificationData.notifications.length,
       "items",
     );
     setInvitations(invitationData?.invitations || []);
@@ -587,6 +597,22 @@ export default function NotificationsPage() {
     setProcessingId(null);
   };
 
+  const loadMoreNotifications = async () => {
+    if (!nextCursor || loadingMore) return;
+    setLoadingMore(true);
+    const page = await fetchNotifications(nextCursor);
+    setNotifications((prev: NotificationItem[]) => {
+      const seen = new Set(prev.map((item) => item.id));
+      return [
+        ...prev,
+        ...page.notifications.filter((item) => !seen.has(item.id)),
+      ];
+    });
+    setNextCursor(page.nextCursor);
+    setViewAllNotifications(true);
+    setLoadingMore(false);
+  };
+
   if (loading) {
     return (
       <div className="min-h-[70vh] flex items-center justify-center">
@@ -691,6 +717,20 @@ export default function NotificationsPage() {
                     </button>
                   );
                 })}
+
+                {(viewAllNotifications || notifications.length <= 10) &&
+                  nextCursor && (
+                    <button
+                      onClick={loadMoreNotifications}
+                      disabled={loadingMore}
+                      className="w-full p-3 flex items-center justify-center gap-2 text-xs text-primary font-medium hover:underline disabled:opacity-60"
+                    >
+                      {loadingMore && (
+                        <Loader2 className="w-4 h-4 animate-spin" />
+                      )}
+                      {loadingMore ? "Loading..." : "Load more"}
+                    </button>
+                  )}
               </div>
             </div>
           </section>
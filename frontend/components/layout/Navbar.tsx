@@ -109,16 +109,15 @@ export default function Navbar({
 
   const fetchUnreadCount = async () => {
     try {
-      const response = await fetch(`${API_URL}/api/notifications`, {
-        credentials: "include",
-      });
+      const response = await fetch(
+        `${API_URL}/api/notifications/unread-count`,
+        {
+          credentials: "include",
+        },
+      );
       if (!response.ok) return;
       const data = await response.json();
-      const notifications = data.notifications || [];
-      const count = notifications.filter(
-        (n: any) => Number(n.read) === 0,
-      ).length;
-      setUnreadCount(count);
+      setUnreadCount(Number(data.unread_count) || 0);
     } catch (error) {
       console.error("Error fetching notifications:", error);
     }